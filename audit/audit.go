@@ -2,47 +2,60 @@ package audit
 
 import (
 	"encoding/json"
+	"errors"
 	"io/ioutil"
 	"strings"
 
 	"github.com/citihub/probr/config"
 	"github.com/citihub/probr/utils"
+	"github.com/cucumber/godog"
 )
 
 // ProbeAudit is used to hold all information related to probe execution
 type ProbeAudit struct {
-	path               string
-	Name               string
-	PodsDestroyed      *int
-	ScenariosAttempted *int
-	ScenariosSucceeded *int
-	ScenariosFailed    *int
-	Result             *string
-	Scenarios          map[int]*ScenarioAudit
+	path                  string
+	vars                  *config.VarOptions // config of the run. If nil, config.Vars is used.
+	Name                  string
+	PodsDestroyed         *int
+	ScenariosAttempted    *int
+	ScenariosSucceeded    *int
+	ScenariosFailed       *int
+	ScenariosInconclusive *int
+	ScenariosGivenNotMet  *int
+	ScenariosPending      *int
+	Result                *string
+	Scenarios             map[int]*ScenarioAudit
 }
 
 // ScenarioAudit is used by scenario states to audit progress through each step
 type ScenarioAudit struct {
 	Name   string
-	Result string // Passed / Failed / Given Not Met
+	Result string // Passed / Failed / Given Not Met / Inconclusive / Pending
 	Tags   []string
 	Steps  map[int]*stepAudit
 }
 
+// resultSeverity orders scenario results from least to most severe
+var resultSeverity = map[string]int{"": 0, "Passed": 1, "Pending": 2, "Given Not Met": 3, "Inconclusive": 4, "Failed": 5}
+
 type stepAudit struct {
 	Function    string
 	Name        string
 	Description string      // Long-form explanation of anything happening in the step
-	Result      string      // Passed / Failed
+	Result      string      // Passed / Failed / Given Not Met / Inconclusive / Pending
 	Error       string      // Log the error text
 	Payload     interface{} // Handles any values that are sent across the network
 }
 
 func (e *ProbeAudit) Write() {
-	if config.Vars.AuditEnabled == "true" && e.probeRan() {
-		if utils.WriteAllowed(e.path, config.Vars.Overwrite()) {
+	vars := e.vars
+	if vars == nil {
+		vars = &config.Vars
+	}
+	if bool(vars.AuditEnabled) && e.probeRan() {
+		if utils.WriteAllowed(e.path, vars.Overwrite()) {
 			json, _ := json.MarshalIndent(e, "", "  ")
-			data := []byte(json)
+			data := []byte(vars.Redact(string(json))) // Payloads may include command output and secrets
 			ioutil.WriteFile(e.path, data, 0755)
 		}
 	}
@@ -62,6 +75,11 @@ func (p *ScenarioAudit) AuditScenarioStep(stepName, description string, payload
 	p.audit(stepFunctionName, stepName, description, payload, err)
 }
 
+// AuditStep records a step whose implementation is not a Go function in this process, such as a step run by a plugin
+func (p *ScenarioAudit) AuditStep(functionName, stepName, description string, payload interface{}, err error) {
+	p.audit(functionName, stepName, description, payload, err)
+}
+
 func (p *ScenarioAudit) audit(functionName string, stepName string, description string, payload interface{}, err error) {
 	// TODO: This function should replace audit. Added here to avoid breaking existing probes.
 	stepNumber := len(p.Steps) + 1
@@ -71,19 +89,58 @@ func (p *ScenarioAudit) audit(functionName string, stepName string, description
 		Description: description,
 		Payload:     payload,
 	}
-	if err == nil {
-		p.Steps[stepNumber].Result = "Passed"
-		p.Result = "Passed"
-	} else {
-		p.Steps[stepNumber].Result = "Failed"
-		p.Steps[stepNumber].Error = strings.Replace(err.Error(), "[ERROR] ", "", -1)
-		if stepNumber == 1 {
-			p.Result = "Given Not Met" // First entry is always a 'given'; failures should be ignored
-		} else {
-			p.Result = "Failed" // First 'given' was met, but a subsequent step failed
-		}
+	result := ResultOf(err)
+	p.Steps[stepNumber].Result = result
+	if err != nil {
+		p.Steps[stepNumber].Error = ErrorText(err)
+	}
+	// Pending steps may return nil so that the rest of the scenario still runs, which must not
+	// overwrite their result. The scenario therefore keeps the most severe result of its steps.
+	p.Result = WorseResult(p.Result, result)
+}
+
+// StepCount returns the number of steps audited in the scenario so far
+func (p *ScenarioAudit) StepCount() int {
+	return len(p.Steps)
+}
+
+// StepResult returns the result and error text of the scenario's nth audited step, counting from 1
+func (p *ScenarioAudit) StepResult(n int) (result, errText string) {
+	if step := p.Steps[n]; step != nil {
+		return step.Result, step.Error
 	}
+	return "", ""
 }
+
+// ResultOf returns the result that is audited for a step that returned err
+func ResultOf(err error) string {
+	switch {
+	case err == nil:
+		return "Passed"
+	case errors.Is(err, godog.ErrPending), errors.Is(err, godog.ErrUndefined):
+		return "Pending" // The step has not been implemented yet
+	case utils.IsInfrastructureError(err):
+		return "Inconclusive" // The control could not be evaluated, so it has neither passed nor failed
+	case utils.IsGivenNotMet(err):
+		return "Given Not Met" // The precondition does not hold, so the control does not apply
+	default:
+		return "Failed"
+	}
+}
+
+// ErrorText returns the text that is audited for a step that returned err
+func ErrorText(err error) string {
+	return strings.Replace(err.Error(), "[ERROR] ", "", -1)
+}
+
+// WorseResult returns the more severe of two step or scenario results, preferring b if they are equally severe
+func WorseResult(a, b string) string {
+	if resultSeverity[b] >= resultSeverity[a] {
+		return b
+	}
+	return a
+}
+
 func (e *ProbeAudit) probeRan() bool {
 	if len(e.Scenarios) > 0 {
 		return true
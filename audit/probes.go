@@ -6,20 +6,28 @@ import (
 
 // Probe is passed through various functions to audit the probe's progress
 type Probe struct {
-	name               string
-	audit              *ProbeAudit
-	Meta               map[string]interface{}
-	PodsCreated        int
-	PodsDestroyed      int
-	ScenariosAttempted int
-	ScenariosSucceeded int
-	ScenariosFailed    int
-	Result             string
+	name                  string
+	summary               *Summary // summary of the run that the probe belongs to. If nil, State is used.
+	audit                 *ProbeAudit
+	Meta                  map[string]interface{}
+	PodsCreated           int
+	PodsDestroyed         int
+	ScenariosAttempted    int
+	ScenariosSucceeded    int
+	ScenariosFailed       int
+	ScenariosInconclusive int
+	ScenariosGivenNotMet  int
+	ScenariosPending      int
+	Result                string
 }
 
 // CountPodCreated increments pods_created for probe
 func (e *Probe) CountPodCreated(podName string) {
-	State.LogPodName(podName)
+	if e.summary != nil {
+		e.summary.LogPodName(podName)
+	} else {
+		State.LogPodName(podName)
+	}
 	e.PodsCreated = e.PodsCreated + 1
 }
 
@@ -28,6 +36,30 @@ func (e *Probe) CountPodDestroyed() {
 	e.PodsDestroyed = e.PodsDestroyed + 1
 }
 
+// ScenarioCount returns the number of scenarios audited by the probe so far
+func (e *Probe) ScenarioCount() int {
+	return len(e.audit.Scenarios)
+}
+
+// Scenario returns the audit of the probe's nth scenario, counting from 1, or nil if there is none
+func (e *Probe) Scenario(n int) *ScenarioAudit {
+	return e.audit.Scenarios[n]
+}
+
+// AuditPath returns the path that the probe's audit is written to, if audits are enabled
+func (e *Probe) AuditPath() string {
+	return e.audit.path
+}
+
+// packName returns the service pack and provider stored in the probe's meta, e.g. "storage/azure"
+func (e *Probe) packName() string {
+	pack, _ := e.Meta["pack"].(string)
+	if provider, _ := e.Meta["provider"].(string); pack != "" && provider != "" {
+		return pack + "/" + provider
+	}
+	return pack
+}
+
 // countResults stores the current total number of failures as e.ScenariosFailed. Run at probe end
 func (e *Probe) countResults() {
 	e.ScenariosAttempted = len(e.audit.Scenarios)
@@ -36,8 +68,57 @@ func (e *Probe) countResults() {
 			e.ScenariosFailed = e.ScenariosFailed + 1
 		} else if v.Result == "Passed" {
 			e.ScenariosSucceeded = e.ScenariosSucceeded + 1
+		} else if v.Result == "Inconclusive" {
+			e.ScenariosInconclusive = e.ScenariosInconclusive + 1
+		} else if v.Result == "Given Not Met" {
+			e.ScenariosGivenNotMet = e.ScenariosGivenNotMet + 1
+		} else if v.Result == "Pending" {
+			e.ScenariosPending = e.ScenariosPending + 1
+		}
+	}
+}
+
+// IsInconclusive reports whether any scenario could not be evaluated due to an infrastructure error,
+// and no scenario failed. Failures take precedence, as they show that a control has not been met.
+func (e *Probe) IsInconclusive() bool {
+	inconclusive := false
+	for _, v := range e.audit.Scenarios {
+		if v.Result == "Failed" {
+			return false
+		}
+		if v.Result == "Inconclusive" {
+			inconclusive = true
+		}
+	}
+	return inconclusive
+}
+
+// IsGivenNotMet reports whether any scenario's preconditions were not met, and every other scenario passed.
+// Such a probe has not shown a control to be failing, so it should not be treated as a failure.
+func (e *Probe) IsGivenNotMet() bool {
+	givenNotMet := false
+	for _, v := range e.audit.Scenarios {
+		if v.Result == "Given Not Met" {
+			givenNotMet = true
+		} else if v.Result != "Passed" {
+			return false
+		}
+	}
+	return givenNotMet
+}
+
+// IsPending reports whether any scenario includes a step that has not been implemented yet, and no scenario
+// failed or was inconclusive. Scenarios whose preconditions were not met are ignored.
+func (e *Probe) IsPending() bool {
+	pending := false
+	for _, v := range e.audit.Scenarios {
+		if v.Result == "Pending" {
+			pending = true
+		} else if v.Result != "Passed" && v.Result != "Given Not Met" {
+			return false
 		}
 	}
+	return pending
 }
 
 // InitializeAuditor creates a new audit entry for the specified scenario
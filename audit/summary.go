@@ -1,104 +1,241 @@
 package audit
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
 
 	"github.com/citihub/probr/config"
 	"github.com/citihub/probr/utils"
 )
 
-type summaryState struct {
-	Meta          map[string]interface{}
-	Status        string
-	ProbesPassed  int
-	ProbesFailed  int
-	ProbesSkipped int
-	Probes        map[string]*Probe
+// Summary holds the audits of the probes in a run, and the number of probes with each result
+type Summary struct {
+	lock    sync.RWMutex       // probes may be running concurrently, so all access to the state is guarded
+	vars    *config.VarOptions // config of the run. If nil, config.Vars is used.
+	Meta    map[string]interface{}
+	Status  string
+	Profile string // Name of the vars file profile that the run used, if any
+	probeCounts
+	Packs          map[string]*probeCounts // Results for each service pack and provider, e.g. "storage/azure"
+	Probes         map[string]*Probe       // Keyed by pack and probe name, e.g. "storage/azure/access_whitelisting"
+	Waivers        []config.WaiverRecord   // Probes and scenarios that were excluded, why, and until when
+	ExpiredWaivers []config.WaiverRecord   // Probes and scenarios that were run because their waivers expired
 }
 
-// State holds the values for all probe and scenario audits throughout the runtime
-var State summaryState
+// probeCounts holds the number of probes with each result
+type probeCounts struct {
+	ProbesPassed       int
+	ProbesFailed       int
+	ProbesSkipped      int
+	ProbesTimedOut     int
+	ProbesInconclusive int
+	ProbesGivenNotMet  int
+	ProbesPending      int
+}
+
+// State holds the values for all probe and scenario audits of the CLI run, whose config is config.Vars
+var State = NewSummary(&config.Vars)
+
+// NewSummary returns an empty summary of a run using the given config, which sets where audits are written
+func NewSummary(vars *config.VarOptions) *Summary {
+	s := &Summary{
+		vars:           vars,
+		Probes:         make(map[string]*Probe),
+		Packs:          make(map[string]*probeCounts),
+		Meta:           make(map[string]interface{}),
+		Waivers:        []config.WaiverRecord{},
+		ExpiredWaivers: []config.WaiverRecord{},
+	}
+	s.Meta["names of pods created"] = []string{}
+	return s
+}
+
+type summaryKey struct{}
+
+// WithSummary returns a copy of the context that carries the summary of a run, to which probes write their audits
+func WithSummary(ctx context.Context, s *Summary) context.Context {
+	return context.WithValue(ctx, summaryKey{}, s)
+}
+
+// FromContext returns the summary carried by the context, or State if it carries none
+func FromContext(ctx context.Context) *Summary {
+	if ctx != nil {
+		if s, ok := ctx.Value(summaryKey{}).(*Summary); ok && s != nil {
+			return s
+		}
+	}
+	return State
+}
 
-func init() {
-	State.Probes = make(map[string]*Probe)
-	State.Meta = make(map[string]interface{})
-	State.Meta["names of pods created"] = []string{}
+type probeKey struct{}
+
+// WithProbe returns a copy of the context that carries the key of the probe being run, see ProbeLog
+func WithProbe(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, probeKey{}, key)
+}
+
+// ProbeLog returns the log of the probe being run with ctx, from the summary carried by ctx. The probe is
+// found by the key carried by ctx, which is unique across packs, or by name if ctx carries no key.
+func ProbeLog(ctx context.Context, name string) *Probe {
+	if ctx != nil {
+		if key, ok := ctx.Value(probeKey{}).(string); ok && key != "" {
+			name = key
+		}
+	}
+	return FromContext(ctx).GetProbeLog(name)
+}
+
+// config returns the config of the run that the summary belongs to
+func (s *Summary) config() *config.VarOptions {
+	if s.vars == nil {
+		return &config.Vars
+	}
+	return s.vars
 }
 
 // PrintSummary will print the current Probes object state, formatted to JSON, if NoSummary is not "true"
-func (s *summaryState) PrintSummary() {
-	if config.Vars.NoSummary == true {
+func (s *Summary) PrintSummary() {
+	vars := s.config()
+	if vars.NoSummary == true {
 		log.Printf("[NOTICE] Summary Log suppressed by configuration NoSummary=true.")
 	} else {
+		s.lock.RLock()
+		defer s.lock.RUnlock()
 		summary, _ := json.MarshalIndent(s, "", "  ")
-		log.Printf("Finished\n%s", summary) // Summary output should not be handled by log levels
+		log.Printf("Finished\n%s", vars.Redact(string(summary))) // Summary output should not be handled by log levels
 	}
 }
 
 // WriteSummary will write the summary to the audit directory
-func (s *summaryState) WriteSummary() {
-	if config.Vars.AuditEnabled == "true" {
-		path := filepath.Join(config.Vars.GetWriteDirectory(), "summary.json")
-		if utils.WriteAllowed(path, config.Vars.Overwrite()) {
+func (s *Summary) WriteSummary() {
+	vars := s.config()
+	if vars.AuditEnabled {
+		path := filepath.Join(vars.GetWriteDirectory(), "summary.json")
+		if utils.WriteAllowed(path, vars.Overwrite()) {
+			s.lock.RLock()
+			defer s.lock.RUnlock()
 			json, _ := json.MarshalIndent(s, "", "  ")
-			data := []byte(json)
+			data := []byte(vars.Redact(string(json)))
 			ioutil.WriteFile(path, data, 0755)
 		}
 	}
 }
 
-// SetProbrStatus evaluates the current summaryState state to set the Status
-func (s *summaryState) SetProbrStatus() {
-	if s.ProbesPassed > 0 && s.ProbesFailed == 0 {
+// SetProbrStatus evaluates the current Summary state to set the Status
+func (s *Summary) SetProbrStatus() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.ProbesPassed > 0 && s.ProbesFailed == 0 && s.ProbesTimedOut == 0 && s.ProbesInconclusive == 0 && s.ProbesGivenNotMet == 0 && s.ProbesPending == 0 {
 		s.Status = "Complete - All Probes Completed Successfully"
 	} else {
 		s.Status = fmt.Sprintf("Complete - %v of %v Probes Failed", s.ProbesFailed, (len(s.Probes) - s.ProbesSkipped))
+		if s.ProbesInconclusive > 0 {
+			s.Status = fmt.Sprintf("%s, %v Inconclusive", s.Status, s.ProbesInconclusive)
+		}
+		if s.ProbesPending > 0 {
+			s.Status = fmt.Sprintf("%s, %v Pending", s.Status, s.ProbesPending)
+		}
+		if s.ProbesGivenNotMet > 0 {
+			s.Status = fmt.Sprintf("%s, %v Given Not Met", s.Status, s.ProbesGivenNotMet)
+		}
+		if s.ProbesTimedOut > 0 {
+			s.Status = fmt.Sprintf("%s, %v Timed Out", s.Status, s.ProbesTimedOut)
+		}
 	}
 }
 
+// SetWaivers records the active and expired waivers of the current config
+func (s *Summary) SetWaivers() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.Waivers, s.ExpiredWaivers = s.config().Waivers()
+}
+
+// SetProfile records the vars file profile that the run used
+func (s *Summary) SetProfile() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.Profile = s.config().Profile
+}
+
 // LogProbeMeta accepts a test name with a key and value to insert to the meta logs for that test. Overwrites key if already present.
-func (s *summaryState) LogProbeMeta(name string, key string, value interface{}) {
-	e := s.GetProbeLog(name)
+func (s *Summary) LogProbeMeta(name string, key string, value interface{}) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	e := s.getProbeLog(name)
 	e.Meta[key] = value
 	s.Probes[name] = e
 	s.Probes[name].name = name // probe must be able to access its own name, but it is not publicly printed
 }
 
 // ProbeComplete takes an probe name and status then updates the summary & probe meta information
-func (s *summaryState) ProbeComplete(name string) {
-	e := s.GetProbeLog(name)
+func (s *Summary) ProbeComplete(name string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	e := s.getProbeLog(name)
 	s.completeProbe(e)
 	e.audit.Write()
 }
 
-// GetProbeLog initializes or returns existing log probe for the provided test name
-func (s *summaryState) GetProbeLog(n string) *Probe {
-	s.initProbe(n)
+// AddProbe initializes the log of a probe, whose audit is written to the named file in the audit directory
+func (s *Summary) AddProbe(key, fileName string) *Probe {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.initProbe(key, fileName)
+	return s.Probes[key]
+}
+
+// GetProbeLog initializes or returns existing log probe for the provided test name.
+// The returned Probe should only be modified by the routine that is executing that probe.
+func (s *Summary) GetProbeLog(n string) *Probe {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.getProbeLog(n)
+}
+
+func (s *Summary) getProbeLog(n string) *Probe {
+	s.initProbe(n, strings.Replace(n, "/", "-", -1))
 	return s.Probes[n]
 }
 
 // LogPodName adds pod names to a list for user's debugging purposes
-func (s *summaryState) LogPodName(n string) {
+func (s *Summary) LogPodName(n string) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
 	podNames := s.Meta["names of pods created"].([]string)
 	podNames = append(podNames, n)
+	sort.Strings(podNames) // Keep the summary deterministic regardless of the order probes complete in
 
 	s.Meta["names of pods created"] = podNames
 }
 
-func (s *summaryState) initProbe(n string) {
+func (s *Summary) initProbe(n, fileName string) {
 	if s.Probes[n] == nil {
-		ap := filepath.Join(config.Vars.AuditDir(), (n + ".json")) // Needed in both probe and ProbeAudit
+		ap := filepath.Join(s.config().AuditDir(), (fileName + ".json")) // Needed in both probe and ProbeAudit
 		s.Probes[n] = &Probe{
 			name:          n,
+			summary:       s,
 			Meta:          make(map[string]interface{}),
 			PodsDestroyed: 0,
 			audit: &ProbeAudit{
 				Name: n,
 				path: ap,
+				vars: s.vars,
 			},
 		}
 		s.Probes[n].Meta["audit_path"] = ap // Meta is open for extension, any similar data can be stored there as needed
@@ -108,25 +245,44 @@ func (s *summaryState) initProbe(n string) {
 		s.Probes[n].audit.ScenariosAttempted = &s.Probes[n].ScenariosAttempted
 		s.Probes[n].audit.ScenariosSucceeded = &s.Probes[n].ScenariosSucceeded
 		s.Probes[n].audit.ScenariosFailed = &s.Probes[n].ScenariosFailed
+		s.Probes[n].audit.ScenariosInconclusive = &s.Probes[n].ScenariosInconclusive
+		s.Probes[n].audit.ScenariosGivenNotMet = &s.Probes[n].ScenariosGivenNotMet
+		s.Probes[n].audit.ScenariosPending = &s.Probes[n].ScenariosPending
 		s.Probes[n].audit.Result = &s.Probes[n].Result
 	}
 }
 
-func (s *summaryState) completeProbe(e *Probe) {
+func (s *Summary) completeProbe(e *Probe) {
 	e.countResults()
-	if e.Result == "Excluded" {
+	switch {
+	case e.Result == "Excluded":
 		e.Meta["audit_path"] = ""
-		s.ProbesSkipped = s.ProbesSkipped + 1
-	} else if len(e.audit.Scenarios) < 1 {
+	case e.Result == "TimedOut":
+		// Keep the result set by the probe runner, the audit holds whichever scenarios did run
+	case len(e.audit.Scenarios) < 1:
 		e.Result = "No Scenarios Executed"
 		e.Meta["audit_path"] = ""
-		s.ProbesSkipped = s.ProbesSkipped + 1
-	} else if e.ScenariosFailed < 1 {
+	// The result follows the same rules as the status that the probe runner sets, see Probe.IsInconclusive
+	case e.IsInconclusive():
+		e.Result = "Inconclusive"
+	case e.IsPending():
+		e.Result = "Pending" // At least one control has not been fully implemented
+	case e.IsGivenNotMet():
+		e.Result = "Given Not Met" // At least one scenario did not apply, and no control failed
+	case e.ScenariosFailed < 1:
 		e.Result = "Success"
-		s.ProbesPassed = s.ProbesPassed + 1
-	} else {
+	default:
 		e.Result = "Failed"
-		s.ProbesFailed = s.ProbesFailed + 1
+	}
+	s.probeCounts.add(e.Result)
+	if pack := e.packName(); pack != "" {
+		if s.Packs == nil {
+			s.Packs = make(map[string]*probeCounts)
+		}
+		if s.Packs[pack] == nil {
+			s.Packs[pack] = &probeCounts{}
+		}
+		s.Packs[pack].add(e.Result)
 	}
 	if len(s.Meta["names of pods created"].([]string)) == 0 {
 		s.Meta["pod creation error"] = "An error appears to have occurred while creating pods. Please ensure that proper config vars were set for this Probr execution."
@@ -134,3 +290,22 @@ func (s *summaryState) completeProbe(e *Probe) {
 		delete(s.Meta, "pod creation error")
 	}
 }
+
+func (c *probeCounts) add(result string) {
+	switch result {
+	case "Success":
+		c.ProbesPassed = c.ProbesPassed + 1
+	case "Failed":
+		c.ProbesFailed = c.ProbesFailed + 1
+	case "TimedOut":
+		c.ProbesTimedOut = c.ProbesTimedOut + 1
+	case "Inconclusive":
+		c.ProbesInconclusive = c.ProbesInconclusive + 1
+	case "Given Not Met":
+		c.ProbesGivenNotMet = c.ProbesGivenNotMet + 1
+	case "Pending":
+		c.ProbesPending = c.ProbesPending + 1
+	default:
+		c.ProbesSkipped = c.ProbesSkipped + 1
+	}
+}
@@ -1,18 +1,24 @@
 package audit
 
 import (
+	"context"
 	"fmt"
+	"io/ioutil"
+	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
 	"testing"
 
 	"github.com/citihub/probr/config"
 	"github.com/citihub/probr/utils"
+	"github.com/cucumber/godog"
 )
 
 func TestSummaryState_LogPodName(t *testing.T) {
 
-	var fakeSummaryState summaryState
+	var fakeSummaryState Summary
 	fakeSummaryState.Probes = make(map[string]*Probe)
 	fakeSummaryState.Meta = make(map[string]interface{})
 	fakeSummaryState.Meta["names of pods created"] = []string{}
@@ -22,7 +28,7 @@ func TestSummaryState_LogPodName(t *testing.T) {
 	}
 	tests := []struct {
 		testName string
-		s        *summaryState
+		s        *Summary
 		args     args
 	}{
 		{
@@ -44,9 +50,36 @@ func TestSummaryState_LogPodName(t *testing.T) {
 	}
 }
 
-// createMockProbe - creates a mock summaryState and probe object in it and returns summaryState object.
-func createSummaryStateWithMockProbe(probename string) summaryState {
-	var sumstate summaryState
+func TestSummaryState_ConcurrentAccess(t *testing.T) {
+	sumstate := createSummaryStateWithMockProbe("testProbe")
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			name := fmt.Sprintf("probe_%v", i)
+			sumstate.LogProbeMeta(name, "key", i)
+			sumstate.LogPodName(fmt.Sprintf("pod_%v", i))
+			sumstate.ProbeComplete(name)
+		}(i)
+	}
+	wg.Wait()
+
+	if len(sumstate.Probes) != 11 {
+		t.Errorf("Expected 11 probes in summary state, found %v", len(sumstate.Probes))
+	}
+	if sumstate.ProbesSkipped != 10 {
+		t.Errorf("Expected 10 probes to be skipped, found %v", sumstate.ProbesSkipped)
+	}
+	podNames := sumstate.Meta["names of pods created"].([]string)
+	if len(podNames) != 10 || !sort.StringsAreSorted(podNames) {
+		t.Errorf("Pod names were not all logged in order: %v", podNames)
+	}
+}
+
+// createMockProbe - creates a mock Summary and probe object in it and returns Summary object.
+func createSummaryStateWithMockProbe(probename string) *Summary {
+	sumstate := new(Summary)
 	sumstate.Probes = make(map[string]*Probe)
 	sumstate.Meta = make(map[string]interface{})
 	sumstate.Meta["names of pods created"] = []string{}
@@ -79,19 +112,19 @@ func TestSummaryState_initProbe(t *testing.T) {
 
 	tests := []struct {
 		testName string
-		s        *summaryState
+		s        *Summary
 		args     args
 	}{
 		{
 			testName: "TestProbeInitialized",
-			s:        &mockSummaryState,
+			s:        mockSummaryState,
 			args:     args{fakeName: "testProbe"},
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.testName, func(t *testing.T) {
-			tt.s.initProbe(tt.args.fakeName)
-			tt.s.initProbe("AnotherProbe")
+			tt.s.initProbe(tt.args.fakeName, tt.args.fakeName)
+			tt.s.initProbe("AnotherProbe", "AnotherProbe")
 			createdProbes := mockSummaryState.Probes
 			v, found := createdProbes["testProbe"]
 			if !found {
@@ -113,26 +146,26 @@ func TestSummaryState_SetProbrStatus(t *testing.T) {
 	}
 	tests := []struct {
 		testName       string
-		s              *summaryState
+		s              *Summary
 		expectedResult string
 		args           args
 	}{
 		{
 			testName:       "SetProbrStatus",
-			s:              &mockSummaryState,
+			s:              mockSummaryState,
 			expectedResult: "Complete - All Probes Completed Successfully",
 			args:           args{probeName: "testProbe", probesPassed: 1, probesFailed: 0},
 		}, {
 			testName:       "SetProbrStatus_WithFailedProbes",
-			s:              &mockSummaryState,
+			s:              mockSummaryState,
 			expectedResult: fmt.Sprintf("Complete - %v of %v Probes Failed", 1, 2),
 			args:           args{probeName: "testProbe", probesPassed: 0, probesFailed: 1},
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.testName, func(t *testing.T) {
-			tt.s.initProbe(tt.args.probeName)
-			tt.s.initProbe("anotherPod")
+			tt.s.initProbe(tt.args.probeName, tt.args.probeName)
+			tt.s.initProbe("anotherPod", "anotherPod")
 			tt.s.ProbesPassed = tt.args.probesPassed
 			tt.s.ProbesFailed = tt.args.probesFailed
 			tt.s.ProbesSkipped = 0
@@ -146,7 +179,7 @@ func TestSummaryState_SetProbrStatus(t *testing.T) {
 
 func TestSummaryState_LogProbeMeta(t *testing.T) {
 
-	var mockSummaryState summaryState
+	var mockSummaryState Summary
 	mockSummaryState.Probes = make(map[string]*Probe)
 	mockSummaryState.Meta = make(map[string]interface{})
 	mockSummaryState.Meta["names of pods created"] = []string{}
@@ -158,7 +191,7 @@ func TestSummaryState_LogProbeMeta(t *testing.T) {
 	}
 	tests := []struct {
 		testName       string
-		s              *summaryState
+		s              *Summary
 		expectedResult string
 		args           args
 	}{
@@ -181,7 +214,7 @@ func TestSummaryState_LogProbeMeta(t *testing.T) {
 
 func TestSummaryState_GetProbeLog(t *testing.T) {
 	var probeName = "testProbe"
-	var mockSummaryState summaryState
+	var mockSummaryState Summary
 	mockSummaryState.Probes = make(map[string]*Probe)
 	mockSummaryState.Meta = make(map[string]interface{})
 	mockSummaryState.Meta["names of pods created"] = []string{}
@@ -192,7 +225,7 @@ func TestSummaryState_GetProbeLog(t *testing.T) {
 	}
 	tests := []struct {
 		testName string
-		s        *summaryState
+		s        *Summary
 		want     *Probe
 		args     args
 	}{
@@ -207,7 +240,7 @@ func TestSummaryState_GetProbeLog(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.testName, func(t *testing.T) {
 			if got := tt.s.GetProbeLog(tt.args.name); strings.Compare(got.name, tt.want.name) > 0 {
-				t.Errorf("summaryState.GetProbeLog() = %v, want %v", got, tt.want)
+				t.Errorf("Summary.GetProbeLog() = %v, want %v", got, tt.want)
 			}
 		})
 	}
@@ -234,13 +267,13 @@ func TestSummaryState_completeProbe(t *testing.T) {
 	}
 	tests := []struct {
 		testName string
-		s        *summaryState
+		s        *Summary
 		args
 		expectedResult string
 	}{
 		{
 			testName:       "completeProbe",
-			s:              &mockSummaryState,
+			s:              mockSummaryState,
 			args:           args{e: mockSummaryState.Probes[probeName]},
 			expectedResult: "Success",
 		},
@@ -250,9 +283,288 @@ func TestSummaryState_completeProbe(t *testing.T) {
 			tt.s.completeProbe(tt.args.e)
 
 			if strings.Compare(tt.args.e.Result, tt.expectedResult) > 0 {
-				t.Errorf("summaryState.completeProbe() = %v, want %v", tt.args.e.Result, tt.expectedResult)
+				t.Errorf("Summary.completeProbe() = %v, want %v", tt.args.e.Result, tt.expectedResult)
 			}
 
 		})
 	}
 }
+
+func TestSummaryState_completeProbe_PackCounts(t *testing.T) {
+	tests := []struct {
+		testName     string
+		pack         string
+		provider     string
+		expectedPack string
+	}{
+		{testName: "packWithProvider", pack: "storage", provider: "azure", expectedPack: "storage/azure"},
+		{testName: "packWithoutProvider", pack: "kubernetes", expectedPack: "kubernetes"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			mockSummaryState := createSummaryStateWithMockProbe("testProbe")
+			probe := mockSummaryState.Probes["testProbe"]
+			probe.Meta["pack"] = tt.pack
+			if tt.provider != "" {
+				probe.Meta["provider"] = tt.provider
+			}
+			mockSummaryState.completeProbe(probe) // No scenarios were run, so the probe is skipped
+
+			counts, ok := mockSummaryState.Packs[tt.expectedPack]
+			if !ok {
+				t.Fatalf("Summary.Packs has no entry for '%s': %v", tt.expectedPack, mockSummaryState.Packs)
+			}
+			if counts.ProbesSkipped != 1 || mockSummaryState.ProbesSkipped != 1 {
+				t.Errorf("Skipped probe was not counted for both the pack and the summary")
+			}
+		})
+	}
+}
+
+func TestSummaryState_completeProbe_TimedOut(t *testing.T) {
+	mockSummaryState := createSummaryStateWithMockProbe("testProbe")
+	probe := mockSummaryState.Probes["testProbe"]
+	probe.Result = "TimedOut"
+
+	mockSummaryState.completeProbe(probe)
+	mockSummaryState.SetProbrStatus()
+
+	if probe.Result != "TimedOut" {
+		t.Errorf("Summary.completeProbe() = %v, want TimedOut", probe.Result)
+	}
+	if mockSummaryState.ProbesTimedOut != 1 || mockSummaryState.ProbesSkipped != 0 {
+		t.Errorf("Timed out probe was not counted as timed out: %+v", mockSummaryState.probeCounts)
+	}
+	if !strings.Contains(mockSummaryState.Status, "1 Timed Out") {
+		t.Errorf("Probr status does not report the timed out probe: %s", mockSummaryState.Status)
+	}
+}
+
+func TestSummaryState_completeProbe_Inconclusive(t *testing.T) {
+	tests := []struct {
+		testName       string
+		stepErrors     []error
+		expectedResult string
+		inconclusive   int
+	}{
+		{
+			testName:       "infrastructureErrorIsInconclusive",
+			stepErrors:     []error{utils.InfrastructureError("cluster unreachable")},
+			expectedResult: "Inconclusive",
+			inconclusive:   1,
+		},
+		{
+			testName:       "controlFailureTakesPrecedence",
+			stepErrors:     []error{utils.InfrastructureError("cluster unreachable"), fmt.Errorf("control not met")},
+			expectedResult: "Failed",
+			inconclusive:   0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			mockSummaryState := createSummaryStateWithMockProbe("testProbe")
+			probe := mockSummaryState.Probes["testProbe"]
+			for i, err := range tt.stepErrors {
+				scenario := probe.InitializeAuditor(fmt.Sprintf("scenario %v", i), nil)
+				scenario.AuditStep("given", "a given step", "", nil, nil) // Ensure the failure is not the first step
+				scenario.AuditStep("then", "a then step", "", nil, err)
+			}
+
+			mockSummaryState.completeProbe(probe)
+
+			if probe.Result != tt.expectedResult {
+				t.Errorf("Summary.completeProbe() = %v, want %v", probe.Result, tt.expectedResult)
+			}
+			if mockSummaryState.ProbesInconclusive != tt.inconclusive {
+				t.Errorf("Summary.ProbesInconclusive = %v, want %v", mockSummaryState.ProbesInconclusive, tt.inconclusive)
+			}
+		})
+	}
+}
+
+func TestSummaryState_completeProbe_GivenNotMet(t *testing.T) {
+	tests := []struct {
+		testName       string
+		stepErrors     []error
+		expectedResult string
+		givenNotMet    int
+	}{
+		{
+			testName:       "allGivenNotMet",
+			stepErrors:     []error{utils.GivenNotMet("no MIC pod found")},
+			expectedResult: "Given Not Met",
+			givenNotMet:    1,
+		},
+		{
+			testName:       "otherScenarioPassed",
+			stepErrors:     []error{utils.GivenNotMet("no MIC pod found"), nil},
+			expectedResult: "Given Not Met",
+			givenNotMet:    1,
+		},
+		{
+			testName:       "otherScenarioFailed",
+			stepErrors:     []error{utils.GivenNotMet("no MIC pod found"), fmt.Errorf("control not met")},
+			expectedResult: "Failed",
+			givenNotMet:    0,
+		},
+		{
+			testName:       "firstStepFailureIsAFailure",
+			stepErrors:     []error{fmt.Errorf("control not met")},
+			expectedResult: "Failed",
+			givenNotMet:    0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			mockSummaryState := createSummaryStateWithMockProbe("testProbe")
+			probe := mockSummaryState.Probes["testProbe"]
+			for i, err := range tt.stepErrors {
+				scenario := probe.InitializeAuditor(fmt.Sprintf("scenario %v", i), nil)
+				scenario.AuditStep("given", "a given step", "", nil, err)
+			}
+
+			mockSummaryState.completeProbe(probe)
+			mockSummaryState.SetProbrStatus()
+
+			if probe.Result != tt.expectedResult {
+				t.Errorf("Summary.completeProbe() = %v, want %v", probe.Result, tt.expectedResult)
+			}
+			if probe.IsGivenNotMet() != (probe.Result == "Given Not Met") {
+				t.Errorf("Probe.IsGivenNotMet() = %v, but the summary result is %v", probe.IsGivenNotMet(), probe.Result)
+			}
+			if mockSummaryState.ProbesGivenNotMet != tt.givenNotMet {
+				t.Errorf("Summary.ProbesGivenNotMet = %v, want %v", mockSummaryState.ProbesGivenNotMet, tt.givenNotMet)
+			}
+			if tt.givenNotMet > 0 && !strings.Contains(mockSummaryState.Status, "Given Not Met") {
+				t.Errorf("Probr status does not report the probe whose preconditions were not met: %s", mockSummaryState.Status)
+			}
+		})
+	}
+}
+
+func TestSummaryState_completeProbe_Pending(t *testing.T) {
+	tests := []struct {
+		testName       string
+		steps          [][]error
+		expectedResult string
+		pending        int
+	}{
+		{
+			testName:       "pendingStepIsNotOverwrittenByLaterSteps",
+			steps:          [][]error{{nil, godog.ErrPending, nil}},
+			expectedResult: "Pending",
+			pending:        1,
+		},
+		{
+			testName:       "pendingAlongsidePassed",
+			steps:          [][]error{{nil, nil}, {godog.ErrPending}},
+			expectedResult: "Pending",
+			pending:        1,
+		},
+		{
+			testName:       "failureTakesPrecedence",
+			steps:          [][]error{{godog.ErrPending, fmt.Errorf("control not met")}},
+			expectedResult: "Failed",
+			pending:        0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			mockSummaryState := createSummaryStateWithMockProbe("testProbe")
+			probe := mockSummaryState.Probes["testProbe"]
+			for i, stepErrors := range tt.steps {
+				scenario := probe.InitializeAuditor(fmt.Sprintf("scenario %v", i), nil)
+				for _, err := range stepErrors {
+					scenario.AuditStep("step", "a step", "", nil, err)
+				}
+			}
+
+			mockSummaryState.completeProbe(probe)
+			mockSummaryState.SetProbrStatus()
+
+			if probe.Result != tt.expectedResult {
+				t.Errorf("Summary.completeProbe() = %v, want %v", probe.Result, tt.expectedResult)
+			}
+			if mockSummaryState.ProbesPending != tt.pending {
+				t.Errorf("Summary.ProbesPending = %v, want %v", mockSummaryState.ProbesPending, tt.pending)
+			}
+			if tt.pending > 0 && !strings.Contains(mockSummaryState.Status, "Pending") {
+				t.Errorf("Probr status does not report the pending probe: %s", mockSummaryState.Status)
+			}
+		})
+	}
+}
+
+func TestSummaryState_WriteSummary_Redacted(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "probr-summary")
+	defer os.RemoveAll(dir)
+	previous := config.Vars
+	defer func() { config.Vars = previous }()
+	config.Vars, _ = config.NewConfig("")
+	config.Vars.WriteDirectory = dir
+	config.Vars.Set("CloudProviders.Azure.ClientSecret", "plain-secret", "--set")
+
+	var s Summary
+	s.Probes = make(map[string]*Probe)
+	s.Meta = map[string]interface{}{"error": "login failed for plain-secret", "url": "https://a.blob.core.windows.net/c?sig=abc123"}
+	s.WriteSummary()
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, "summary.json"))
+	if err != nil {
+		t.Fatalf("Summary was not written: %v", err)
+	}
+	if strings.Contains(string(data), "plain-secret") || strings.Contains(string(data), "abc123") {
+		t.Errorf("Secrets were not redacted from the summary:\n%s", data)
+	}
+}
+
+func TestSummaryState_SetProfile(t *testing.T) {
+	defer func() { config.Vars.Profile = "" }()
+	config.Vars.Profile = "prod"
+	s := &Summary{}
+	s.SetProfile()
+	if s.Profile != "prod" {
+		t.Errorf("SetProfile() recorded '%s', expected 'prod'", s.Profile)
+	}
+}
+
+func TestNewSummary_UsesItsOwnConfig(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "probr-summary")
+	defer os.RemoveAll(dir)
+	vars, _ := config.NewConfig("")
+	vars.WriteDirectory = dir
+	vars.Profile = "prod"
+
+	s := NewSummary(&vars)
+	s.SetProfile()
+	probe := s.GetProbeLog("testProbe")
+	probe.InitializeAuditor("scenario", nil)
+	probe.CountPodCreated("testPod")
+	s.ProbeComplete("testProbe")
+	s.WriteSummary()
+
+	if s.Profile != "prod" {
+		t.Errorf("SetProfile() recorded '%s', expected the profile of the summary's config", s.Profile)
+	}
+	if pods := s.Meta["names of pods created"].([]string); len(pods) != 1 {
+		t.Errorf("Pod was not logged in the probe's own summary: %v", pods)
+	}
+	if pods := State.Meta["names of pods created"].([]string); len(pods) != 0 {
+		t.Errorf("Pod was logged in State: %v", pods)
+	}
+	for _, path := range []string{filepath.Join(dir, "summary.json"), filepath.Join(dir, "audit", "testProbe.json")} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("Expected %s to be written to the summary's write directory: %v", path, err)
+		}
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	s := NewSummary(&config.Vars)
+	if got := FromContext(WithSummary(context.Background(), s)); got != s {
+		t.Errorf("FromContext() did not return the summary carried by the context")
+	}
+	if got := FromContext(context.Background()); got != State {
+		t.Errorf("FromContext() = %p, expected State when the context carries no summary", got)
+	}
+}
@@ -0,0 +1,303 @@
+package cliflags
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"strings"
+
+	"github.com/citihub/probr/service_packs/coreengine"
+)
+
+// Command is a single command of the probr CLI, such as `run` or `config show`
+type Command struct {
+	Name     string
+	Args     string            // Describes the positional arguments in the usage line, e.g. "[PACK]"
+	Summary  string            // One line description, shown in the help of the parent command
+	Flags    []string          // Names of the flags accepted by the command, in addition to the global flags
+	Formats  []string          // Output formats accepted by the -output flag, the first being the default
+	Switches map[string]string // Boolean flags that only change the output of the command, with their usage
+	NoConfig bool              // Set if the command does not read the config, and so accepts no global flags
+	Run      func(inv *Invocation) int
+	Commands []*Command // Subcommands. A command with subcommands is only used to group them.
+}
+
+// Invocation holds everything that a command was called with
+type Invocation struct {
+	Ctx    context.Context // Cancelled if the command is aborted
+	Args   []string        // Positional arguments, with flags removed
+	Format string          // Value of the -output flag, for commands that have Formats
+	Out    io.Writer
+
+	switches map[string]*bool
+}
+
+// Switch returns whether the named switch of the command was passed
+func (inv *Invocation) Switch(name string) bool {
+	value, ok := inv.switches[name]
+	return ok && *value
+}
+
+// runFlags are the flags that affect which probes are run and how
+var runFlags = []string{"kubeconfig", "writedirectory", "tags", "resultsformat", "plugindirectory", "timeout", "concurrency", "silent", "nosummary", "strict", "set"}
+
+// root is the top of the command tree. It is built in init to avoid an initialization loop with helpCommand.
+var root *Command
+
+func init() {
+	root = &Command{
+		Name: "probr",
+		Commands: []*Command{
+			{
+				Name:    "run",
+				Args:    "[PACK | PACK/PROBE | PACK/PROBE/@TAG | @TAG]...",
+				Summary: "Run the probes of every included service pack, or only the selected packs, probes and scenarios",
+				Flags:   runFlags,
+				Run:     runCommand,
+			},
+			{
+				Name:    "plan",
+				Args:    "[PACK | PACK/PROBE | PACK/PROBE/@TAG | @TAG]...",
+				Summary: "Show which scenarios would be run and why any others are excluded, without running them",
+				Flags:   []string{"kubeconfig", "writedirectory", "tags", "plugindirectory", "set"},
+				Formats: []string{"text", "json"},
+				Run:     planCommand,
+			},
+			{
+				Name:    "list",
+				Summary: "List every service pack, probe and scenario, along with their tags",
+				Flags:   []string{"writedirectory", "plugindirectory"},
+				Formats: []string{"table", "json", "yaml"},
+				Run:     listCommand,
+			},
+			{
+				Name:    "cleanup",
+				Summary: "Delete the pods, storage accounts and other resources that probes created but did not delete, e.g. as a run was aborted",
+				Flags:   []string{"writedirectory", "set"},
+				Run:     cleanupCommand,
+			},
+			{
+				Name:     "show-requirements",
+				Args:     "[PACK]",
+				Summary:  "Show the config vars that each service pack requires",
+				NoConfig: true,
+				Run:      showRequirementsCommand,
+			},
+			{
+				Name:    "config",
+				Summary: "Create, inspect and validate the config",
+				Commands: []*Command{
+					{
+						Name:     "show",
+						Summary:  "Show the config that would be used, after applying the vars file, environment and flags",
+						Flags:    runFlags,
+						Formats:  []string{"yaml", "json"},
+						Switches: map[string]string{"origin": "show where each value was set: default, vars file, profile, env or flag"},
+						Run:      configShowCommand,
+					},
+					{
+						Name:     "init",
+						Args:     "[FILE]",
+						Summary:  "Write a commented vars file listing every config var and the probes and scenarios that may be excluded",
+						Flags:    []string{"writedirectory"},
+						Switches: map[string]string{"force": "overwrite FILE if it already exists"},
+						Run:      configInitCommand,
+					},
+					{
+						Name:     "validate",
+						Args:     "VARSFILE",
+						Summary:  "Check a vars file for unknown keys and invalid values, without connecting to any cluster or cloud provider",
+						NoConfig: true,
+						Run:      configValidateCommand,
+					},
+				},
+			},
+			{
+				Name:     "version",
+				Summary:  "Show the version of probr",
+				NoConfig: true,
+				Run:      versionCommand,
+			},
+			{
+				Name:     "help",
+				Args:     "[COMMAND]",
+				Summary:  "Show the help for a command",
+				NoConfig: true,
+				Run:      helpCommand,
+			},
+		},
+	}
+}
+
+// Execute runs the command named by the arguments, which should not include the program name, and
+// returns the exit code. If the arguments start with a flag, or are empty, the run command is used.
+// Commands that run probes or delete resources stop early once ctx is cancelled.
+func Execute(ctx context.Context, args []string, out io.Writer) int {
+	path, args := findCommand(args)
+	cmd := path[len(path)-1]
+	if cmd.Run == nil {
+		if len(args) > 0 && !isHelpFlag(args[0]) {
+			log.Printf("[ERROR] Unknown command '%s'", strings.TrimSpace(commandName(path)+" "+args[0]))
+			writeHelp(out, path)
+			return coreengine.ExitInternalError
+		}
+		writeHelp(out, path)
+		if len(args) > 0 {
+			return coreengine.ExitSuccess
+		}
+		return coreengine.ExitInternalError
+	}
+
+	fs := flag.NewFlagSet(commandName(path), flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard) // Errors are logged, and help is written by writeHelp
+	var flags []Flag
+	if !cmd.NoConfig {
+		flags = defineFlags(fs, append(globalFlags, cmd.Flags...)...)
+	}
+	inv := &Invocation{Ctx: ctx, Out: out, switches: make(map[string]*bool)}
+	if len(cmd.Formats) > 0 {
+		fs.StringVar(&inv.Format, "output", cmd.Formats[0], fmt.Sprintf("output format, one of %s", strings.Join(cmd.Formats, ", ")))
+	}
+	for name, usage := range cmd.Switches {
+		inv.switches[name] = fs.Bool(name, false, usage)
+	}
+
+	positional, err := parseInterspersed(fs, args)
+	if err == flag.ErrHelp {
+		writeHelp(out, path)
+		return coreengine.ExitSuccess
+	}
+	if err != nil {
+		log.Printf("[ERROR] %v. Run '%s -h' for usage.", err, commandName(path))
+		return coreengine.ExitInternalError
+	}
+	if len(cmd.Formats) > 0 {
+		if _, found := findFold(cmd.Formats, inv.Format); !found {
+			log.Printf("[ERROR] Unknown output format '%s'. Must be one of %v", inv.Format, cmd.Formats)
+			return coreengine.ExitInternalError
+		}
+		inv.Format = strings.ToLower(inv.Format)
+	}
+	inv.Args = positional
+
+	if err := HandleFlags(fs, flags); err != nil {
+		log.Print(err)
+		return coreengine.ExitInternalError
+	}
+	return cmd.Run(inv)
+}
+
+// findCommand walks the command tree using the leading arguments, returning the path to the
+// command that was found and the arguments that remain
+func findCommand(args []string) ([]*Command, []string) {
+	if len(args) == 0 || (strings.HasPrefix(args[0], "-") && !isHelpFlag(args[0])) {
+		return []*Command{root, root.Commands[0]}, args // Default to 'run', as before commands were introduced
+	}
+	path := []*Command{root}
+	for len(args) > 0 {
+		sub := path[len(path)-1].subcommand(args[0])
+		if sub == nil {
+			break
+		}
+		path = append(path, sub)
+		args = args[1:]
+	}
+	return path, args
+}
+
+func (cmd *Command) subcommand(name string) *Command {
+	for _, sub := range cmd.Commands {
+		if sub.Name == name {
+			return sub
+		}
+	}
+	return nil
+}
+
+// parseInterspersed parses flags that appear before, between or after the positional arguments,
+// which the flag package would otherwise stop at
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+func isHelpFlag(arg string) bool {
+	switch arg {
+	case "-h", "-help", "--help":
+		return true
+	}
+	return false
+}
+
+func commandName(path []*Command) string {
+	var names []string
+	for _, cmd := range path {
+		names = append(names, cmd.Name)
+	}
+	return strings.Join(names, " ")
+}
+
+// writeHelp writes the usage of the last command in the path, including its subcommands or flags
+func writeHelp(out io.Writer, path []*Command) {
+	cmd := path[len(path)-1]
+	name := commandName(path)
+	if len(cmd.Commands) > 0 {
+		fmt.Fprintf(out, "Usage: %s <command> [flags]\n", name)
+		if cmd.Summary != "" {
+			fmt.Fprintf(out, "\n%s\n", cmd.Summary)
+		}
+		fmt.Fprintf(out, "\nCommands:\n")
+		for _, sub := range cmd.Commands {
+			fmt.Fprintf(out, "  %-20s%s\n", sub.Name, sub.Summary)
+		}
+		fmt.Fprintf(out, "\nRun '%s <command> -h' for the flags of a command.\n", name)
+		return
+	}
+
+	usage := name
+	if cmd.Args != "" {
+		usage = fmt.Sprintf("%s %s", usage, cmd.Args)
+	}
+	fmt.Fprintf(out, "Usage: %s [flags]\n\n%s\n", usage, cmd.Summary)
+
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	if !cmd.NoConfig {
+		defineFlags(fs, append(globalFlags, cmd.Flags...)...)
+	}
+	if len(cmd.Formats) > 0 {
+		fs.String("output", cmd.Formats[0], fmt.Sprintf("output format, one of %s", strings.Join(cmd.Formats, ", ")))
+	}
+	for name, usage := range cmd.Switches {
+		fs.Bool(name, false, usage)
+	}
+	hasFlags := false
+	fs.VisitAll(func(*flag.Flag) { hasFlags = true })
+	if hasFlags {
+		fmt.Fprintf(out, "\nFlags:\n")
+		fs.SetOutput(out)
+		fs.PrintDefaults()
+	}
+}
+
+// findFold returns the index of val within the slice, ignoring case
+func findFold(slice []string, val string) (int, bool) {
+	for i, item := range slice {
+		if strings.EqualFold(item, val) {
+			return i, true
+		}
+	}
+	return -1, false
+}
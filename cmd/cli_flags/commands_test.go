@@ -0,0 +1,124 @@
+package cliflags
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/citihub/probr/config"
+	"github.com/citihub/probr/service_packs/coreengine"
+)
+
+func TestExecute(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "probr-execute")
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		testName         string
+		args             []string
+		expectedCode     int
+		expectedInOutput string
+	}{
+		{testName: "Help", args: []string{"-h"}, expectedCode: coreengine.ExitSuccess, expectedInOutput: "show-requirements"},
+		{testName: "HelpCommand", args: []string{"help", "config", "show"}, expectedCode: coreengine.ExitSuccess, expectedInOutput: "Usage: probr config show [flags]"},
+		{testName: "CommandHelpFlag", args: []string{"run", "-h"}, expectedCode: coreengine.ExitSuccess, expectedInOutput: "-concurrency"},
+		{testName: "UnknownCommand", args: []string{"walk"}, expectedCode: coreengine.ExitInternalError},
+		{testName: "GroupWithoutSubcommand", args: []string{"config"}, expectedCode: coreengine.ExitInternalError, expectedInOutput: "show"},
+		{testName: "UnknownFlag", args: []string{"list", "-colour"}, expectedCode: coreengine.ExitInternalError},
+		{testName: "UnknownFormat", args: []string{"list", "-output", "xml"}, expectedCode: coreengine.ExitInternalError},
+		{testName: "UnknownPack", args: []string{"run", "not_a_pack", "-writedirectory", dir}, expectedCode: coreengine.ExitInternalError},
+		{testName: "Version", args: []string{"version"}, expectedCode: coreengine.ExitSuccess, expectedInOutput: "probr version"},
+		{testName: "CleanupNothingRecorded", args: []string{"cleanup", "-writedirectory", dir}, expectedCode: coreengine.ExitSuccess, expectedInOutput: "No resources to delete"},
+		{testName: "ShowRequirements", args: []string{"show-requirements", "kubernetes"}, expectedCode: coreengine.ExitSuccess, expectedInOutput: "AuthorisedContainerRegistry"},
+		{testName: "ShowRequirementsUnknownPack", args: []string{"show-requirements", "not_a_pack"}, expectedCode: coreengine.ExitInternalError},
+		{testName: "ConfigValidate", args: []string{"config", "validate", "../../examples/config.yml"}, expectedCode: coreengine.ExitSuccess, expectedInOutput: "is valid"},
+		{testName: "ConfigValidateMissingFile", args: []string{"config", "validate", "not_a_file.yml"}, expectedCode: coreengine.ExitInternalError, expectedInOutput: "is not valid"},
+		{testName: "ConfigValidateWithoutFile", args: []string{"config", "validate"}, expectedCode: coreengine.ExitInternalError},
+		{testName: "ConfigInit", args: []string{"config", "init", "-writedirectory", dir}, expectedCode: coreengine.ExitSuccess, expectedInOutput: "# - Name: iam"},
+		{testName: "ConfigInitFile", args: []string{"config", "init", dir + "/config.yml", "-writedirectory", dir}, expectedCode: coreengine.ExitSuccess, expectedInOutput: "Vars file written"},
+		{testName: "ConfigInitFileExists", args: []string{"config", "init", dir + "/config.yml", "-writedirectory", dir}, expectedCode: coreengine.ExitInternalError},
+		{testName: "ConfigInitForce", args: []string{"config", "init", dir + "/config.yml", "-force", "-writedirectory", dir}, expectedCode: coreengine.ExitSuccess},
+		{testName: "ConfigValidateInit", args: []string{"config", "validate", dir + "/config.yml"}, expectedCode: coreengine.ExitSuccess, expectedInOutput: "is valid"},
+		{testName: "ConfigShowOrigin", args: []string{"config", "show", "-origin", "-set", "ServicePacks.Kubernetes.KubeContext=dev"}, expectedCode: coreengine.ExitSuccess, expectedInOutput: "Value: dev\n  Origin: flag --set"},
+		{testName: "ConfigShowWithFlags", args: []string{"config", "show", "-output", "json", "-tags", "@k-iam"}, expectedCode: coreengine.ExitSuccess, expectedInOutput: `"Tags": "@k-iam"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			code := Execute(context.Background(), tt.args, out)
+			if code != tt.expectedCode {
+				t.Errorf("Execute(%v) = %v, expected %v. Output:\n%s", tt.args, code, tt.expectedCode, out)
+			}
+			if !strings.Contains(out.String(), tt.expectedInOutput) {
+				t.Errorf("Execute(%v) output did not contain '%s':\n%s", tt.args, tt.expectedInOutput, out)
+			}
+		})
+	}
+	config.Vars.Tags = ""
+}
+
+func TestExecute_NoOutput(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "probr-no-output")
+	defer os.RemoveAll(dir)
+	writeDirectory := filepath.Join(dir, "probr_output")
+	varsFile := filepath.Join(dir, "config.yml")
+	ioutil.WriteFile(varsFile, []byte("WriteDirectory: "+writeDirectory+"\n"), 0644)
+
+	tests := []struct {
+		testName string
+		args     []string
+	}{
+		{testName: "Plan", args: []string{"plan", "-writedirectory", writeDirectory}},
+		{testName: "List", args: []string{"list", "-writedirectory", writeDirectory}},
+		{testName: "ConfigInit", args: []string{"config", "init", "-writedirectory", writeDirectory}},
+		{testName: "ConfigValidate", args: []string{"config", "validate", varsFile}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			if code := Execute(context.Background(), tt.args, &bytes.Buffer{}); code != coreengine.ExitSuccess {
+				t.Fatalf("Execute(%v) = %v, expected %v", tt.args, code, coreengine.ExitSuccess)
+			}
+			if _, err := os.Stat(writeDirectory); !os.IsNotExist(err) {
+				t.Errorf("Execute(%v) should not create the write directory", tt.args)
+				os.RemoveAll(writeDirectory)
+			}
+		})
+	}
+}
+
+func TestFindCommand(t *testing.T) {
+	tests := []struct {
+		testName         string
+		args             []string
+		expectedCommand  string
+		expectedRestArgs int
+	}{
+		{testName: "NoArgs", args: []string{}, expectedCommand: "probr run"},
+		{testName: "FlagsOnly", args: []string{"-varsfile", "config.yml"}, expectedCommand: "probr run", expectedRestArgs: 2},
+		{testName: "Subcommand", args: []string{"config", "show", "-output", "json"}, expectedCommand: "probr config show", expectedRestArgs: 2},
+		{testName: "Unknown", args: []string{"walk"}, expectedCommand: "probr", expectedRestArgs: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			path, rest := findCommand(tt.args)
+			if commandName(path) != tt.expectedCommand || len(rest) != tt.expectedRestArgs {
+				t.Errorf("findCommand(%v) = '%s' with %v args, expected '%s' with %v", tt.args, commandName(path), len(rest), tt.expectedCommand, tt.expectedRestArgs)
+			}
+		})
+	}
+}
+
+func TestParseInterspersed(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	tags := fs.String("tags", "", "")
+	silent := fs.Bool("silent", false, "")
+	args, err := parseInterspersed(fs, []string{"-silent", "kubernetes", "-tags", "@k-iam"})
+	if err != nil || len(args) != 1 || args[0] != "kubernetes" || *tags != "@k-iam" || !*silent {
+		t.Errorf("parseInterspersed() = %v, %v with tags '%s' and silent %v", args, err, *tags, *silent)
+	}
+}
@@ -4,146 +4,279 @@ import (
 	"flag"
 	"log"
 	"os"
+	"strings"
 
 	"github.com/citihub/probr/config"
 	"github.com/citihub/probr/utils"
 )
 
-type flagHandlerFunc func(v interface{})
+type flagHandlerFunc func(v interface{}) error
 
 // Flag holds the user-provided value for the flag, and the function to be run within executeHandler
 type Flag struct {
 	// exported to avoid conflict with the default `flag` provided in go
+	Name    string
 	Handler flagHandlerFunc
 	Value   interface{}
 }
 
-var flags []Flag
+func (f Flag) executeHandler() error {
+	return f.Handler(f.Value)
+}
 
-func (f Flag) executeHandler() {
-	f.Handler(f.Value)
+// flagDefinition describes a flag that may be accepted by one or more commands
+type flagDefinition struct {
+	name    string
+	handler flagHandlerFunc
+	define  func(fs *flag.FlagSet) interface{}
 }
 
-// HandleFlags executes the logic for any flags that are provided via `./probr (--<FLAG>)`
-func HandleFlags() {
+// globalFlags are accepted by every command that reads the config
+var globalFlags = []string{"varsfile", "profile", "loglevel"}
 
-	stringFlag("varsfile", "path to config file", varsFileHandler)
-	stringFlag("loglevel", "set log level", loglevelHandler)
-	stringFlag("kubeconfig", "kube config file", kubeConfigHandler)
-	stringFlag("writedirectory", "output directory", writeDirHandler)
-	stringFlag("tags", "feature tags to include or exclude", tagsHandler)
-	stringFlag("resultsformat", "set the bdd results format (default = cucumber)", resultsformatHandler)
-	boolFlag("silent", "disable visual runtime indicator, useful for CI tasks", silentHandler)
-	boolFlag("nosummary", "switch off summary output", nosummaryHandler)
-	flag.Parse()
+// definitions holds every flag in the order that their handlers are executed.
+// The profile handler must come before the varsfile handler, which reads the vars file with the
+// selected profile. The varsfile handler resets the config that the others modify.
+var definitions = []flagDefinition{
+	stringFlag("profile", "name of the profile in the vars file to apply (default = PROBR_PROFILE)", profileHandler),
+	stringFlag("varsfile", "path to config file", varsFileHandler),
+	stringFlag("loglevel", "set log level", loglevelHandler),
+	stringFlag("kubeconfig", "kube config file", kubeConfigHandler),
+	stringFlag("writedirectory", "output directory", writeDirHandler),
+	stringFlag("tags", "feature tags to include or exclude", tagsHandler),
+	stringFlag("resultsformat", "set the bdd results format (default = cucumber)", resultsformatHandler),
+	stringFlag("plugindirectory", "directory containing service pack plugins", pluginDirectoryHandler),
+	stringFlag("timeout", "maximum duration of the whole run, e.g. 1h30m (default = no limit)", timeoutHandler),
+	intFlag("concurrency", "maximum number of probes to run at the same time (default = 1)", concurrencyHandler),
+	boolFlag("silent", "disable visual runtime indicator, useful for CI tasks", silentHandler),
+	boolFlag("nosummary", "switch off summary output", nosummaryHandler),
+	boolFlag("strict", "fail the run if any scenario has pending or undefined steps", strictHandler),
+	listFlag("set", "override a config var, e.g. --set ServicePacks.Kubernetes.KubeContext=dev. May be repeated.", setHandler),
+}
 
-	for _, f := range flags {
-		f.executeHandler()
-	}
+func stringFlag(name string, usage string, handler flagHandlerFunc) flagDefinition {
+	return flagDefinition{name: name, handler: handler, define: func(fs *flag.FlagSet) interface{} {
+		return fs.String(name, "", usage)
+	}}
+}
+
+func intFlag(name string, usage string, handler flagHandlerFunc) flagDefinition {
+	return flagDefinition{name: name, handler: handler, define: func(fs *flag.FlagSet) interface{} {
+		return fs.Int(name, 0, usage)
+	}}
+}
+
+func boolFlag(name string, usage string, handler flagHandlerFunc) flagDefinition {
+	return flagDefinition{name: name, handler: handler, define: func(fs *flag.FlagSet) interface{} {
+		return fs.Bool(name, false, usage)
+	}}
+}
+
+func listFlag(name string, usage string, handler flagHandlerFunc) flagDefinition {
+	return flagDefinition{name: name, handler: handler, define: func(fs *flag.FlagSet) interface{} {
+		values := &stringList{}
+		fs.Var(values, name, usage)
+		return values
+	}}
+}
+
+// stringList is a flag that collects its value each time it is passed
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
 }
 
-func stringFlag(name string, usage string, handler flagHandlerFunc) {
-	f := Flag{
-		Handler: handler,
-		Value:   new(string),
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// defineFlags adds the named flags to the flag set, returning them in the order that they should be handled
+func defineFlags(fs *flag.FlagSet, names ...string) []Flag {
+	var flags []Flag
+	for _, d := range definitions {
+		if _, found := utils.FindString(names, d.name); found {
+			flags = append(flags, Flag{Name: d.name, Handler: d.handler, Value: d.define(fs)})
+		}
 	}
-	v := f.Value.(*string)
-	flag.StringVar(v, name, "", usage)
-	flags = append(flags, f)
+	return flags
 }
 
-func boolFlag(name string, usage string, handler flagHandlerFunc) {
-	f := Flag{
-		Handler: handler,
-		Value:   new(bool),
+// HandleFlags executes the logic for any flags that were parsed by the flag set. The config is
+// always initialized if varsfile was defined, even if it was not passed, with the profile that was
+// passed or none, so a profile from a previous call is not kept. The log level is applied
+// before anything else so that it is respected by the logs of every other handler.
+func HandleFlags(fs *flag.FlagSet, flags []Flag) error {
+	passed := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		passed[f.Name] = true
+	})
+
+	for _, f := range flags {
+		if f.Name == "loglevel" && passed[f.Name] {
+			if err := validateLogLevel(*f.Value.(*string)); err != nil {
+				return err
+			}
+			config.SetLogFilter(*f.Value.(*string), os.Stderr)
+		}
+	}
+	for _, f := range flags {
+		if passed[f.Name] || f.Name == "varsfile" || f.Name == "profile" {
+			if err := f.executeHandler(); err != nil {
+				return err
+			}
+		}
 	}
-	v := f.Value.(*bool)
-	flag.BoolVar(v, name, false, usage)
-	flags = append(flags, f)
+	return nil
 }
 
 // Note:
 // Even though it's a bit ugly, using things like `*v.(*string)` comes from accepting bool, string, and other flag types
 
-// varsFileHandler initializes configuration with VarsFile overriding env vars & defaults
-func varsFileHandler(v interface{}) {
+// varsFileHandler initializes configuration with VarsFile overriding defaults, and env vars overriding VarsFile
+func varsFileHandler(v interface{}) error {
 	err := config.Init(*v.(*string))
 	if err != nil {
-		log.Fatalf("[ERROR] error returned from config.Init: %v", err)
+		return utils.ReformatError("error returned from config.Init: %v", err)
 	} else if len(*v.(*string)) > 0 {
 		config.Vars.VarsFile = *v.(*string)
-		log.Printf("[INFO] Config read from file '%v', but may still be overridden by CLI flags.", v.(*string))
+		log.Printf("[INFO] Config read from file '%v', but may still be overridden by env vars and CLI flags.", *v.(*string))
+		if config.Vars.Profile != "" {
+			log.Printf("[NOTICE] Applied profile '%s' from the vars file", config.Vars.Profile)
+		}
 	} else {
 		log.Printf("[NOTICE] No configuration variables file specified. Using environment variabls and defaults only.")
 	}
+	return nil
+}
+
+// profileHandler selects the profile that varsFileHandler will apply. An empty value leaves it to PROBR_PROFILE.
+func profileHandler(v interface{}) error {
+	config.SelectProfile(*v.(*string))
+	return nil
 }
 
 // writeDirHandler
-func writeDirHandler(v interface{}) {
+func writeDirHandler(v interface{}) error {
 	if len(*v.(*string)) > 0 {
 		log.Printf("[NOTICE] Output Directory has been overridden via command line")
 		config.Vars.WriteDirectory = *v.(*string)
+		flagOrigin("WriteDirectory", "writedirectory")
 	}
+	return nil
 }
 
 // loglevelHandler validates provided value and sets output accordingly
-func loglevelHandler(v interface{}) {
-	if len(*v.(*string)) > 0 {
-		levels := []string{"DEBUG", "INFO", "NOTICE", "WARN", "ERROR"}
-		_, found := utils.FindString(levels, *v.(*string))
-		if !found {
-			log.Fatalf("[ERROR] Unknown loglevel specified: '%s'. Must be one of %v", *v.(*string), levels)
-		} else {
-			config.Vars.LogLevel = *v.(*string)
-			config.SetLogFilter(config.Vars.LogLevel, os.Stderr)
-		}
+func loglevelHandler(v interface{}) error {
+	if err := validateLogLevel(*v.(*string)); err != nil {
+		return err
 	}
+	config.Vars.LogLevel = *v.(*string)
+	flagOrigin("LogLevel", "loglevel")
+	config.SetLogFilter(config.Vars.LogLevel, os.Stderr)
+	return nil
 }
 
-func resultsformatHandler(v interface{}) {
-	if len(*v.(*string)) > 0 {
-		options := []string{"cucumber", "events", "junit", "pretty", "progress"}
-		_, found := utils.FindString(options, *v.(*string))
-		if !found {
-			log.Fatalf("[ERROR] Unknown resultsformat specified: '%s'. Must be one of %v", *v.(*string), options)
-		} else {
-			config.Vars.ResultsFormat = *v.(*string)
-			config.SetLogFilter(config.Vars.ResultsFormat, os.Stderr)
-		}
+func validateLogLevel(level string) error {
+	levels := []string{"DEBUG", "INFO", "NOTICE", "WARN", "ERROR"}
+	if _, found := utils.FindString(levels, level); !found {
+		return utils.ReformatError("Unknown loglevel specified: '%s'. Must be one of %v", level, levels)
 	}
+	return nil
 }
 
-func tagsHandler(v interface{}) {
+func resultsformatHandler(v interface{}) error {
+	options := []string{"cucumber", "events", "junit", "pretty", "progress"}
+	if _, found := utils.FindString(options, *v.(*string)); !found {
+		return utils.ReformatError("Unknown resultsformat specified: '%s'. Must be one of %v", *v.(*string), options)
+	}
+	config.Vars.ResultsFormat = *v.(*string)
+	flagOrigin("ResultsFormat", "resultsformat")
+	return nil
+}
+
+func tagsHandler(v interface{}) error {
 	if len(*v.(*string)) > 0 {
 		config.Vars.Tags = *v.(*string)
+		flagOrigin("Tags", "tags")
 		log.Printf("[INFO] tags have been added via command line.")
 	}
+	return nil
 }
 
-func kubeConfigHandler(v interface{}) {
+func kubeConfigHandler(v interface{}) error {
 	if len(*v.(*string)) > 0 {
 		config.Vars.ServicePacks.Kubernetes.KubeConfigPath = *v.(*string)
+		flagOrigin("ServicePacks.Kubernetes.KubeConfig", "kubeconfig")
 		log.Printf("[NOTICE] Kubeconfig path has been overridden via command line")
 	}
 	if len(config.Vars.ServicePacks.Kubernetes.KubeConfigPath) == 0 {
 		log.Printf("[NOTICE] No kubeconfig path specified. Falling back to default paths.")
 	}
+	return nil
 }
 
-func silentHandler(v interface{}) {
-	config.Vars.Silent = isFlagPassed("silent")
+func pluginDirectoryHandler(v interface{}) error {
+	if len(*v.(*string)) > 0 {
+		config.Vars.PluginDirectory = *v.(*string)
+		flagOrigin("PluginDirectory", "plugindirectory")
+		log.Printf("[NOTICE] Plugin directory has been overridden via command line")
+	}
+	return nil
 }
 
-func nosummaryHandler(v interface{}) {
-	config.Vars.NoSummary = isFlagPassed("nosummary")
+func concurrencyHandler(v interface{}) error {
+	if *v.(*int) < 0 {
+		return utils.ReformatError("Invalid concurrency specified: '%v'. Must be a positive number", *v.(*int))
+	} else if *v.(*int) > 0 {
+		config.Vars.ProbeConcurrency = *v.(*int)
+		flagOrigin("ProbeConcurrency", "concurrency")
+		log.Printf("[NOTICE] Probe concurrency has been overridden via command line")
+	}
+	return nil
 }
 
-func isFlagPassed(flagName string) bool {
-	found := false
-	flag.Visit(func(f *flag.Flag) {
-		if f.Name == flagName {
-			found = true
+func timeoutHandler(v interface{}) error {
+	if err := config.Vars.RunTimeout.UnmarshalText([]byte(*v.(*string))); err != nil {
+		return utils.ReformatError("Invalid timeout specified: %v", err)
+	}
+	flagOrigin("RunTimeout", "timeout")
+	log.Printf("[NOTICE] Run timeout has been overridden via command line")
+	return nil
+}
+
+func silentHandler(v interface{}) error {
+	config.Vars.Silent = *v.(*bool)
+	return nil
+}
+
+func nosummaryHandler(v interface{}) error {
+	config.Vars.NoSummary = *v.(*bool)
+	return nil
+}
+
+func strictHandler(v interface{}) error {
+	config.Vars.Strict = *v.(*bool)
+	return nil
+}
+
+// setHandler applies each --set Path.To.Field=value, after every other flag so that it takes priority
+func setHandler(v interface{}) error {
+	for _, assignment := range *v.(*stringList) {
+		parts := strings.SplitN(assignment, "=", 2)
+		if len(parts) != 2 {
+			return utils.ReformatError("Invalid --set value '%s'. Expected Path.To.Field=value", assignment)
 		}
-	})
-	return found
+		if err := config.Vars.Set(parts[0], parts[1], "--set"); err != nil {
+			return err
+		}
+		log.Printf("[NOTICE] %s has been overridden via command line", parts[0])
+	}
+	return nil
+}
+
+// flagOrigin records that the config var at the key was set by the named flag
+func flagOrigin(key, flagName string) {
+	config.Vars.SetOrigin(key, config.OriginFlag+" --"+flagName)
 }
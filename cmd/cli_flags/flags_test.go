@@ -1,45 +1,115 @@
 package cliflags
 
 import (
+	"flag"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/citihub/probr/config"
 )
 
 func TestHandleFlags(t *testing.T) {
-
-	// Note:
-	// This test is only verifying WriteDirectory cli flag.
-	// It could be extended to test other flags, or all. If so, it should be refactored to avoid code duplication. Keeping it simple for now (YAGNI).
-
 	tests := []struct {
 		testName                  string
 		addCliFlag                string
 		expectedResultInConfigVar string
+		expectError               bool
 	}{
 		{
 			testName:                  "HandleFlag_WithCliFlag_ShouldAddCliFlagValueToGlobalConfig",
 			addCliFlag:                "-writedirectory=newdirectoryfromcliflag",
 			expectedResultInConfigVar: "newdirectoryfromcliflag",
 		},
+		{
+			testName:    "HandleFlag_WithInvalidLogLevel_ShouldReturnError",
+			addCliFlag:  "-loglevel=LOUD",
+			expectError: true,
+		},
+		{
+			testName:    "HandleFlag_WithNegativeConcurrency_ShouldReturnError",
+			addCliFlag:  "-concurrency=-1",
+			expectError: true,
+		},
+		{
+			testName:                  "HandleFlag_WithSet_ShouldAddValueToGlobalConfig",
+			addCliFlag:                "-set=WriteDirectory=directoryfromset",
+			expectedResultInConfigVar: "directoryfromset",
+		},
+		{
+			testName:    "HandleFlag_WithSetUnknownKey_ShouldReturnError",
+			addCliFlag:  "-set=WriteDirectry=directoryfromset",
+			expectError: true,
+		},
+		{
+			testName:    "HandleFlag_WithSetMissingValue_ShouldReturnError",
+			addCliFlag:  "-set=WriteDirectory",
+			expectError: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.testName, func(t *testing.T) {
+			config.Vars.WriteDirectory = ""
 
-			// Simulating cli arguments
-			os.Args = append(os.Args, "-writedirectory=newdirectoryfromcliflag")
+			// A new flag set is used for each test, so flags can be defined more than once
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			flags := defineFlags(fs, "writedirectory", "loglevel", "concurrency", "set")
+			if err := fs.Parse([]string{tt.addCliFlag}); err != nil {
+				t.Fatalf("Unexpected error parsing flags: %v", err)
+			}
 
-			// This function is expected to modify global configVar object, setting values based on tags
-			// It cannot be called more than once, since global flag object is used and it would raise a "flag redefined" error.
-			// An alternative for a potential refactoring is to use FlagSet instead. See: https://stackoverflow.com/questions/24504024/defining-independent-flagsets-in-golang
-			HandleFlags()
+			err := HandleFlags(fs, flags)
+			if (err != nil) != tt.expectError {
+				t.Errorf("HandleFlags() error = %v, expected error: %v", err, tt.expectError)
+				return
+			}
 
 			//Check WriteDirectory was set in global ConfigVars
-			if config.Vars.WriteDirectory != tt.expectedResultInConfigVar {
+			if !tt.expectError && config.Vars.WriteDirectory != tt.expectedResultInConfigVar {
 				t.Errorf("HandleFlags(); config.Vars.WiteDirectory = %v, Expected: %v", config.Vars.WriteDirectory, tt.expectedResultInConfigVar)
+			}
+		})
+	}
+}
+
+func TestHandleFlags_Profile(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "probr-flags")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "config.yml")
+	data := "ServicePacks:\n  Kubernetes:\n    KubeContext: dev\nProfiles:\n  prod:\n    ServicePacks:\n      Kubernetes:\n        KubeContext: prod\n"
+	ioutil.WriteFile(path, []byte(data), 0644)
+	defer config.SelectProfile("")
+
+	tests := []struct {
+		testName            string
+		args                []string
+		expectedProfile     string
+		expectedKubeContext string
+		expectError         bool
+	}{
+		{testName: "Profile", args: []string{"-varsfile", path, "-profile", "prod"}, expectedProfile: "prod", expectedKubeContext: "prod"},
+		{testName: "PreviousProfileNotKept", args: []string{"-varsfile", path}, expectedProfile: "", expectedKubeContext: "dev"},
+		{testName: "UnknownProfile", args: []string{"-varsfile", path, "-profile", "staging"}, expectError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			flags := defineFlags(fs, globalFlags...)
+			if err := fs.Parse(tt.args); err != nil {
+				t.Fatalf("Unexpected error parsing flags: %v", err)
+			}
+			err := HandleFlags(fs, flags)
+			if (err != nil) != tt.expectError {
+				t.Fatalf("HandleFlags() error = %v, expected error: %v", err, tt.expectError)
+			}
+			if tt.expectError {
 				return
 			}
+			if config.Vars.Profile != tt.expectedProfile || config.Vars.ServicePacks.Kubernetes.KubeContext != tt.expectedKubeContext {
+				t.Errorf("HandleFlags() applied profile '%s' with KubeContext '%s', expected '%s' and '%s'",
+					config.Vars.Profile, config.Vars.ServicePacks.Kubernetes.KubeContext, tt.expectedProfile, tt.expectedKubeContext)
+			}
 		})
 	}
 }
@@ -1,59 +1,236 @@
 package cliflags
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"log"
 	"os"
 	"strings"
+	"time"
 
+	"github.com/briandowns/spinner"
+	"gopkg.in/yaml.v2"
+
+	"github.com/citihub/probr"
+	"github.com/citihub/probr/audit"
 	"github.com/citihub/probr/config"
+	"github.com/citihub/probr/service_packs/coreengine"
 )
 
-// HandleRequestForRequiredVars will execute the logic for `./probr show-requirements (<PACK>)`
-func HandleRequestForRequiredVars() {
-	if os.Args[1] == "show-requirements" {
-		log.Printf("[INFO] CLI option 'show-requirements' was found")
-		for req := range config.Requirements {
-			if len(os.Args) > 2 {
-				// Show specified
-				if strings.ToLower(req) == strings.ToLower(os.Args[2]) {
-					respond(req, config.Requirements[req]...)
-					os.Exit(0)
-				}
-			} else {
-				// Show all
-				respond(req, config.Requirements[req]...)
-			}
+// runCommand will execute the logic for `./probr run (<SELECTION>...)`
+func runCommand(inv *Invocation) int {
+	config.Vars.LogConfigState()
+
+	if showIndicator() {
+		// At this loglevel, Probr is often silent for long periods. Add a visual runtime indicator.
+		config.Spinner = spinner.New(spinner.CharSets[42], 500*time.Millisecond)
+		config.Spinner.Start()
+		defer config.Spinner.Stop()
+	}
+
+	s, ts, err := probr.RunAllProbes(inv.Ctx, inv.Args...)
+	if err != nil {
+		log.Printf("[ERROR] Error executing tests %v", err)
+		return coreengine.ExitInternalError
+	}
+	log.Printf("[INFO] Overall test completion status: %v", s)
+	audit.State.SetProbrStatus()
+	audit.State.SetWaivers()
+	audit.State.SetProfile()
+
+	out := probr.GetAllProbeResults(ts)
+	if out == nil || len(out) == 0 {
+		audit.State.Meta["no probes completed"] = fmt.Sprintf(
+			"Probe results not written to file, possibly due to all being excluded or permissions on the specified output directory: %s",
+			config.Vars.CucumberDir(),
+		)
+	}
+	audit.State.PrintSummary()
+	audit.State.WriteSummary()
+	return s
+}
+
+// --silent disables, and otherwise only shows on ERROR/WARN
+func showIndicator() bool {
+	return (config.Vars.LogLevel == "ERROR" || config.Vars.LogLevel == "WARN") && !config.Vars.Silent
+}
+
+// planCommand will execute the logic for `./probr plan (<SELECTION>...)`
+func planCommand(inv *Invocation) int {
+	plan, err := probr.PlanAllProbes(inv.Args...)
+	if err != nil {
+		log.Print(err)
+		return coreengine.ExitInternalError
+	}
+	if inv.Format == "json" {
+		printJSON(inv.Out, plan)
+	} else {
+		plan.WriteText(inv.Out)
+	}
+	return coreengine.ExitSuccess
+}
+
+// listCommand will execute the logic for `./probr list`
+func listCommand(inv *Invocation) int {
+	if len(inv.Args) > 0 {
+		log.Printf("[ERROR] Unexpected arguments %v. Usage: probr list [flags]", inv.Args)
+		return coreengine.ExitInternalError
+	}
+	catalog := probr.ListAllProbes()
+	switch inv.Format {
+	case "json":
+		printJSON(inv.Out, catalog)
+	case "yaml":
+		printYAML(inv.Out, catalog)
+	default:
+		catalog.WriteTable(inv.Out)
+	}
+	return coreengine.ExitSuccess
+}
+
+// cleanupCommand will execute the logic for `./probr cleanup`
+func cleanupCommand(inv *Invocation) int {
+	if len(inv.Args) > 0 {
+		log.Printf("[ERROR] Unexpected arguments %v. Usage: probr cleanup [flags]", inv.Args)
+		return coreengine.ExitInternalError
+	}
+	deleted, err := probr.CleanupResources(inv.Ctx)
+	for _, r := range deleted {
+		fmt.Fprintf(inv.Out, "Deleted %s\n", r)
+	}
+	if err != nil {
+		log.Print(err)
+		return coreengine.ExitInternalError
+	}
+	if len(deleted) == 0 {
+		fmt.Fprintf(inv.Out, "No resources to delete\n")
+	}
+	return coreengine.ExitSuccess
+}
+
+// showRequirementsCommand will execute the logic for `./probr show-requirements (<PACK>)`
+func showRequirementsCommand(inv *Invocation) int {
+	if len(inv.Args) > 1 {
+		log.Printf("[ERROR] Expected a single service pack name, but found %v", inv.Args)
+		return coreengine.ExitInternalError
+	}
+	found := false
+	for _, pack := range config.GetPacks() {
+		if len(inv.Args) == 0 || strings.ToLower(pack) == strings.ToLower(inv.Args[0]) {
+			respond(inv.Out, pack, config.RequiredVars(pack)...)
+			found = true
 		}
-		os.Exit(0) // Never run probr if 'show-requirements' is called
 	}
+	if !found {
+		log.Printf("[ERROR] Unknown service pack '%s'. Must be one of %v", inv.Args[0], config.GetPacks())
+		return coreengine.ExitInternalError
+	}
+	return coreengine.ExitSuccess
 }
 
-func respond(pack string, vars ...string) {
-	fmt.Printf("Required variables for %s:\n", pack)
+func respond(out io.Writer, pack string, vars ...string) {
+	fmt.Fprintf(out, "Required variables for %s:\n", pack)
 	for _, v := range vars {
-		fmt.Printf("    %s\n", v)
+		fmt.Fprintf(out, "    %s\n", v)
+	}
+}
+
+// configShowCommand will execute the logic for `./probr config show (--origin)`
+func configShowCommand(inv *Invocation) int {
+	var v interface{} = config.Vars.Redacted()
+	if inv.Switch("origin") {
+		v = config.Vars.Settings()
+	}
+	if inv.Format == "json" {
+		printJSON(inv.Out, v)
+	} else {
+		printYAML(inv.Out, v)
+	}
+	return coreengine.ExitSuccess
+}
+
+// configInitCommand will execute the logic for `./probr config init (<FILE>) (--force)`
+func configInitCommand(inv *Invocation) int {
+	if len(inv.Args) > 1 {
+		log.Printf("[ERROR] Expected the path of a single vars file. Usage: probr config init [FILE]")
+		return coreengine.ExitInternalError
+	}
+	var scaffold bytes.Buffer
+	if err := config.WriteScaffold(&scaffold, probr.ListAllProbes().ScaffoldProbes()); err != nil {
+		log.Printf("[ERROR] Could not generate vars file: %v", err)
+		return coreengine.ExitInternalError
+	}
+	if len(inv.Args) == 0 {
+		fmt.Fprint(inv.Out, scaffold.String())
+		return coreengine.ExitSuccess
+	}
+	path := inv.Args[0]
+	if _, err := os.Stat(path); err == nil && !inv.Switch("force") {
+		log.Printf("[ERROR] %s already exists. Use --force to overwrite it", path)
+		return coreengine.ExitInternalError
+	}
+	if err := ioutil.WriteFile(path, scaffold.Bytes(), 0644); err != nil {
+		log.Printf("[ERROR] Could not write vars file: %v", err)
+		return coreengine.ExitInternalError
 	}
+	fmt.Fprintf(inv.Out, "Vars file written to %s\n", path)
+	return coreengine.ExitSuccess
 }
 
-// HandlePackOption will execute the logic necessary for `./probr run <PACK>`
-func HandlePackOption() {
-	if os.Args[1] == "run" {
-		log.Printf("[DEBUG] CLI option 'run' was found. Args: %s", os.Args)
-		for _, pack := range config.GetPacks() {
-			if strings.ToLower(pack) == strings.ToLower(os.Args[2]) {
-				log.Printf("[INFO] CLI Option specified to run only %s service pack", pack)
-				config.Vars.Meta.RunOnly = pack
+// configValidateCommand will execute the logic for `./probr config validate <VARSFILE>`
+func configValidateCommand(inv *Invocation) int {
+	if len(inv.Args) != 1 {
+		log.Printf("[ERROR] Expected the path of a single vars file. Usage: probr config validate VARSFILE")
+		return coreengine.ExitInternalError
+	}
+	path := inv.Args[0]
+	if err := config.ValidateVarsFile(path); err != nil {
+		fmt.Fprintf(inv.Out, "%s is not valid:\n", path)
+		if errs, ok := err.(config.ValidationErrors); ok {
+			for _, e := range errs {
+				fmt.Fprintf(inv.Out, "  %v\n", e)
 			}
+		} else {
+			fmt.Fprintf(inv.Out, "  %v\n", err)
 		}
-		if config.Vars.Meta.RunOnly == "" {
-			// If run was specified without a valid pack name, exit
-			log.Printf("[ERROR] Expected a service pack name.\n\nUsage: ./probr run <PACK-NAME>\n\n")
-			os.Exit(2)
-		}
-		// Remove the "run" and "PACK-NAME" arguments to prevent interference with flag handling
-		copy(os.Args[1:], os.Args[3:])
-		os.Args = os.Args[:len(os.Args)-2]
-		log.Printf("[DEBUG] Args after 'run %s': %s", config.Vars.Meta.RunOnly, os.Args)
+		return coreengine.ExitInternalError
+	}
+	fmt.Fprintf(inv.Out, "%s is valid\n", path)
+	return coreengine.ExitSuccess
+}
+
+// versionCommand will execute the logic for `./probr version`
+func versionCommand(inv *Invocation) int {
+	fmt.Fprintf(inv.Out, "probr version %s\n", probr.Version)
+	return coreengine.ExitSuccess
+}
+
+// helpCommand will execute the logic for `./probr help (<COMMAND>)`
+func helpCommand(inv *Invocation) int {
+	path, args := findCommand(inv.Args)
+	if len(inv.Args) == 0 {
+		path = []*Command{root}
+	}
+	if len(args) > 0 {
+		log.Printf("[ERROR] Unknown command '%s'", strings.Join(inv.Args, " "))
+		writeHelp(inv.Out, []*Command{root})
+		return coreengine.ExitInternalError
 	}
+	writeHelp(inv.Out, path)
+	return coreengine.ExitSuccess
+}
+
+func printJSON(out io.Writer, v interface{}) {
+	encoder := json.NewEncoder(out)
+	encoder.SetEscapeHTML(false) // Keep tag expressions such as "@a && ~@b" readable
+	encoder.SetIndent("", "  ")
+	encoder.Encode(v)
+}
+
+func printYAML(out io.Writer, v interface{}) {
+	data, _ := yaml.Marshal(v)
+	fmt.Fprint(out, string(data))
 }
@@ -1,95 +1,70 @@
 package main
 
 import (
-	"fmt"
+	"context"
 	"log"
 	"os"
 	"os/signal"
+	"sync/atomic"
 	"syscall"
 	"time"
 
-	"github.com/briandowns/spinner"
-
 	"github.com/citihub/probr"
-	"github.com/citihub/probr/audit"
 	cliflags "github.com/citihub/probr/cmd/cli_flags"
-	"github.com/citihub/probr/config"
+	"github.com/citihub/probr/ledger"
+	"github.com/citihub/probr/service_packs/coreengine"
 )
 
+// cleanupTimeout is how long an aborted run is given to delete the resources that it created
+const cleanupTimeout = 2 * time.Minute
+
 func main() {
+	runID := ledger.NewRunID()
+	ctx, cancel := context.WithCancel(ledger.WithRun(context.Background(), runID))
 
 	// Setup for handling SIGTERM (Ctrl+C)
-	setupCloseHandler()
-
-	err := config.Init("") // Create default config
-	if err != nil {
-		log.Printf("[ERROR] error returned from config.Init: %v", err)
-		exit(2)
-	}
-
-	if len(os.Args[1:]) > 0 {
-		log.Printf("[DEBUG] Checking for CLI options or flags")
-		cliflags.HandleRequestForRequiredVars()
-		cliflags.HandlePackOption()
-		// TODO: Find a way to get loglevel handling to work ABOVE this point,
-		// or to move the Options handlers below the flags handler
-		// Currently only ERROR will print prior to HandleFlags()
-		cliflags.HandleFlags()
-	}
-
-	config.Vars.LogConfigState()
-
-	if showIndicator() {
-		// At this loglevel, Probr is often silent for long periods. Add a visual runtime indicator.
-		config.Spinner = spinner.New(spinner.CharSets[42], 500*time.Millisecond)
-		config.Spinner.Start()
-	}
-
-	s, ts, err := probr.RunAllProbes()
-	if err != nil {
-		log.Printf("[ERROR] Error executing tests %v", err)
-		exit(2) // Exit 2+ is for logic/functional errors
-	}
-	log.Printf("[INFO] Overall test completion status: %v", s)
-	audit.State.SetProbrStatus()
+	aborted := setupCloseHandler(cancel)
 
-	out := probr.GetAllProbeResults(ts)
-	if out == nil || len(out) == 0 {
-		audit.State.Meta["no probes completed"] = fmt.Sprintf(
-			"Probe results not written to file, possibly due to all being excluded or permissions on the specified output directory: %s",
-			config.Vars.CucumberDir(),
-		)
-	}
-	audit.State.PrintSummary()
-	audit.State.WriteSummary()
-
-	exit(s)
-}
-
-// --silent disables, and otherwise only shows on ERROR/WARN
-func showIndicator() bool {
-	return (config.Vars.LogLevel == "ERROR" || config.Vars.LogLevel == "WARN") && !config.Vars.Silent
-}
-
-func exit(status int) {
-	if showIndicator() {
-		config.Spinner.Stop()
+	status := cliflags.Execute(ctx, os.Args[1:], os.Stdout)
+	if atomic.LoadInt32(aborted) == 1 {
+		status = cleanupAbortedRun(runID, status)
 	}
 	os.Exit(status)
 }
 
 // setupCloseHandler creates a 'listener' on a new goroutine which will notify the
-// program if it receives an interrupt from the OS. We then handle this by calling
-// our clean up procedure and exiting the program.
+// program if it receives an interrupt from the OS. We then handle this by cancelling
+// the context of the command, which returns once its in-flight scenarios have finished.
+// A second interrupt exits immediately, without cleaning up.
 // Ref: https://golangcode.com/handle-ctrl-c-exit-in-terminal/
-func setupCloseHandler() {
-	c := make(chan os.Signal)
+func setupCloseHandler(cancel context.CancelFunc) *int32 {
+	var aborted int32
+	c := make(chan os.Signal, 2)
 	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
 	go func() {
+		sig := <-c
+		log.Printf("Execution aborted - %v. Waiting for running scenarios to finish, interrupt again to exit now", sig)
+		atomic.StoreInt32(&aborted, 1)
+		cancel()
 		<-c
-		log.Printf("Execution aborted - %v", "SIGTERM")
-		probr.CleanupTmp()
-		// TODO: Additional cleanup may be needed. For instance, any pods created during tests are not being dropped if aborted.
-		os.Exit(0)
+		log.Printf("Execution aborted without cleanup. Run 'probr cleanup' to delete the resources that were created")
+		os.Exit(coreengine.ExitInternalError)
 	}()
+	return &aborted
+}
+
+// cleanupAbortedRun deletes the resources that the aborted run recorded in the ledger but did not delete, and
+// returns the exit status of the aborted command. An aborted run never reports success, as not every probe ran.
+// Resources of other runs that share the write directory are left for 'probr cleanup'.
+func cleanupAbortedRun(runID string, status int) int {
+	ctx, cancel := context.WithTimeout(context.Background(), cleanupTimeout)
+	defer cancel()
+	if _, err := probr.CleanupRun(ctx, runID); err != nil {
+		log.Printf("%v\nRun 'probr cleanup' to retry", err)
+	}
+	probr.CleanupTmp()
+	if status == coreengine.ExitSuccess {
+		return coreengine.ExitInconclusive
+	}
+	return status
 }
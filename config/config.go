@@ -7,13 +7,13 @@ import (
 	"log"
 	"os"
 	"path/filepath"
-	"reflect"
-	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/briandowns/spinner"
 	"github.com/citihub/probr/utils"
-	"gopkg.in/yaml.v2"
+	"gopkg.in/yaml.v3"
 )
 
 // Vars is a singleton instance of VarOptions
@@ -22,25 +22,23 @@ var Vars VarOptions
 // Spinner holds the current state of the CLI spinner
 var Spinner *spinner.Spinner
 
-// GetTags returns Tags, prioritising command line parameter over vars file
+// tagsLock guards the lazy evaluation of Tags, which may be read by several probes running at once
+var tagsLock sync.Mutex
+
+// GetTags returns Tags, prioritising command line parameter over vars file, with any tag aliases expanded
 func (ctx *VarOptions) GetTags() string {
+	tagsLock.Lock()
+	defer tagsLock.Unlock()
+	return expandTags(ctx.getTags(), TagAliases())
+}
+
+func (ctx *VarOptions) getTags() string {
 	if ctx.Tags == "" {
 		ctx.handleTagExclusions() // only process tag exclusions from vars file if not supplied via the command line
 	}
 	return ctx.Tags
 }
 
-// SetTags will parse the tags specified in Vars.Tags
-func (ctx *VarOptions) SetTags(tags map[string][]string) {
-	configTags := strings.Split(ctx.GetTags(), ",")
-	for _, configTag := range configTags {
-		for _, tag := range tags[configTag] {
-			configTags = append(configTags, "@"+tag)
-		}
-	}
-	ctx.Tags = strings.Join(configTags, ",")
-}
-
 // Handle tag exclusions provided via the config vars file
 func (ctx *VarOptions) handleTagExclusions() {
 	for _, tag := range ctx.TagExclusions {
@@ -62,43 +60,116 @@ func Init(configPath string) error {
 	}
 	config.Meta = Vars.Meta // Persist any existing Meta data
 	Vars = config
-	setFromEnvOrDefaults(&Vars) // Set any values not retrieved from file
 
 	SetLogFilter(Vars.LogLevel, os.Stderr) // Set the minimum log level obtained from Vars
 	log.Printf("[DEBUG] Config initialized by %s", utils.CallerName(1))
 
-	Vars.handleConfigFileExclusions()
-
 	return nil
 }
 
-// NewConfig overrides the current config.Vars values
+// NewConfig returns the defaults, overridden by any values in the vars file at path c and its selected profile, which are in turn
+// overridden by any env vars. Values that are references, such as "env:NAME", are then resolved.
+// Flags are applied afterwards, by the caller.
 func NewConfig(c string) (VarOptions, error) {
+	name, origin := profileName()
+	return newConfig(c, name, origin)
+}
+
+// NewProfileConfig is NewConfig, but applies the named profile rather than any selected by --profile or PROBR_PROFILE.
+// If the name is empty, the profile is selected as it is by NewConfig.
+func NewProfileConfig(c, profile string) (VarOptions, error) {
+	if profile == "" {
+		return NewConfig(c)
+	}
+	return newConfig(c, profile, "the profile option")
+}
+
+// newConfig builds the config as described by NewConfig, applying the named profile, if any, which was selected by origin
+func newConfig(c, name, origin string) (VarOptions, error) {
 	// Create config structure
 	config := VarOptions{}
-	if c == "" {
-		return config, nil // No file path provided, return empty config
+	setDefaults(&config)
+	if c != "" {
+		if err := config.decodeVarsFile(c, name, origin); err != nil {
+			return config, err
+		}
+	} else if name != "" {
+		return config, fmt.Errorf("profile '%s' was selected by %s, but no vars file was given", name, origin)
 	}
+	setFromEnv(&config)
+	if err := config.resolveReferences(); err != nil {
+		return config, err
+	}
+	redactor, err := newRedactor(&config)
+	config.redactor = redactor
+	return config, err
+}
+
+// decodeVarsFile overrides the config with the values in the vars file at path c, and those of the named profile if any
+func (ctx *VarOptions) decodeVarsFile(c, name, origin string) error {
 	err := ValidateConfigPath(c)
 	if err != nil {
-		return config, err
+		return err
 	}
-	// Open config file
-	file, err := os.Open(c)
+	data, err := ioutil.ReadFile(c)
 	if err != nil {
-		return config, err
+		return err
 	}
-	defer file.Close()
 
-	// Init new YAML decode
-	d := yaml.NewDecoder(file)
+	// Reject unknown keys and invalid values, which would otherwise be silently ignored
+	if err := validateVars(data); err != nil {
+		return fmt.Errorf("invalid vars file '%s':\n%v", c, err)
+	}
 
-	// Start YAML decoding from file
-	if err := d.Decode(&config); err != nil {
-		return config, err
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	removeEmptyValues(&doc)
+	root := &yaml.Node{Kind: yaml.MappingNode} // An empty file may still select a profile, which will not be found
+	if len(doc.Content) > 0 {
+		root = doc.Content[0]
+	}
+	profiles := takeProfiles(root)
+	ctx.setNodeOrigins(root, "", OriginVarsFile)
+	if name != "" {
+		if err := ctx.applyProfile(root, profiles, name, origin); err != nil {
+			return err
+		}
+		ctx.Profile = name
 	}
+	return root.Decode(ctx)
+}
 
-	return config, nil
+// setNodeOrigins records each key in the node as having been set by the origin
+func (ctx *VarOptions) setNodeOrigins(node *yaml.Node, prefix, origin string) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := joinKey(prefix, node.Content[i].Value)
+		if node.Content[i+1].Kind == yaml.MappingNode {
+			ctx.setNodeOrigins(node.Content[i+1], key, origin)
+		} else {
+			ctx.SetOrigin(key, origin)
+		}
+	}
+}
+
+// removeEmptyValues removes keys without a value from the document, so that they keep the default
+// value instead of being reset when the document is decoded
+func removeEmptyValues(node *yaml.Node) {
+	if node.Kind == yaml.MappingNode {
+		var content []*yaml.Node
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			v := node.Content[i+1]
+			if v.Kind == yaml.ScalarNode && v.Value == "" {
+				continue
+			}
+			content = append(content, node.Content[i], v)
+		}
+		node.Content = content
+	}
+	for _, child := range node.Content {
+		removeEmptyValues(child)
+	}
 }
 
 // ValidateConfigPath simply ensures the file exists
@@ -115,10 +186,11 @@ func ValidateConfigPath(path string) error {
 
 // LogConfigState will write the config file to the write directory
 func (ctx *VarOptions) LogConfigState() {
-	json, _ := json.MarshalIndent(Vars, "", "  ")
+	json, _ := json.MarshalIndent(ctx.Redacted(), "", "  ")
+	json = []byte(ctx.redactor.redact(string(json)))
 	log.Printf("[INFO] Config State: %s", json)
 	path := filepath.Join(ctx.GetWriteDirectory(), "config.json")
-	if ctx.WriteConfig == "true" && utils.WriteAllowed(path, ctx.Overwrite()) {
+	if bool(ctx.WriteConfig) && utils.WriteAllowed(path, ctx.Overwrite()) {
 		data := []byte(json)
 		ioutil.WriteFile(path, data, 0644)
 		log.Printf("[NOTICE] Config State written to file %s", path)
@@ -132,14 +204,24 @@ func (ctx *VarOptions) TmpDir() string {
 	return tmpDir
 }
 
-// Overwrite returns the string value of the OverwriteHistoricalAudits in bool format
+// Overwrite returns whether historical audits and other output files may be overwritten
 func (ctx *VarOptions) Overwrite() bool {
-	value, err := strconv.ParseBool(ctx.OverwriteHistoricalAudits)
-	if err != nil {
-		log.Printf("[ERROR] Could not parse value '%s' for OverwriteHistoricalAudits %s", ctx.OverwriteHistoricalAudits, err)
-		return false
-	}
-	return value
+	return bool(ctx.OverwriteHistoricalAudits)
+}
+
+// GetRunTimeout returns the maximum duration of the whole run, or 0 if the run has no deadline
+func (ctx *VarOptions) GetRunTimeout() time.Duration {
+	return time.Duration(ctx.RunTimeout)
+}
+
+// GetProbeTimeout returns the maximum duration of each probe, or 0 if probes have no deadline
+func (ctx *VarOptions) GetProbeTimeout() time.Duration {
+	return time.Duration(ctx.ProbeTimeout)
+}
+
+// GetScenarioTimeout returns the maximum duration of each scenario, or 0 if scenarios have no deadline
+func (ctx *VarOptions) GetScenarioTimeout() time.Duration {
+	return time.Duration(ctx.ScenarioTimeout)
 }
 
 // AuditDir creates and returns -audit- directory within WriteDirectory
@@ -162,23 +244,39 @@ func (ctx *VarOptions) GetWriteDirectory() string {
 	return ctx.WriteDirectory
 }
 
-func (ctx *VarOptions) handleConfigFileExclusions() {
-	ctx.handleProbeExclusions("kubernetes", ctx.ServicePacks.Kubernetes.Probes)
-	ctx.handleProbeExclusions("storage", ctx.ServicePacks.Storage.Probes)
+// ExcludeTag adds an exclusion for the tag to the tag filter, unless it was already excluded. The justification
+// is returned by ExclusionJustification. Service packs use this to apply the probe and scenario exclusions
+// from the vars file, using the tags that their feature files declare.
+func (ctx *VarOptions) ExcludeTag(tag, justification string) {
+	tagsLock.Lock()
+	defer tagsLock.Unlock()
+
+	tag = strings.TrimPrefix(tag, "@")
+	if _, excluded := ctx.exclusions[tag]; excluded {
+		return
+	}
+	if ctx.exclusions == nil {
+		ctx.exclusions = make(map[string]string)
+	}
+	ctx.exclusions[tag] = justification
+	ctx.getTags() // TagExclusions are only applied while Tags is empty, so they must be applied first
+	ctx.addExclusion(tag)
 }
 
-func (ctx *VarOptions) handleProbeExclusions(packName string, probes []Probe) {
-	for _, probe := range probes {
-		if probe.IsExcluded() {
-			ctx.addExclusion(fmt.Sprintf("probes/%s/%s", packName, probe.Name))
-		} else {
-			for _, scenario := range probe.Scenarios {
-				if scenario.IsExcluded() {
-					ctx.addExclusion(fmt.Sprintf("probes/%s/%s/%s", packName, probe.Name, scenario.Name))
-				}
-			}
+// ExclusionJustification returns the reason given in the vars file for a tag that is excluded via
+// TagExclusions or a probe or scenario's Excluded value or active Waiver, e.g. "k-iam-001".
+// It returns "" if the tag was not excluded by the vars file.
+func (ctx *VarOptions) ExclusionJustification(tag string) string {
+	tag = strings.TrimPrefix(tag, "@")
+	if justification, excluded := ctx.exclusions[tag]; excluded {
+		return justification
+	}
+	for _, excluded := range ctx.TagExclusions {
+		if tag == strings.TrimPrefix(excluded, "@") {
+			return "listed in TagExclusions"
 		}
 	}
+	return ""
 }
 
 func (ctx *VarOptions) addExclusion(tag string) {
@@ -188,65 +286,24 @@ func (ctx *VarOptions) addExclusion(tag string) {
 	ctx.Tags = fmt.Sprintf("%s~@%s", ctx.Tags, tag)
 }
 
-// IsExcluded will log and return exclusion configuration
-func (k Kubernetes) IsExcluded() bool {
-	return validatePackRequirements("Kubernetes", k)
-}
-
-// IsExcluded will log and return exclusion configuration
-func (s Storage) IsExcluded() bool {
-	return validatePackRequirements("Storage", s)
-}
-
-// IsExcluded will log and return exclusion configuration
-func (a APIM) IsExcluded() bool {
-	return validatePackRequirements("APIM", a)
-}
-
 // IsExcluded will log and return exclusion configuration
 func (p Probe) IsExcluded() bool {
-	if p.Excluded != "" {
-		log.Printf("[NOTICE] Excluding %s probe. Justification: %s", strings.Replace(p.Name, "_", " ", -1), p.Excluded)
+	name := fmt.Sprintf("%s probe", strings.Replace(p.Name, "_", " ", -1))
+	if j := p.Justification(); j != "" {
+		log.Printf("[NOTICE] Excluding %s. Justification: %s", name, j)
 		return true
 	}
+	logExpiredWaiver(name, p.Waiver)
 	return false
 }
 
 // IsExcluded will log and return exclusion configuration
 func (s Scenario) IsExcluded() bool {
-	if s.Excluded != "" {
-		log.Printf("[NOTICE] Excluding scenario '%s'. Justification: %s", s.Name, s.Excluded)
-		return true
-	}
-	return false
-}
-
-func validatePackRequirements(name string, object interface{}) bool {
-	// reflect for dynamic type querying
-	storage := reflect.Indirect(reflect.ValueOf(object))
-
-	for i, requirement := range Requirements[name] {
-		if storage.FieldByName(requirement).String() == "" {
-			if Vars.Meta.RunOnly == "" || strings.ToLower(Vars.Meta.RunOnly) == strings.ToLower(name) {
-				// Warn if the pack may have been expected to run
-				log.Printf("[WARN] Ignoring %s service pack due to required var '%s' not being present.", name, Requirements[name][i])
-			}
-			return true
-		}
-	}
-	if Vars.Meta.RunOnly != "" && strings.ToLower(Vars.Meta.RunOnly) != strings.ToLower(name) {
-		// If another pack is specified as RunOnly, this should be excluded
-		log.Printf("[NOTICE] Ignoring %s service pack due to %s being specified by 'probr run <SERVICE-PACK-NAME>'", name, Vars.Meta.RunOnly)
+	name := fmt.Sprintf("scenario '%s'", s.Name)
+	if j := s.Justification(); j != "" {
+		log.Printf("[NOTICE] Excluding %s. Justification: %s", name, j)
 		return true
 	}
-	log.Printf("[NOTICE] %s service pack included.", name)
+	logExpiredWaiver(name, s.Waiver)
 	return false
 }
-
-// GetPacks returns a list of pack names (as specified by internal/config/requirements.go)
-func GetPacks() (keys []string) {
-	for value := range Requirements {
-		keys = append(keys, value)
-	}
-	return keys
-}
@@ -2,8 +2,13 @@ package config
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 )
 
 //
@@ -24,6 +29,34 @@ func assertIsExcluded(obj Excludable, t *testing.T) {
 	}
 }
 
+func assertPackIsNotExcluded(config *VarOptions, pack string, t *testing.T) {
+	if config.PackIsExcluded(pack) {
+		t.Logf("Service pack '%s' has been excluded", pack)
+		t.Fail()
+	}
+}
+
+func assertPackIsExcluded(config *VarOptions, pack string, t *testing.T) {
+	if !config.PackIsExcluded(pack) {
+		t.Logf("Service pack '%s' has not been excluded", pack)
+		t.Fail()
+	}
+}
+
+// registerTestPacks mimics the registration that is done by the service packs
+func registerTestPacks() {
+	RegisterPackConfig("kubernetes", PackConfig{
+		RequiredVars: []string{"AuthorisedContainerRegistry", "UnauthorisedContainerImage"},
+		Settings:     func(v *VarOptions) interface{} { return v.ServicePacks.Kubernetes },
+		Probes:       func(v *VarOptions) []Probe { return v.ServicePacks.Kubernetes.Probes },
+	})
+	RegisterPackConfig("storage", PackConfig{
+		RequiredVars: []string{"Provider"},
+		Settings:     func(v *VarOptions) interface{} { return v.ServicePacks.Storage },
+		Probes:       func(v *VarOptions) []Probe { return v.ServicePacks.Storage.Probes },
+	})
+}
+
 func newConfigWithScenarioExclusionAndInclusion() (config VarOptions, excludedTag string) {
 	config, _ = NewConfig("")
 	excludedTag = "that guy"
@@ -85,30 +118,140 @@ func TestNewConfig(t *testing.T) {
 	}
 }
 
+func TestNewConfig_TypedValues(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "probr-config")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "config.yml")
+	data := "AuditEnabled: false\nRunTimeout: 90s\nProbeTimeout:\nServicePacks:\n  Kubernetes:\n    KeepPods: \"true\"\n"
+	if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config, err := NewConfig(path)
+	if err != nil {
+		t.Fatalf("NewConfig() error = %v", err)
+	}
+	if config.AuditEnabled {
+		t.Errorf("AuditEnabled = true, expected the vars file to override the default")
+	}
+	if !config.ServicePacks.Kubernetes.KeepPods {
+		t.Errorf("KeepPods = false, expected a quoted boolean to be accepted")
+	}
+	if config.RunTimeout != Duration(90*time.Second) {
+		t.Errorf("RunTimeout = %v, expected 1m30s", time.Duration(config.RunTimeout))
+	}
+	if config.ProbeTimeout != 0 || config.ScenarioTimeout != Duration(30*time.Minute) {
+		t.Errorf("Empty or missing timeouts should keep their defaults")
+	}
+}
+
 func TestK8sIsExcluded(t *testing.T) {
+	registerTestPacks()
 	config, _ := NewConfig("")
 
 	// 0 required vars set
-	assertIsExcluded(config.ServicePacks.Kubernetes, t)
+	assertPackIsExcluded(&config, "kubernetes", t)
 
 	// 1 of 2 required vars set
 	config.ServicePacks.Kubernetes.AuthorisedContainerRegistry = "not-empty"
-	assertIsExcluded(config.ServicePacks.Kubernetes, t)
+	assertPackIsExcluded(&config, "kubernetes", t)
 
 	// All required vars set
 	config.ServicePacks.Kubernetes.UnauthorisedContainerImage = "not-empty"
-	assertIsNotExcluded(config.ServicePacks.Kubernetes, t)
+	assertPackIsNotExcluded(&config, "kubernetes", t)
 }
 
 func TestStorageIsExcluded(t *testing.T) {
+	registerTestPacks()
 	config, _ := NewConfig("")
 
 	// 0 required vars set
-	assertIsExcluded(config.ServicePacks.Storage, t)
+	assertPackIsExcluded(&config, "storage", t)
 
 	// All required vars set
 	config.ServicePacks.Storage.Provider = "not-empty"
-	assertIsNotExcluded(config.ServicePacks.Storage, t)
+	assertPackIsNotExcluded(&config, "storage", t)
+}
+
+func TestPackIsExcludedBySelections(t *testing.T) {
+	registerTestPacks()
+	config, _ := NewConfig("")
+	config.ServicePacks.Storage.Provider = "not-empty"
+
+	config.Meta.Selections = []Selection{{Pack: "kubernetes"}}
+	assertPackIsExcluded(&config, "storage", t)
+	if reason := config.PackExclusionReason("kubernetes"); !strings.Contains(reason, "required var") {
+		t.Errorf("A selected pack should be excluded due to its required vars, got '%s'", reason)
+	}
+
+	config.Meta.Selections = []Selection{{Pack: "kubernetes"}, {Pack: "Storage", Probe: "access_whitelisting"}}
+	assertPackIsNotExcluded(&config, "storage", t)
+}
+
+func TestProbeTags(t *testing.T) {
+	tests := []struct {
+		testName         string
+		tags             string
+		selections       []Selection
+		probe            string
+		expected         string
+		expectedSelected bool
+	}{
+		{testName: "NoSelections", tags: "@k-iam", probe: "iam", expected: "@k-iam", expectedSelected: true},
+		{testName: "PackSelected", selections: []Selection{{Pack: "kubernetes"}}, probe: "iam", expected: "", expectedSelected: true},
+		{testName: "OtherProbeSelected", selections: []Selection{{Pack: "kubernetes", Probe: "general"}}, probe: "iam", expected: "", expectedSelected: false},
+		{testName: "ScenariosSelected", tags: "~@k-iam-003", selections: []Selection{{Pack: "kubernetes", Probe: "iam", Tag: "k-iam-001"}, {Pack: "kubernetes", Probe: "iam", Tag: "k-iam-002"}}, probe: "iam", expected: "~@k-iam-003 && @k-iam-001,@k-iam-002", expectedSelected: true},
+		{testName: "ProbeAndScenarioSelected", selections: []Selection{{Pack: "kubernetes", Probe: "iam", Tag: "k-iam-001"}, {Pack: "kubernetes", Probe: "iam"}}, probe: "iam", expected: "", expectedSelected: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			config := VarOptions{Tags: tt.tags}
+			config.Meta.Selections = tt.selections
+			if tags := config.ProbeTags("kubernetes", tt.probe); tags != tt.expected {
+				t.Errorf("ProbeTags() = '%s', expected '%s'", tags, tt.expected)
+			}
+			if selected := config.ProbeIsSelected("kubernetes", tt.probe); selected != tt.expectedSelected {
+				t.Errorf("ProbeIsSelected() = %v, expected %v", selected, tt.expectedSelected)
+			}
+		})
+	}
+}
+
+func TestGetPacks(t *testing.T) {
+	registerTestPacks()
+	packs := GetPacks()
+	if len(packs) != 2 || packs[0] != "kubernetes" || packs[1] != "storage" {
+		t.Errorf("GetPacks() = %v, want [kubernetes storage]", packs)
+	}
+	if len(RequiredVars("Kubernetes")) != 2 {
+		t.Errorf("RequiredVars() did not return the registered requirements: %v", RequiredVars("Kubernetes"))
+	}
+}
+
+func TestRegisterPackConfig_Concurrent(t *testing.T) {
+	registerTestPacks()
+	config, _ := NewConfig("")
+
+	// Plugin packs are registered and unregistered while other runs read the pack configs. Run with -race.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			RegisterPackConfig("plugin_pack", PackConfig{RequiredVars: []string{"Provider"}})
+			UnregisterPackConfig("plugin_pack")
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		GetPacks()
+		RequiredVars("plugin_pack")
+		config.PackExclusionReason("kubernetes")
+		config.Waivers()
+		packSettingsName(reflect.TypeOf(config.ServicePacks.Storage))
+	}
+	<-done
+
+	if packs := GetPacks(); len(packs) != 2 {
+		t.Errorf("GetPacks() = %v, expected the unregistered pack to be removed", packs)
+	}
 }
 
 func TestProbeIsExcluded(t *testing.T) {
@@ -132,11 +275,24 @@ func TestScenarioIsExcluded(t *testing.T) {
 	checkPreformattedScenarioExclusions(config, t)
 }
 
-func TestHandleProbeExclusions(t *testing.T) {
-	config, excludedTag := newConfigWithScenarioExclusionAndInclusion()
-	config.handleProbeExclusions("kubernetes", config.ServicePacks.Kubernetes.Probes)
-	checkPreformattedScenarioExclusions(config, t) // verify that exclusions weren't modified
-	checkTagsContainExclusion(config, excludedTag, t)
+func TestExcludeTag(t *testing.T) {
+	config, _ := NewConfig("")
+	config.TagExclusions = []string{"k-gen-001"}
+	config.ExcludeTag("@k-iam-001", "No pod identity")
+	config.ExcludeTag("k-iam-001", "Excluded twice")
+	checkTagsContainExclusion(config, "~@k-iam-001", t)
+	if config.Tags != "~@k-gen-001 && ~@k-iam-001" {
+		t.Errorf("Tags = '%s', expected TagExclusions to be kept and the tag to be excluded once", config.Tags)
+	}
+	if j := config.ExclusionJustification("@k-iam-001"); j != "No pod identity" {
+		t.Errorf("ExclusionJustification() = '%s', expected the first justification", j)
+	}
+	if j := config.ExclusionJustification("k-gen-001"); j != "listed in TagExclusions" {
+		t.Errorf("ExclusionJustification() = '%s', expected TagExclusions", j)
+	}
+	if j := config.ExclusionJustification("k-cra-001"); j != "" {
+		t.Errorf("ExclusionJustification() = '%s', expected none for a tag that is not excluded", j)
+	}
 }
 
 func TestAddExclusion(t *testing.T) {
@@ -147,35 +303,15 @@ func TestAddExclusion(t *testing.T) {
 	checkTagsContainExclusion(config, tag, t)
 }
 
-func TestSetTags(t *testing.T) {
-	vars, _ := NewConfig("")
-	tagName := "@probes/kubernetes"
-	tagID := "k-cra"
-	expected := 0
-	vars.Tags = tagName
-	tags := map[string][]string{tagName: []string{tagID}}
-	t.Log(tags)
-	vars.SetTags(tags)
-	for _, tag := range strings.Split(vars.Tags, ",") {
-		t.Log("Tag found:" + tag)
-		if tag == tagName || tag == "@"+tagID {
-			expected = expected + 1
-		}
-	}
-	if expected != 2 {
-		t.Errorf("Tag name and tag ID were not found in VarOptions.Tags")
-	}
-}
-
 // TestOverwrite ...
 func TestOverwrite(t *testing.T) {
 	vars, _ := NewConfig("")
-	vars.OverwriteHistoricalAudits = "true"
+	vars.OverwriteHistoricalAudits = true
 	if vars.Overwrite() != true {
 		t.Errorf("Overwrite() should return a bool of 'true'")
 	}
 
-	vars.OverwriteHistoricalAudits = "false"
+	vars.OverwriteHistoricalAudits = false
 	if vars.Overwrite() != false {
 		t.Errorf("Overwrite() should return a bool of 'false'")
 	}
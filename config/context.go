@@ -0,0 +1,63 @@
+package config
+
+import (
+	"context"
+	"sync"
+)
+
+type varsKey struct{}
+
+// WithVars returns a copy of the context that carries the config of a run. Service packs read it with
+// FromContext, so that runs with different configs may take place in the same process.
+func WithVars(ctx context.Context, vars *VarOptions) context.Context {
+	return context.WithValue(ctx, varsKey{}, vars)
+}
+
+// FromContext returns the config carried by the context, or Vars if it carries none
+func FromContext(ctx context.Context) *VarOptions {
+	if ctx != nil {
+		if vars, ok := ctx.Value(varsKey{}).(*VarOptions); ok && vars != nil {
+			return vars
+		}
+	}
+	return &Vars
+}
+
+// redactedConfigs holds the configs, other than Vars, whose secrets are redacted from every log. See RedactLogs.
+var (
+	redactedConfigs     = make(map[*VarOptions]int)
+	redactedConfigsLock sync.RWMutex
+)
+
+// RedactLogs redacts the secrets of the config from every log, as well as those of Vars, until the returned
+// func is called. This is used by runs whose config is not Vars, as logs are shared by the whole process.
+// If SetLogFilter has not been called, the current log output is wrapped so that its logs are redacted.
+func RedactLogs(vars *VarOptions) (release func()) {
+	redactLogOutput() // Not called under the lock, as the log's own lock is held while each log is redacted
+
+	redactedConfigsLock.Lock()
+	defer redactedConfigsLock.Unlock()
+	redactedConfigs[vars]++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			redactedConfigsLock.Lock()
+			defer redactedConfigsLock.Unlock()
+			if redactedConfigs[vars]--; redactedConfigs[vars] <= 0 {
+				delete(redactedConfigs, vars)
+			}
+		})
+	}
+}
+
+// redactLogs replaces the secrets of Vars and of any config passed to RedactLogs
+func redactLogs(s string) string {
+	s = Vars.Redact(s)
+	redactedConfigsLock.RLock()
+	defer redactedConfigsLock.RUnlock()
+	for vars := range redactedConfigs {
+		s = vars.Redact(s)
+	}
+	return s
+}
@@ -0,0 +1,37 @@
+package config
+
+import (
+	"context"
+	"testing"
+)
+
+func TestFromContext(t *testing.T) {
+	vars := &VarOptions{}
+	if got := FromContext(WithVars(context.Background(), vars)); got != vars {
+		t.Errorf("FromContext() did not return the config carried by the context")
+	}
+	if got := FromContext(context.Background()); got != &Vars {
+		t.Errorf("FromContext() should fall back to Vars when the context carries no config")
+	}
+}
+
+func TestRedactLogs(t *testing.T) {
+	vars := &VarOptions{}
+	vars.CloudProviders.Azure.ClientSecret = "runner-secret"
+	r, err := newRedactor(vars)
+	if err != nil {
+		t.Fatalf("newRedactor() returned unexpected error: %v", err)
+	}
+	vars.redactor = r
+
+	text := "secret is runner-secret"
+	release := RedactLogs(vars)
+	if redacted := redactLogs(text); redacted != "secret is [REDACTED]" {
+		t.Errorf("Secret of a registered config was not redacted: '%s'", redacted)
+	}
+	release()
+	release() // Releasing twice should not release another registration
+	if redacted := redactLogs(text); redacted != text {
+		t.Errorf("Secret of a released config should no longer be redacted: '%s'", redacted)
+	}
+}
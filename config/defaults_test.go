@@ -5,7 +5,7 @@ import (
 	"testing"
 )
 
-func Test_setFromEnvOrDefaults(t *testing.T) {
+func Test_setDefaultsAndEnv(t *testing.T) {
 
 	// Note:
 	// This test is only verifying WriteDirectory.
@@ -28,13 +28,13 @@ func Test_setFromEnvOrDefaults(t *testing.T) {
 		expectedResultWriteDirectory string
 	}{
 		{
-			testName:                     "setFromEnvOrDefaults_GivenEnvVar_ShouldSetConfigVarToEnvVarValue",
+			testName:                     "setDefaultsAndEnv_GivenEnvVar_ShouldSetConfigVarToEnvVarValue",
 			testArgs:                     args{e: &VarOptions{}},
 			setEnvVar:                    true,
 			expectedResultWriteDirectory: envVarValuePROBRWRITEDIRECTORY,
 		},
 		{
-			testName:                     "setFromEnvOrDefaults_WithoutEnvVar_ShouldSetConfigVarToDefaultValue",
+			testName:                     "setDefaultsAndEnv_WithoutEnvVar_ShouldSetConfigVarToDefaultValue",
 			testArgs:                     args{e: &VarOptions{}},
 			setEnvVar:                    false,
 			expectedResultWriteDirectory: defaultValuePROBRWRITEDIRECTORY,
@@ -50,11 +50,12 @@ func Test_setFromEnvOrDefaults(t *testing.T) {
 				os.Setenv("PROBR_WRITE_DIRECTORY", "")
 			}
 
-			setFromEnvOrDefaults(tt.testArgs.e) //This function will modify config object
+			setDefaults(tt.testArgs.e) //These functions will modify config object
+			setFromEnv(tt.testArgs.e)
 
 			//Check WriteDirectory
 			if tt.testArgs.e.WriteDirectory != tt.expectedResultWriteDirectory {
-				t.Errorf("setFromEnvOrDefaults(); PROBR_WRITE_DIRECTORY = %v, Expected: %v", tt.testArgs.e.WriteDirectory, tt.expectedResultWriteDirectory)
+				t.Errorf("setDefaults(); setFromEnv(); PROBR_WRITE_DIRECTORY = %v, Expected: %v", tt.testArgs.e.WriteDirectory, tt.expectedResultWriteDirectory)
 				return
 			}
 
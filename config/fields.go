@@ -0,0 +1,174 @@
+package config
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/citihub/probr/utils"
+)
+
+// Origins of config values, in order of precedence. Each overrides those before it.
+const (
+	OriginDefault  = "default"
+	OriginVarsFile = "vars file"
+	OriginProfile  = "profile" // Followed by the name of the profile, e.g. "profile prod"
+	OriginEnv      = "env"     // Followed by the name of the env var, e.g. "env PROBR_TAGS"
+	OriginFlag     = "flag"    // Followed by the name of the flag, e.g. "flag --set"
+)
+
+// field is a config var that may be set in a vars file, and so also by an env var or the --set flag
+type field struct {
+	key   string // Path to the key in a vars file, e.g. "ServicePacks.Kubernetes.KubeConfig"
+	tag   reflect.StructTag
+	value reflect.Value
+}
+
+// Setting is the value of a config var, along with where it was set
+type Setting struct {
+	Key    string      `json:"Key" yaml:"Key"`
+	Value  interface{} `json:"Value" yaml:"Value"`
+	Origin string      `json:"Origin" yaml:"Origin"`
+}
+
+// fields returns every config var that may be set in a vars file, in the order they are declared
+func (ctx *VarOptions) fields() []field {
+	var fields []field
+	collectFields(reflect.ValueOf(ctx).Elem(), "", &fields)
+	return fields
+}
+
+func collectFields(v reflect.Value, prefix string, fields *[]field) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := strings.Split(f.Tag.Get("yaml"), ",")[0]
+		if f.PkgPath != "" || name == "" || name == "-" {
+			continue
+		}
+		key := joinKey(prefix, name)
+		if f.Type.Kind() == reflect.Struct && !isText(f.Type) {
+			collectFields(v.Field(i), key, fields)
+			continue
+		}
+		*fields = append(*fields, field{key: key, tag: f.Tag, value: v.Field(i)})
+	}
+}
+
+// isText is true for types such as Bool and Duration, which parse their own values
+func isText(t reflect.Type) bool {
+	_, ok := reflect.New(t).Interface().(encoding.TextUnmarshaler)
+	return ok
+}
+
+// setText parses the text into the field. Lists are written as comma separated values.
+func (f field) setText(text string) error {
+	if values := f.tag.Get("enum"); values != "" {
+		if _, found := utils.FindString(strings.Split(values, ","), text); !found {
+			return fmt.Errorf("'%s' is not one of %v", text, strings.Split(values, ","))
+		}
+	}
+	if isText(f.value.Type()) {
+		return f.value.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(text))
+	}
+	switch f.value.Kind() {
+	case reflect.String:
+		f.value.SetString(text)
+	case reflect.Int:
+		i, err := strconv.Atoi(text)
+		if err != nil {
+			return fmt.Errorf("'%s' is not a whole number", text)
+		}
+		f.value.SetInt(int64(i))
+	case reflect.Slice:
+		if f.value.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("may only be set in a vars file")
+		}
+		var items []string
+		for _, item := range strings.Split(text, ",") {
+			items = append(items, strings.TrimSpace(item))
+		}
+		f.value.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("may only be set in a vars file")
+	}
+	return nil
+}
+
+// Set overrides the config var at the key, e.g. "ServicePacks.Kubernetes.KubeConfig", as if it was
+// set by the named flag. The key is not case sensitive. Lists are written as comma separated values.
+func (ctx *VarOptions) Set(key, value, flag string) error {
+	fields := ctx.fields()
+	for _, f := range fields {
+		if strings.EqualFold(f.key, key) {
+			if err := f.setText(value); err != nil {
+				return utils.ReformatError("Invalid value for %s: %v", f.key, err)
+			}
+			if err := ctx.resolveReference(f); err != nil {
+				return utils.ReformatError("%v", err)
+			}
+			ctx.SetOrigin(f.key, OriginFlag+" "+flag)
+			redactor, err := newRedactor(ctx) // The value may be sensitive, or a pattern
+			if err != nil {
+				return utils.ReformatError("%v", err)
+			}
+			ctx.redactor = redactor
+			return nil
+		}
+	}
+	if suggestions := suggestKeys(key, fields); len(suggestions) > 0 {
+		return utils.ReformatError("Unknown config var '%s', did you mean %s?", key, quoteJoin(suggestions))
+	}
+	return utils.ReformatError("Unknown config var '%s'. Run 'probr config show --origin' to list them", key)
+}
+
+// suggestKeys returns up to three keys of fields that are similar to key, or whose last part is
+// similar to it, as the full path is easily forgotten
+func suggestKeys(key string, fields []field) []string {
+	distances := make(map[string]int)
+	var suggestions []string
+	for _, f := range fields {
+		name := f.key[strings.LastIndex(f.key, ".")+1:]
+		if len(suggest(key, []string{f.key, name})) > 0 {
+			lower := strings.ToLower(key)
+			distances[f.key] = minInt(editDistance(lower, strings.ToLower(f.key)), editDistance(lower, strings.ToLower(name)))
+			suggestions = append(suggestions, f.key)
+		}
+	}
+	sort.SliceStable(suggestions, func(i, j int) bool { return distances[suggestions[i]] < distances[suggestions[j]] })
+	if len(suggestions) > 3 {
+		suggestions = suggestions[:3]
+	}
+	return suggestions
+}
+
+// SetOrigin records where the value of the config var at the key was set
+func (ctx *VarOptions) SetOrigin(key, origin string) {
+	if ctx.origins == nil {
+		ctx.origins = make(map[string]string)
+	}
+	ctx.origins[key] = origin
+}
+
+// Origin returns where the value of the config var at the key was set, e.g. "vars file" or "env PROBR_TAGS"
+func (ctx *VarOptions) Origin(key string) string {
+	if origin, ok := ctx.origins[key]; ok {
+		return origin
+	}
+	return OriginDefault
+}
+
+// Settings returns the value and origin of every config var that may be set in a vars file, sorted by key.
+// Values that were resolved from references are given as the references, and other secrets are redacted.
+func (ctx *VarOptions) Settings() []Setting {
+	var settings []Setting
+	redacted := ctx.Redacted()
+	for _, f := range redacted.fields() {
+		settings = append(settings, Setting{Key: f.key, Value: f.value.Interface(), Origin: ctx.Origin(f.key)})
+	}
+	sort.SliceStable(settings, func(i, j int) bool { return settings[i].Key < settings[j].Key })
+	return settings
+}
@@ -0,0 +1,93 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewConfig_Precedence(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "probr-precedence")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "config.yml")
+	data := "WriteDirectory: from_file\nLogLevel: INFO\n"
+	if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	previous := os.Getenv("PROBR_WRITE_DIRECTORY")
+	defer os.Setenv("PROBR_WRITE_DIRECTORY", previous)
+	os.Setenv("PROBR_WRITE_DIRECTORY", "from_env")
+
+	config, err := NewConfig(path)
+	if err != nil {
+		t.Fatalf("NewConfig() error = %v", err)
+	}
+	tests := []struct {
+		key            string
+		value          interface{}
+		expectedValue  interface{}
+		expectedOrigin string
+	}{
+		{key: "ServicePacks.Kubernetes.ProbeImage", value: config.ServicePacks.Kubernetes.ProbeImage, expectedValue: "citihub/probr-probe", expectedOrigin: OriginDefault},
+		{key: "LogLevel", value: config.LogLevel, expectedValue: "INFO", expectedOrigin: OriginVarsFile},
+		{key: "WriteDirectory", value: config.WriteDirectory, expectedValue: "from_env", expectedOrigin: "env PROBR_WRITE_DIRECTORY"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			if tt.value != tt.expectedValue {
+				t.Errorf("%s = %v, expected %v", tt.key, tt.value, tt.expectedValue)
+			}
+			if origin := config.Origin(tt.key); origin != tt.expectedOrigin {
+				t.Errorf("Origin(%s) = '%s', expected '%s'", tt.key, origin, tt.expectedOrigin)
+			}
+		})
+	}
+}
+
+func TestSet(t *testing.T) {
+	tests := []struct {
+		testName      string
+		key           string
+		value         string
+		expectedError string
+	}{
+		{testName: "String", key: "ServicePacks.Kubernetes.KubeContext", value: "dev"},
+		{testName: "CaseInsensitiveKey", key: "servicepacks.kubernetes.kubecontext", value: "dev"},
+		{testName: "Duration", key: "RunTimeout", value: "1h"},
+		{testName: "List", key: "TagExclusions", value: "k-iam, k-gen"},
+		{testName: "InvalidEnum", key: "LogLevel", value: "LOUD", expectedError: "'LOUD' is not one of"},
+		{testName: "InvalidDuration", key: "RunTimeout", value: "soon", expectedError: "'soon' is not a duration"},
+		{testName: "VarsFileOnly", key: "ServicePacks.Kubernetes.Probes", value: "iam", expectedError: "may only be set in a vars file"},
+		{testName: "MisspelledKey", key: "KubeContxt", value: "dev", expectedError: "did you mean 'ServicePacks.Kubernetes.KubeContext'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			config, _ := NewConfig("")
+			err := config.Set(tt.key, tt.value, "--set")
+			if tt.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectedError) {
+					t.Errorf("Set(%s, %s) error = %v, expected '%s'", tt.key, tt.value, err, tt.expectedError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Set(%s, %s) returned unexpected error: %v", tt.key, tt.value, err)
+			}
+			for _, s := range config.Settings() {
+				if strings.EqualFold(s.Key, tt.key) && s.Origin != "flag --set" {
+					t.Errorf("Origin(%s) = '%s', expected 'flag --set'", s.Key, s.Origin)
+				}
+			}
+		})
+	}
+
+	config, _ := NewConfig("")
+	config.Set("RunTimeout", "1h", "--set")
+	config.Set("TagExclusions", "k-iam, k-gen", "--set")
+	if config.RunTimeout != Duration(time.Hour) || strings.Join(config.TagExclusions, ",") != "k-iam,k-gen" {
+		t.Errorf("Set() did not parse the values: RunTimeout = %v, TagExclusions = %v", time.Duration(config.RunTimeout), config.TagExclusions)
+	}
+}
@@ -4,20 +4,50 @@ import (
 	"io"
 	"log"
 	"strings"
+	"sync"
 
 	"github.com/hashicorp/logutils"
 )
 
-// SetLogFilter will override the minimum log level.
+// SetLogFilter will override the minimum log level. Secrets are redacted from every log that is written. See RedactLogs.
 func SetLogFilter(minLevel string, writer io.Writer) {
 	filter := &logutils.LevelFilter{
 		Levels:   []logutils.LogLevel{"DEBUG", "INFO", "NOTICE", "WARN", "ERROR"},
 		MinLevel: logutils.LogLevel(minLevel),
-		Writer:   writer,
+		Writer:   &redactingWriter{writer: writer},
 	}
 	log.SetOutput(filter)
 }
 
+// logOutputLock prevents runs that start at the same time from each wrapping the log output
+var logOutputLock sync.Mutex
+
+// redactLogOutput wraps the log output in a redactingWriter, unless it already redacts secrets
+func redactLogOutput() {
+	logOutputLock.Lock()
+	defer logOutputLock.Unlock()
+
+	w := log.Writer()
+	if filter, ok := w.(*logutils.LevelFilter); ok {
+		w = filter.Writer
+	}
+	if _, ok := w.(*redactingWriter); !ok {
+		log.SetOutput(&redactingWriter{writer: log.Writer()})
+	}
+}
+
+// redactingWriter redacts secrets from each log before it is written
+type redactingWriter struct {
+	writer io.Writer
+}
+
+func (w *redactingWriter) Write(p []byte) (int, error) {
+	if _, err := w.writer.Write([]byte(redactLogs(string(p)))); err != nil {
+		return 0, err
+	}
+	return len(p), nil // The caller is only concerned with its own bytes having been handled
+}
+
 // LineBreakReplacer replaces carriage return (\r), linefeed (\n), formfeed (\f) and other similar characters with a space.
 func LineBreakReplacer(s string) string {
 
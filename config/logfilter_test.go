@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"log"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/citihub/probr/utils"
@@ -61,3 +62,10 @@ func TestLogLevel(t *testing.T) {
 	bufferShouldLog(t, testString, buf)
 
 }
+
+func TestLogRedaction(t *testing.T) {
+	buf := tmpLogger("[ERROR] Request failed: https://account.blob.core.windows.net/c?sv=2019&sig=abc123XYZ", "ERROR")
+	if strings.Contains(buf.String(), "abc123XYZ") || !strings.Contains(buf.String(), "sig="+RedactedText) {
+		t.Errorf("SAS signature was not redacted from the log: '%s'", buf.String())
+	}
+}
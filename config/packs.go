@@ -0,0 +1,150 @@
+package config
+
+import (
+	"fmt"
+	"log"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// PackConfig describes where a service pack's settings live within VarOptions, and which of them are required
+type PackConfig struct {
+	RequiredVars []string                      // Names of fields in Settings that must be set for the pack to run
+	Settings     func(*VarOptions) interface{} // Returns the pack's settings, e.g. ServicePacks.Kubernetes
+	Probes       func(*VarOptions) []Probe     // Returns the probe and scenario exclusions for the pack
+}
+
+// packConfigs holds the config of each registered service pack, keyed by its lower case name.
+// Plugin packs are registered and unregistered while other runs may be reading it.
+var (
+	packConfigs     = make(map[string]PackConfig)
+	packConfigsLock sync.RWMutex
+)
+
+// RegisterPackConfig makes the config for a service pack available to Init, show-requirements and 'probr run <PACK>'.
+// This is called by coreengine.RegisterServicePack and should not usually be called directly.
+func RegisterPackConfig(name string, pc PackConfig) {
+	packConfigsLock.Lock()
+	defer packConfigsLock.Unlock()
+	packConfigs[strings.ToLower(name)] = pc
+}
+
+// UnregisterPackConfig removes the config of a service pack that is no longer available, see RegisterPackConfig
+func UnregisterPackConfig(name string) {
+	packConfigsLock.Lock()
+	defer packConfigsLock.Unlock()
+	delete(packConfigs, strings.ToLower(name))
+}
+
+// getPackConfig returns the config of the named service pack, which is empty if the pack is not registered
+func getPackConfig(name string) PackConfig {
+	packConfigsLock.RLock()
+	defer packConfigsLock.RUnlock()
+	return packConfigs[strings.ToLower(name)]
+}
+
+// GetPacks returns a sorted list of the names of all registered service packs
+func GetPacks() (keys []string) {
+	packConfigsLock.RLock()
+	for value := range packConfigs {
+		keys = append(keys, value)
+	}
+	packConfigsLock.RUnlock()
+	sort.Strings(keys)
+	return keys
+}
+
+// RequiredVars returns the config vars that must be set for the named service pack to run
+func RequiredVars(pack string) []string {
+	return getPackConfig(pack).RequiredVars
+}
+
+// PackIsExcluded will log and return whether the named service pack should be excluded from this run
+func (ctx *VarOptions) PackIsExcluded(name string) bool {
+	reason := ctx.PackExclusionReason(name)
+	switch {
+	case reason == "":
+		log.Printf("[NOTICE] %s service pack included.", name)
+		return false
+	case !ctx.PackIsSelected(name):
+		// If other packs were selected, this is expected to be excluded
+		log.Printf("[NOTICE] Ignoring %s service pack due to %s", name, reason)
+	default:
+		// Warn if the pack may have been expected to run
+		log.Printf("[WARN] Ignoring %s service pack due to %s.", name, reason)
+	}
+	return true
+}
+
+// PackExclusionReason returns the reason the named service pack is excluded from this run, or "" if it is included
+func (ctx *VarOptions) PackExclusionReason(name string) string {
+	pc := getPackConfig(name)
+	var settings interface{}
+	if pc.Settings != nil {
+		settings = pc.Settings(ctx)
+	}
+	return ctx.validatePackRequirements(name, settings)
+}
+
+func (ctx *VarOptions) validatePackRequirements(name string, object interface{}) string {
+	// reflect for dynamic type querying
+	settings := reflect.Indirect(reflect.ValueOf(object))
+
+	if !ctx.PackIsSelected(name) {
+		return "other service packs being selected"
+	}
+	for _, requirement := range RequiredVars(name) {
+		if !settings.IsValid() || settings.FieldByName(requirement).String() == "" {
+			return fmt.Sprintf("required var '%s' not being present", requirement)
+		}
+	}
+	return ""
+}
+
+// PackIsSelected returns whether the named service pack, or any of its probes, was selected to run
+func (ctx *VarOptions) PackIsSelected(name string) bool {
+	return len(ctx.Meta.Selections) == 0 || len(ctx.selectionsFor(name, "")) > 0
+}
+
+// ProbeIsSelected returns whether the named probe, or any of its scenarios, was selected to run
+func (ctx *VarOptions) ProbeIsSelected(pack, probe string) bool {
+	return len(ctx.Meta.Selections) == 0 || len(ctx.selectionsFor(pack, probe)) > 0
+}
+
+// ProbeTags returns the tags used to filter the scenarios of the named probe. These are the tags from
+// GetTags, along with the scenario tags or aliases that were selected for the probe, if only some of its scenarios were.
+func (ctx *VarOptions) ProbeTags(pack, probe string) string {
+	tags := ctx.GetTags()
+	var selected []string
+	for _, s := range ctx.selectionsFor(pack, probe) {
+		if s.Tag == "" {
+			return tags // The whole probe was selected
+		}
+		selected = append(selected, "@"+s.Tag)
+	}
+	if len(selected) == 0 {
+		return tags
+	}
+	scenarios := expandTags(strings.Join(selected, ","), TagAliases())
+	if tags == "" {
+		return scenarios
+	}
+	return fmt.Sprintf("%s && %s", tags, scenarios)
+}
+
+// selectionsFor returns the selections that include the named pack and probe. If probe is empty,
+// the selections for any of the pack's probes are returned.
+func (ctx *VarOptions) selectionsFor(pack, probe string) []Selection {
+	var selections []Selection
+	for _, s := range ctx.Meta.Selections {
+		if !strings.EqualFold(s.Pack, pack) {
+			continue
+		}
+		if probe == "" || s.Probe == "" || s.Probe == probe {
+			selections = append(selections, s)
+		}
+	}
+	return selections
+}
@@ -0,0 +1,107 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Profile overrides parts of the vars file for one of the environments that share it, such as a
+// production cluster and subscription. Profiles are listed by name under the Profiles key.
+type Profile struct {
+	ServicePacks   ServicePacks   `yaml:"ServicePacks"`
+	CloudProviders CloudProviders `yaml:"CloudProviders"`
+}
+
+// profilesKey is the key of the vars file that holds the profiles
+const profilesKey = "Profiles"
+
+// profileEnv is the env var that selects a profile, unless one is selected by SelectProfile
+const profileEnv = "PROBR_PROFILE"
+
+// selectedProfile is the profile selected by the --profile flag, which is needed before the vars file is read
+var selectedProfile string
+
+// SelectProfile sets the profile to be applied by NewConfig, overriding PROBR_PROFILE. An empty name
+// selects the profile in PROBR_PROFILE, if any. This is called by the --profile flag.
+func SelectProfile(name string) {
+	selectedProfile = name
+}
+
+// profileName returns the selected profile and where it was selected, or "" if there is none
+func profileName() (name, origin string) {
+	if selectedProfile != "" {
+		return selectedProfile, OriginFlag + " --profile"
+	}
+	return os.Getenv(profileEnv), OriginEnv + " " + profileEnv
+}
+
+// takeProfiles removes the Profiles key from the root of a vars file, returning its value or nil if it has none
+func takeProfiles(root *yaml.Node) *yaml.Node {
+	if root.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == profilesKey {
+			profiles := root.Content[i+1]
+			root.Content = append(root.Content[:i], root.Content[i+2:]...)
+			return profiles
+		}
+	}
+	return nil
+}
+
+// validateProfiles checks each profile against the schema of Profile
+func validateProfiles(profiles *yaml.Node, errs *ValidationErrors) {
+	if profiles.Kind != yaml.MappingNode {
+		if profiles.Kind != yaml.ScalarNode || profiles.Value != "" {
+			*errs = append(*errs, ValidationError{Line: profiles.Line, Key: profilesKey, Message: fmt.Sprintf("expected a map of profiles by name, but found %s", describeNode(profiles))})
+		}
+		return
+	}
+	for i := 0; i+1 < len(profiles.Content); i += 2 {
+		validateNode(profiles.Content[i+1], reflect.TypeOf(Profile{}), joinKey(profilesKey, profiles.Content[i].Value), errs)
+	}
+}
+
+// applyProfile merges the named profile into the root of the vars file, returning an error if it is not defined
+func (ctx *VarOptions) applyProfile(root, profiles *yaml.Node, name, origin string) error {
+	var names []string
+	if profiles != nil && profiles.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(profiles.Content); i += 2 {
+			if profiles.Content[i].Value == name {
+				ctx.setNodeOrigins(profiles.Content[i+1], "", fmt.Sprintf("%s %s", OriginProfile, name))
+				mergeNodes(root, profiles.Content[i+1])
+				return nil
+			}
+			names = append(names, profiles.Content[i].Value)
+		}
+	}
+	sort.Strings(names)
+	return fmt.Errorf("profile '%s' selected by %s is not defined in the vars file. Must be one of %v", name, origin, names)
+}
+
+// mergeNodes overrides the keys of a mapping with those of another. Maps are merged, while any other value is replaced.
+func mergeNodes(base, override *yaml.Node) {
+	if base.Kind != yaml.MappingNode || override.Kind != yaml.MappingNode {
+		*base = *override
+		return
+	}
+	for i := 0; i+1 < len(override.Content); i += 2 {
+		key, value := override.Content[i], override.Content[i+1]
+		found := false
+		for j := 0; j+1 < len(base.Content); j += 2 {
+			if base.Content[j].Value == key.Value {
+				mergeNodes(base.Content[j+1], value)
+				found = true
+				break
+			}
+		}
+		if !found {
+			base.Content = append(base.Content, key, value)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const profilesVarsFile = `LogLevel: INFO
+ServicePacks:
+  Kubernetes:
+    KubeContext: dev
+    AuthorisedContainerRegistry: dev.azurecr.io
+    ContainerRequiredDropCapabilities:
+      - NET_RAW
+      - NET_ADMIN
+Profiles:
+  prod:
+    ServicePacks:
+      Kubernetes:
+        KubeContext: prod
+        ContainerRequiredDropCapabilities:
+          - ALL
+    CloudProviders:
+      Azure:
+        SubscriptionID: prod-subscription
+  uat:
+    ServicePacks:
+      Kubernetes:
+        KubeContext: uat
+`
+
+func TestNewConfig_Profile(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "probr-profiles")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "config.yml")
+	if err := ioutil.WriteFile(path, []byte(profilesVarsFile), 0644); err != nil {
+		t.Fatal(err)
+	}
+	previous := os.Getenv(profileEnv)
+	defer os.Setenv(profileEnv, previous)
+	defer SelectProfile("")
+
+	tests := []struct {
+		testName       string
+		env            string
+		flag           string
+		path           string
+		expectedError  string
+		expectedValues map[string]interface{}
+		expectedOrigin string
+	}{
+		{testName: "NoProfile", path: path, expectedValues: map[string]interface{}{"Profile": "", "KubeContext": "dev", "DropCapabilities": "NET_RAW,NET_ADMIN", "SubscriptionID": ""}, expectedOrigin: OriginVarsFile},
+		{testName: "EnvProfile", env: "prod", path: path, expectedValues: map[string]interface{}{"Profile": "prod", "KubeContext": "prod", "DropCapabilities": "ALL", "SubscriptionID": "prod-subscription"}, expectedOrigin: "profile prod"},
+		{testName: "FlagOverridesEnv", env: "prod", flag: "uat", path: path, expectedValues: map[string]interface{}{"Profile": "uat", "KubeContext": "uat", "DropCapabilities": "NET_RAW,NET_ADMIN", "SubscriptionID": ""}, expectedOrigin: "profile uat"},
+		{testName: "UnknownProfile", flag: "staging", path: path, expectedError: "profile 'staging' selected by flag --profile is not defined in the vars file. Must be one of [prod uat]"},
+		{testName: "NoVarsFile", env: "prod", expectedError: "profile 'prod' was selected by env PROBR_PROFILE, but no vars file was given"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			os.Setenv(profileEnv, tt.env)
+			SelectProfile(tt.flag)
+			config, err := NewConfig(tt.path)
+			if tt.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectedError) {
+					t.Fatalf("NewConfig() error = %v, expected '%s'", err, tt.expectedError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewConfig() error = %v", err)
+			}
+			k8s := config.ServicePacks.Kubernetes
+			values := map[string]interface{}{
+				"Profile":          config.Profile,
+				"KubeContext":      k8s.KubeContext,
+				"DropCapabilities": strings.Join(k8s.ContainerRequiredDropCapabilities, ","),
+				"SubscriptionID":   config.CloudProviders.Azure.SubscriptionID,
+			}
+			for name, expected := range tt.expectedValues {
+				if values[name] != expected {
+					t.Errorf("%s = '%v', expected '%v'", name, values[name], expected)
+				}
+			}
+			if k8s.AuthorisedContainerRegistry != "dev.azurecr.io" || config.LogLevel != "INFO" {
+				t.Errorf("Values that the profile does not set should be kept from the vars file")
+			}
+			if origin := config.Origin("ServicePacks.Kubernetes.KubeContext"); origin != tt.expectedOrigin {
+				t.Errorf("Origin() = '%s', expected '%s'", origin, tt.expectedOrigin)
+			}
+		})
+	}
+}
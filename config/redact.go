@@ -0,0 +1,120 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// RedactedText replaces secrets in logs, the config dump, audits and the summary
+const RedactedText = "[REDACTED]"
+
+// defaultRedactPatterns match common secrets. If a pattern has a group, only the group is redacted.
+var defaultRedactPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)\bsig=([^&\s"'\\]+)`),                                                    // SAS URL signatures
+	regexp.MustCompile(`(?i)\bAccountKey=([^;\s"'\\]+)`),                                             // Storage connection strings
+	regexp.MustCompile(`(?i)\bbearer\s+([A-Za-z0-9\-._~+/]+=*)`),                                     // Authorization headers
+	regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`),                     // JSON web tokens
+	regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`), // PEM private keys
+}
+
+// minRedactedLength avoids redacting every occurrence of a trivially short value, such as "1"
+const minRedactedLength = 4
+
+// redactor replaces the values of sensitive config vars, and any text that matches a pattern
+type redactor struct {
+	values   []string
+	patterns []*regexp.Regexp
+}
+
+// newRedactor collects the values of config vars that are tagged as sensitive or were resolved from
+// references, and compiles the RedactPatterns
+func newRedactor(ctx *VarOptions) (*redactor, error) {
+	r := &redactor{patterns: defaultRedactPatterns}
+	for _, pattern := range ctx.RedactPatterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RedactPatterns entry '%s': %v", pattern, err)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+	for _, f := range ctx.sensitiveFields() {
+		value := f.value.String()
+		if len(value) < minRedactedLength {
+			continue
+		}
+		r.values = append(r.values, value)
+		if escaped, _ := json.Marshal(value); string(escaped[1:len(escaped)-1]) != value {
+			r.values = append(r.values, string(escaped[1:len(escaped)-1])) // As it appears within JSON
+		}
+	}
+	sort.Slice(r.values, func(i, j int) bool { return len(r.values[i]) > len(r.values[j]) }) // Longest first, in case one contains another
+	return r, nil
+}
+
+// sensitiveFields returns the string config vars that are tagged as sensitive or were resolved from references
+func (ctx *VarOptions) sensitiveFields() []field {
+	var fields []field
+	for _, f := range ctx.fields() {
+		if _, referenced := ctx.references[f.key]; f.value.Kind() == reflect.String && (f.tag.Get("sensitive") == "true" || referenced) {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+func (r *redactor) redact(s string) string {
+	if r == nil {
+		r = &redactor{patterns: defaultRedactPatterns}
+	}
+	for _, value := range r.values {
+		s = strings.Replace(s, value, RedactedText, -1)
+	}
+	for _, re := range r.patterns {
+		s = redactPattern(re, s)
+	}
+	return s
+}
+
+func redactPattern(re *regexp.Regexp, s string) string {
+	if re.NumSubexp() == 0 {
+		return re.ReplaceAllLiteralString(s, RedactedText)
+	}
+	var b strings.Builder
+	last := 0
+	for _, match := range re.FindAllStringSubmatchIndex(s, -1) {
+		if match[2] < 0 {
+			continue // The group did not take part in the match
+		}
+		b.WriteString(s[last:match[2]])
+		b.WriteString(RedactedText)
+		last = match[3]
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+// Redact replaces any secrets within the text, according to the current config
+func Redact(s string) string {
+	return Vars.Redact(s)
+}
+
+// Redact replaces any secrets within the text, according to this config
+func (ctx *VarOptions) Redact(s string) string {
+	return ctx.redactor.redact(s)
+}
+
+// Redacted returns a copy of the config that may be output safely. Values that were resolved from
+// references are replaced by the references, and other sensitive values are redacted.
+func (ctx *VarOptions) Redacted() VarOptions {
+	redacted := ctx.Unresolved()
+	for _, f := range redacted.fields() {
+		if _, referenced := ctx.references[f.key]; !referenced && f.tag.Get("sensitive") == "true" && f.value.String() != "" {
+			f.value.SetString(RedactedText)
+		}
+	}
+	return redacted
+}
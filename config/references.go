@@ -0,0 +1,111 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Prefixes of references to values that are kept outside of the vars file, such as secrets
+const (
+	envReference  = "env:"  // The value of an env var, e.g. "env:AZURE_CLIENT_SECRET"
+	fileReference = "file:" // The content of a file, e.g. "file:/run/secrets/azure"
+	execReference = "exec:" // The output of a credential helper, e.g. "exec:pass show probr/azure"
+)
+
+// execTimeout limits how long a credential helper may take
+var execTimeout = 30 * time.Second
+
+// resolveReferences replaces each value that is a reference with the value it refers to. The references
+// are kept, so that they are output by LogConfigState and config show instead of the resolved values.
+func (ctx *VarOptions) resolveReferences() error {
+	for _, f := range ctx.fields() {
+		if err := ctx.resolveReference(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ctx *VarOptions) resolveReference(f field) error {
+	if f.value.Kind() != reflect.String {
+		return nil
+	}
+	reference := f.value.String()
+	if !isReference(reference) {
+		delete(ctx.references, f.key) // Any previous reference has been overridden
+		return nil
+	}
+	value, err := resolve(reference)
+	if err != nil {
+		return fmt.Errorf("could not resolve %s from '%s': %v", f.key, reference, err)
+	}
+	f.value.SetString(value)
+	if ctx.references == nil {
+		ctx.references = make(map[string]string)
+	}
+	ctx.references[f.key] = reference
+	return nil
+}
+
+func isReference(value string) bool {
+	return strings.HasPrefix(value, envReference) || strings.HasPrefix(value, fileReference) || strings.HasPrefix(value, execReference)
+}
+
+func resolve(reference string) (string, error) {
+	switch {
+	case strings.HasPrefix(reference, envReference):
+		name := strings.TrimPrefix(reference, envReference)
+		value := os.Getenv(name)
+		if value == "" {
+			return "", fmt.Errorf("env var %s is not set", name)
+		}
+		return value, nil
+	case strings.HasPrefix(reference, fileReference):
+		data, err := ioutil.ReadFile(strings.TrimPrefix(reference, fileReference))
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	default:
+		return runHelper(strings.Fields(strings.TrimPrefix(reference, execReference)))
+	}
+}
+
+// runHelper runs a credential helper, returning its output. Arguments are separated by spaces,
+// and are not interpreted by a shell.
+func runHelper(args []string) (string, error) {
+	if len(args) == 0 {
+		return "", fmt.Errorf("no command was given")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), execTimeout)
+	defer cancel()
+	var stderr strings.Builder
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if ctx.Err() != nil {
+		return "", fmt.Errorf("%s did not complete within %v", args[0], execTimeout)
+	}
+	if err != nil {
+		return "", fmt.Errorf("%s failed: %v %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimRight(string(out), "\r\n"), nil
+}
+
+// Unresolved returns a copy of the config in which any values that were resolved from references
+// are replaced by the references themselves, so that it may be output without revealing secrets
+func (ctx *VarOptions) Unresolved() VarOptions {
+	unresolved := *ctx
+	for _, f := range unresolved.fields() {
+		if reference, ok := ctx.references[f.key]; ok {
+			f.value.SetString(reference)
+		}
+	}
+	return unresolved
+}
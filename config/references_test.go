@@ -0,0 +1,79 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "probr-references")
+	defer os.RemoveAll(dir)
+	secretFile := filepath.Join(dir, "secret")
+	ioutil.WriteFile(secretFile, []byte("from-file\n"), 0600)
+	previous := os.Getenv("PROBR_TEST_SECRET")
+	defer os.Setenv("PROBR_TEST_SECRET", previous)
+	os.Setenv("PROBR_TEST_SECRET", "from-env")
+
+	tests := []struct {
+		testName      string
+		reference     string
+		expected      string
+		expectedError string
+		unixOnly      bool
+	}{
+		{testName: "Env", reference: "env:PROBR_TEST_SECRET", expected: "from-env"},
+		{testName: "EnvNotSet", reference: "env:PROBR_TEST_NOT_SET", expectedError: "env var PROBR_TEST_NOT_SET is not set"},
+		{testName: "File", reference: "file:" + secretFile, expected: "from-file"},
+		{testName: "FileMissing", reference: "file:" + filepath.Join(dir, "missing"), expectedError: "missing"},
+		{testName: "ExecWithoutCommand", reference: "exec:", expectedError: "no command was given"},
+		{testName: "ExecUnknownCommand", reference: "exec:probr-not-a-command", expectedError: "probr-not-a-command failed"},
+		{testName: "Exec", reference: "exec:echo from-exec", expected: "from-exec", unixOnly: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			if tt.unixOnly && runtime.GOOS == "windows" {
+				t.Skip("echo is not an executable on windows")
+			}
+			value, err := resolve(tt.reference)
+			if tt.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.expectedError) {
+					t.Errorf("resolve(%s) error = %v, expected '%s'", tt.reference, err, tt.expectedError)
+				}
+				return
+			}
+			if err != nil || value != tt.expected {
+				t.Errorf("resolve(%s) = '%s', %v, expected '%s'", tt.reference, value, err, tt.expected)
+			}
+		})
+	}
+}
+
+func TestUnresolved(t *testing.T) {
+	previous := os.Getenv("PROBR_TEST_SECRET")
+	defer os.Setenv("PROBR_TEST_SECRET", previous)
+	os.Setenv("PROBR_TEST_SECRET", "super-secret")
+
+	config, _ := NewConfig("")
+	if err := config.Set("CloudProviders.Azure.ClientSecret", "env:PROBR_TEST_SECRET", "--set"); err != nil {
+		t.Fatalf("Set() returned unexpected error: %v", err)
+	}
+	if config.CloudProviders.Azure.ClientSecret != "super-secret" {
+		t.Errorf("ClientSecret = '%s', expected the reference to be resolved", config.CloudProviders.Azure.ClientSecret)
+	}
+	if unresolved := config.Unresolved(); unresolved.CloudProviders.Azure.ClientSecret != "env:PROBR_TEST_SECRET" {
+		t.Errorf("Unresolved().ClientSecret = '%s', expected the reference", unresolved.CloudProviders.Azure.ClientSecret)
+	}
+	if config.CloudProviders.Azure.ClientSecret != "super-secret" {
+		t.Errorf("Unresolved() modified the config")
+	}
+
+	// Overriding the reference with a value should forget the reference
+	config.Set("CloudProviders.Azure.ClientSecret", "plain", "--set")
+	if unresolved := config.Unresolved(); unresolved.CloudProviders.Azure.ClientSecret != "plain" {
+		t.Errorf("Unresolved().ClientSecret = '%s', expected 'plain'", unresolved.CloudProviders.Azure.ClientSecret)
+	}
+}
@@ -0,0 +1,144 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// ScaffoldProbe is a probe to be listed in the vars file written by WriteScaffold
+type ScaffoldProbe struct {
+	Name      string
+	Scenarios []ScaffoldScenario
+}
+
+// ScaffoldScenario is a scenario to be listed beneath its probe in the vars file written by WriteScaffold
+type ScaffoldScenario struct {
+	Tag  string // The tag that identifies the scenario, without "@"
+	Name string
+}
+
+// scaffold writes a commented vars file, line by line
+type scaffold struct {
+	w      *bufio.Writer
+	probes map[string][]ScaffoldProbe
+}
+
+// WriteScaffold writes a vars file containing every config var, commented with its description, default,
+// env var and the service packs that require it. Values are left empty so that their defaults apply. The
+// probes and scenarios of each service pack, keyed by pack name, are listed as commented out exclusions.
+// As it is generated from VarOptions and the registered packs, the scaffold cannot fall out of date.
+func WriteScaffold(w io.Writer, probes map[string][]ScaffoldProbe) error {
+	s := &scaffold{w: bufio.NewWriter(w), probes: probes}
+	s.line("", "# Generated by 'probr config init'. Empty values use the default shown, and any value may be")
+	s.line("", "# overridden by the env var shown or by --set. Check changes with 'probr config validate <VARSFILE>'.")
+	s.writeStruct(reflect.TypeOf(VarOptions{}), "", "")
+	s.writeProfiles()
+	return s.w.Flush()
+}
+
+func (s *scaffold) line(indent string, format string, a ...interface{}) {
+	fmt.Fprintf(s.w, indent+format+"\n", a...)
+}
+
+// writeStruct writes each field that may be set in a vars file. If the struct holds the settings of a
+// service pack, pack is its name, so that its required vars and probes can be listed.
+func (s *scaffold) writeStruct(t reflect.Type, indent, pack string) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := strings.Split(f.Tag.Get("yaml"), ",")[0]
+		if f.PkgPath != "" || name == "" || name == "-" {
+			continue
+		}
+		if indent == "" {
+			s.line("", "")
+		}
+		s.writeComments(f, indent, pack)
+		s.line(indent, "%s:", name)
+		switch {
+		case f.Type == reflect.TypeOf([]Probe{}):
+			s.writeProbes(indent+"  ", s.probes[pack])
+		case f.Type.Kind() == reflect.Struct && !isText(f.Type):
+			s.writeStruct(f.Type, indent+"  ", packSettingsName(f.Type))
+		}
+	}
+}
+
+func (s *scaffold) writeComments(f reflect.StructField, indent, pack string) {
+	if doc := f.Tag.Get("doc"); doc != "" {
+		s.line(indent, "# %s", doc)
+	}
+	var details []string
+	if pack != "" && found(RequiredVars(pack), f.Name) {
+		details = append(details, fmt.Sprintf("Required by the %s service pack", pack))
+	}
+	if values := f.Tag.Get("enum"); values != "" {
+		details = append(details, "One of "+strings.Join(strings.Split(values, ","), ", "))
+	}
+	if value := f.Tag.Get("default"); value != "" {
+		details = append(details, "Default: "+value)
+	}
+	if env := f.Tag.Get("env"); env != "" {
+		details = append(details, "Env: "+env)
+	}
+	if f.Tag.Get("sensitive") == "true" {
+		details = append(details, "Keep secrets out of this file with a reference such as env:NAME")
+	}
+	if len(details) > 0 {
+		s.line(indent, "# %s", strings.Join(details, ". "))
+	}
+}
+
+// writeProbes lists the probes of a pack as commented out exclusions, after describing the keys of a waiver
+func (s *scaffold) writeProbes(indent string, probes []ScaffoldProbe) {
+	if len(probes) == 0 {
+		return
+	}
+	s.line(indent, "# To exclude a probe or scenario, uncomment it and add a Waiver, which has the keys:")
+	waiver := reflect.TypeOf(Waiver{})
+	for i := 0; i < waiver.NumField(); i++ {
+		f := waiver.Field(i)
+		s.line(indent, "#   %s: %s", f.Tag.Get("yaml"), f.Tag.Get("doc"))
+	}
+	for _, probe := range probes {
+		s.line(indent, "# - Name: %s", probe.Name)
+		s.line(indent, "#   Waiver:")
+		s.line(indent, "#     Justification:")
+		if len(probe.Scenarios) == 0 {
+			continue
+		}
+		s.line(indent, "#   Scenarios:")
+		for _, scenario := range probe.Scenarios {
+			s.line(indent, "#     - Name: %s # %s", scenario.Tag, scenario.Name)
+			s.line(indent, "#       Waiver:")
+			s.line(indent, "#         Justification:")
+		}
+	}
+}
+
+func (s *scaffold) writeProfiles() {
+	s.line("", "")
+	s.line("", "# Overrides of ServicePacks and CloudProviders for each environment, selected by --profile or %s", profileEnv)
+	s.line("", "%s:", profilesKey)
+	s.line("", "  # prod:")
+	s.line("", "  #   ServicePacks:")
+	s.line("", "  #     Kubernetes:")
+	s.line("", "  #       KubeContext: prod")
+}
+
+// packSettingsName returns the name of the registered service pack whose settings are of the given type, or ""
+func packSettingsName(t reflect.Type) string {
+	for _, name := range GetPacks() {
+		pc := getPackConfig(name)
+		if pc.Settings == nil {
+			continue
+		}
+		settings := reflect.TypeOf(pc.Settings(&VarOptions{}))
+		if settings != nil && (settings == t || settings.Kind() == reflect.Ptr && settings.Elem() == t) {
+			return name
+		}
+	}
+	return ""
+}
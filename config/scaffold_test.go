@@ -0,0 +1,75 @@
+package config
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWriteScaffold(t *testing.T) {
+	registerTestPacks()
+	probes := map[string][]ScaffoldProbe{
+		"kubernetes": {{Name: "iam", Scenarios: []ScaffoldScenario{{Tag: "k-iam-001", Name: "Prevent cross namespace Azure Identities"}}}},
+		"storage":    {{Name: "encryption_at_rest"}},
+	}
+	var out bytes.Buffer
+	if err := WriteScaffold(&out, probes); err != nil {
+		t.Fatal(err)
+	}
+	scaffold := out.String()
+	for _, expected := range []string{
+		"    # Required by the kubernetes service pack. Env: PROBR_AUTHORISED_REGISTRY\n    AuthorisedContainerRegistry:\n",
+		"    # Default: ~/.kube/config. Env: KUBE_CONFIG\n    KubeConfig:\n",
+		"# One of IO, INMEM. Default: IO. Env: PROBR_OUTPUT_TYPE\nOutputType:\n",
+		"      # - Name: iam\n      #   Waiver:\n      #     Justification:\n      #   Scenarios:\n      #     - Name: k-iam-001 # Prevent cross namespace Azure Identities\n" +
+			"      #       Waiver:\n      #         Justification:\n",
+		"      # - Name: encryption_at_rest\n      #   Waiver:\n      #     Justification:\n  # Probes of cloud API management services\n",
+		"Keep secrets out of this file",
+		"\nProfiles:\n",
+	} {
+		if !strings.Contains(scaffold, expected) {
+			t.Errorf("Scaffold did not contain:\n%s\nScaffold:\n%s", expected, scaffold)
+		}
+	}
+
+	if err := validateVars(out.Bytes()); err != nil {
+		t.Fatalf("Scaffold is not a valid vars file: %v", err)
+	}
+	dir, _ := ioutil.TempDir("", "probr-scaffold")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "config.yml")
+	ioutil.WriteFile(path, out.Bytes(), 0644)
+	fromScaffold, err := NewConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defaults, _ := NewConfig("")
+	scaffoldFields, defaultFields := fromScaffold.fields(), defaults.fields()
+	for i, f := range scaffoldFields {
+		if !reflect.DeepEqual(f.value.Interface(), defaultFields[i].value.Interface()) {
+			t.Errorf("%s = %v from the scaffold, expected the default %v", f.key, f.value.Interface(), defaultFields[i].value.Interface())
+		}
+	}
+}
+
+func TestFieldsHaveDocs(t *testing.T) {
+	// The doc tag is the only description of a field in the scaffold written by 'probr config init'
+	var v VarOptions
+	for _, f := range v.fields() {
+		if f.tag.Get("doc") == "" {
+			t.Errorf("%s has no doc tag", f.key)
+		}
+	}
+	for _, i := range []interface{}{Probe{}, Scenario{}, Waiver{}} {
+		typ := reflect.TypeOf(i)
+		for j := 0; j < typ.NumField(); j++ {
+			if f := typ.Field(j); f.Tag.Get("yaml") != "" && f.Tag.Get("doc") == "" {
+				t.Errorf("%s.%s has no doc tag", typ.Name(), f.Name)
+			}
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package config
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/citihub/probr/utils"
+)
+
+// tagAliases maps an alias, such as "standard/cis/5.2.5", to the scenario and feature tags that it stands for.
+// Tags are held without "@".
+var (
+	tagAliases     = make(map[string][]string)
+	tagAliasesLock sync.RWMutex
+)
+
+// RegisterTagAliases makes a service pack's tag aliases available to every tag filter. Aliases
+// with the same name in more than one pack stand for the tags of all of them. This is called by
+// coreengine.RegisterServicePack and should not usually be called directly.
+func RegisterTagAliases(aliases map[string][]string) {
+	tagAliasesLock.Lock()
+	defer tagAliasesLock.Unlock()
+
+	for alias, tags := range aliases {
+		alias = strings.TrimPrefix(alias, "@")
+		for _, tag := range tags {
+			tag = strings.TrimPrefix(tag, "@")
+			if !found(tagAliases[alias], tag) {
+				tagAliases[alias] = append(tagAliases[alias], tag)
+			}
+		}
+	}
+}
+
+// TagAliases returns a copy of the registered tag aliases, without "@"
+func TagAliases() map[string][]string {
+	tagAliasesLock.RLock()
+	defer tagAliasesLock.RUnlock()
+
+	aliases := make(map[string][]string)
+	for alias, tags := range tagAliases {
+		aliases[alias] = append([]string{}, tags...)
+	}
+	return aliases
+}
+
+// ExpandTag returns the tag, without "@", followed by the tags that it stands for if it is an alias
+func ExpandTag(tag string) []string {
+	tag = strings.TrimPrefix(tag, "@")
+	return append([]string{tag}, aliasTargets(tag, TagAliases())...)
+}
+
+// expandTags replaces each alias in a Godog tag filter with the tags that it stands for, keeping the
+// alias itself in case a scenario is tagged with it. An alias also matches any alias that it is the
+// parent of, so "@standard/cis/5.2" expands to the tags of "@standard/cis/5.2.5" and "@standard/cis/5.2.7".
+//
+// Godog filters are a list of clauses separated by "&&", each of which is met if any of its ","
+// separated tags are. "@alias" is expanded within its clause, while "~@alias" must exclude every
+// tag the alias stands for, so the clause is repeated once for each of them.
+// The filter is returned unchanged if it contains no aliases.
+func expandTags(filter string, aliases map[string][]string) string {
+	var clauses [][]string
+	expanded := false
+	for _, clause := range strings.Split(filter, "&&") {
+		repeated := [][]string{{}}
+		for _, tag := range strings.Split(clause, ",") {
+			tag = strings.TrimSpace(tag)
+			negated := strings.HasPrefix(tag, "~")
+			name := strings.TrimPrefix(strings.TrimPrefix(tag, "~"), "@")
+			if name == "" {
+				continue
+			}
+			tags := append([]string{name}, aliasTargets(name, aliases)...)
+			expanded = expanded || len(tags) > 1
+			if !negated {
+				for i := range repeated {
+					repeated[i] = appendTags(repeated[i], "@", tags...)
+				}
+				continue
+			}
+			var next [][]string
+			for _, partial := range repeated {
+				for _, t := range tags {
+					next = append(next, appendTags(append([]string{}, partial...), "~@", t))
+				}
+			}
+			repeated = next
+		}
+		for _, c := range repeated {
+			if len(c) > 0 {
+				clauses = appendClause(clauses, c)
+			}
+		}
+	}
+	if !expanded {
+		return filter
+	}
+	var joined []string
+	for _, c := range clauses {
+		joined = append(joined, strings.Join(c, ","))
+	}
+	return strings.Join(joined, " && ")
+}
+
+// aliasTargets returns the tags that an alias stands for, including those of the aliases that it is the parent of
+func aliasTargets(name string, aliases map[string][]string) []string {
+	var matched []string
+	for alias := range aliases {
+		if alias == name || strings.HasPrefix(alias, name+"/") || strings.HasPrefix(alias, name+".") {
+			matched = append(matched, alias)
+		}
+	}
+	sort.Strings(matched)
+	var targets []string
+	for _, alias := range matched {
+		for _, tag := range aliases[alias] {
+			if tag != name && !found(targets, tag) {
+				targets = append(targets, tag)
+			}
+		}
+	}
+	return targets
+}
+
+// appendTags adds the tags to a clause with the prefix "@" or "~@", skipping any that are already in it
+func appendTags(clause []string, prefix string, tags ...string) []string {
+	for _, tag := range tags {
+		if !found(clause, prefix+tag) {
+			clause = append(clause, prefix+tag)
+		}
+	}
+	return clause
+}
+
+// appendClause adds a clause to the filter, unless an identical clause is already in it
+func appendClause(clauses [][]string, clause []string) [][]string {
+	for _, c := range clauses {
+		if strings.Join(c, ",") == strings.Join(clause, ",") {
+			return clauses
+		}
+	}
+	return append(clauses, clause)
+}
+
+func found(values []string, value string) bool {
+	_, found := utils.FindString(values, value)
+	return found
+}
@@ -0,0 +1,65 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestExpandTags(t *testing.T) {
+	aliases := map[string][]string{
+		"standard/cis/5.2.5":   {"k-pod-001", "k-pod-002"},
+		"standard/cis/5.2.7":   {"k-pod-012"},
+		"standard/cis/5.7.2":   {"k-pod-011"},
+		"csp/azure":            {"s-azaw", "k-iam"},
+		"probes/storage/azure": {"s-azaw"},
+	}
+	tests := []struct {
+		testName string
+		filter   string
+		expected string
+	}{
+		{testName: "NoAliases", filter: "@k-iam-001, @k-iam-002 && ~@k-gen", expected: "@k-iam-001, @k-iam-002 && ~@k-gen"},
+		{testName: "Empty", filter: "", expected: ""},
+		{testName: "Alias", filter: "@standard/cis/5.2.5", expected: "@standard/cis/5.2.5,@k-pod-001,@k-pod-002"},
+		{testName: "AliasWithoutAt", filter: "standard/cis/5.2.7", expected: "@standard/cis/5.2.7,@k-pod-012"},
+		{testName: "ParentAlias", filter: "@standard/cis/5.2", expected: "@standard/cis/5.2,@k-pod-001,@k-pod-002,@k-pod-012"},
+		{testName: "ParentAliasByPath", filter: "@standard/cis", expected: "@standard/cis,@k-pod-001,@k-pod-002,@k-pod-012,@k-pod-011"},
+		{testName: "PartialNameIsNotParent", filter: "@standard/ci", expected: "@standard/ci"},
+		{testName: "AliasWithinClause", filter: "@k-gen,@csp/azure && ~@k-iam-001", expected: "@k-gen,@csp/azure,@s-azaw,@k-iam && ~@k-iam-001"},
+		{testName: "NegatedAlias", filter: "~@csp/azure", expected: "~@csp/azure && ~@s-azaw && ~@k-iam"},
+		{testName: "NegatedAliasWithinClause", filter: "@k-gen,~@csp/azure", expected: "@k-gen,~@csp/azure && @k-gen,~@s-azaw && @k-gen,~@k-iam"},
+		{testName: "DuplicateClauses", filter: "~@probes/storage/azure && ~@s-azaw", expected: "~@probes/storage/azure && ~@s-azaw"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			if got := expandTags(tt.filter, aliases); got != tt.expected {
+				t.Errorf("expandTags(%s) = '%s', expected '%s'", tt.filter, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRegisterTagAliases(t *testing.T) {
+	defer func() { tagAliases = make(map[string][]string) }()
+	RegisterTagAliases(map[string][]string{"@control_type/preventative": {"@k-pod", "@k-iam"}})
+	RegisterTagAliases(map[string][]string{"control_type/preventative": {"s-azaw-002", "@k-pod"}})
+
+	aliases := TagAliases()
+	if got := aliases["control_type/preventative"]; len(got) != 3 || got[0] != "k-pod" || got[2] != "s-azaw-002" {
+		t.Errorf("TagAliases() = %v, expected the tags of both packs without '@'", aliases)
+	}
+
+	vars, _ := NewConfig("")
+	vars.Tags = "@control_type/preventative"
+	if tags := vars.GetTags(); tags != "@control_type/preventative,@k-pod,@k-iam,@s-azaw-002" {
+		t.Errorf("GetTags() = '%s', expected the alias to be expanded", tags)
+	}
+	if vars.Tags != "@control_type/preventative" {
+		t.Errorf("GetTags() modified Tags to '%s'", vars.Tags)
+	}
+
+	vars.Tags = ""
+	vars.Meta.Selections = []Selection{{Pack: "kubernetes", Probe: "iam", Tag: "control_type/preventative"}}
+	if tags := vars.ProbeTags("kubernetes", "iam"); tags != "@control_type/preventative,@k-pod,@k-iam,@s-azaw-002" {
+		t.Errorf("ProbeTags() = '%s', expected the selected alias to be expanded", tags)
+	}
+}
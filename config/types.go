@@ -2,105 +2,130 @@ package config
 
 // VarOptions contains all top-level config vars
 type VarOptions struct {
-	// NOTE: Env and Defaults are ONLY available if corresponding logic is added to defaults.go
-	ServicePacks              ServicePacks   `yaml:"ServicePacks"`
-	CloudProviders            CloudProviders `yaml:"CloudProviders"`
-	OutputType                string         `yaml:"OutputType"`
-	WriteDirectory            string         `yaml:"WriteDirectory"`
-	AuditEnabled              string         `yaml:"AuditEnabled"`
-	LogLevel                  string         `yaml:"LogLevel"`
-	OverwriteHistoricalAudits string         `yaml:"OverwriteHistoricalAudits"`
-	TagExclusions             []string       `yaml:"TagExclusions"`
-	WriteConfig               string         `yaml:"WriteConfig"`
-	Tags                      string         // set by flags
-	VarsFile                  string         // set by flags only
-	NoSummary                 bool           // set by flags only
-	Silent                    bool           // set by flags only
-	Meta                      Meta           // set by CLI options only
-	ResultsFormat             string         // set by flags only
+	// Each value may be set by a default tag, the vars file, an env tag and then the --set flag,
+	// with each overriding the last. See defaults.go and fields.go. The doc tag describes the value in
+	// the vars file generated by 'probr config init'. See scaffold.go.
+	ServicePacks              ServicePacks      `yaml:"ServicePacks" doc:"Settings of each service pack. A pack is only run if all of its required vars are set."`
+	CloudProviders            CloudProviders    `yaml:"CloudProviders" doc:"Settings of each cloud provider, which may be used by any service pack"`
+	OutputType                string            `yaml:"OutputType" enum:"IO,INMEM" env:"PROBR_OUTPUT_TYPE" default:"IO" doc:"IO writes results to files, as is needed by the CLI. INMEM keeps them in memory instead."`
+	WriteDirectory            string            `yaml:"WriteDirectory" env:"PROBR_WRITE_DIRECTORY" default:"probr_output" doc:"Path to all output, including audit, cucumber results and other temp files"`
+	AuditEnabled              Bool              `yaml:"AuditEnabled" env:"PROBR_AUDIT_ENABLED" default:"true" doc:"Write an audit of each probe to WriteDirectory"`
+	LogLevel                  string            `yaml:"LogLevel" enum:"DEBUG,INFO,NOTICE,WARN,ERROR" env:"PROBR_LOG_LEVEL" default:"ERROR" doc:"Log verbosity"`
+	OverwriteHistoricalAudits Bool              `yaml:"OverwriteHistoricalAudits" env:"OVERWRITE_AUDITS" default:"true" doc:"Allow the output of previous runs to be overwritten"`
+	TagExclusions             []string          `yaml:"TagExclusions" env:"PROBR_TAG_EXCLUSIONS" doc:"Tags of scenarios to exclude from every run"`
+	WriteConfig               Bool              `yaml:"WriteConfig" env:"PROBR_LOG_CONFIG" default:"true" doc:"Write the config of each run to config.json in WriteDirectory"`
+	ProbeConcurrency          int               `yaml:"ProbeConcurrency" env:"PROBR_PROBE_CONCURRENCY" default:"1" doc:"Maximum number of probes to run at the same time"`
+	PluginDirectory           string            `yaml:"PluginDirectory" env:"PROBR_PLUGIN_DIRECTORY" doc:"Directory containing service pack plugins"`
+	RunTimeout                Duration          `yaml:"RunTimeout" env:"PROBR_RUN_TIMEOUT" default:"0s" doc:"Maximum duration of the whole run, such as 1h30m. Zero means no limit."`
+	ProbeTimeout              Duration          `yaml:"ProbeTimeout" env:"PROBR_PROBE_TIMEOUT" default:"0s" doc:"Maximum duration of each probe. Zero means no limit."`
+	ScenarioTimeout           Duration          `yaml:"ScenarioTimeout" env:"PROBR_SCENARIO_TIMEOUT" default:"30m" doc:"Maximum duration of each scenario. Zero means no limit."`
+	Tags                      string            `yaml:"Tags" env:"PROBR_TAGS" doc:"Tags of the scenarios to run or exclude, such as @k-gen && ~@k-gen-001"`
+	VarsFile                  string            // set by flags only
+	Profile                   string            // set by the --profile flag or PROBR_PROFILE only. See profiles.go.
+	NoSummary                 bool              // set by flags only
+	Silent                    bool              // set by flags only
+	Strict                    bool              // set by flags only
+	Meta                      Meta              // set by CLI options only
+	ResultsFormat             string            `yaml:"ResultsFormat" enum:"cucumber,events,junit,pretty,progress" env:"PROBR_RESULTS_FORMAT" default:"cucumber" doc:"Format of the scenario results"`
+	RedactPatterns            []string          `yaml:"RedactPatterns" env:"PROBR_REDACT_PATTERNS" doc:"Regular expressions for secrets to be redacted, in addition to the defaults"`
+	origins                   map[string]string // Where each value was set, by key. See Origin.
+	references                map[string]string // References that values were resolved from, by key. See Unresolved.
+	redactor                  *redactor
+	exclusions                map[string]string // Justifications of the tags excluded by ExcludeTag, without "@"
 }
 
 // Meta config options
 type Meta struct {
-	RunOnly string // set by CLI 'run' option
+	Selections []Selection // set by CLI 'run' and 'plan' commands. If empty, all service packs are selected.
+}
+
+// Selection is a service pack, probe or scenario tag that was selected to be run from the command line
+type Selection struct {
+	Pack  string
+	Probe string // If empty, all of the pack's probes are selected
+	Tag   string // Scenario tag, without "@". If empty, all of the probe's scenarios are selected.
 }
 
 // ServicePacks config options
 type ServicePacks struct {
-	Kubernetes Kubernetes `yaml:"Kubernetes"`
-	Storage    Storage    `yaml:"Storage"`
-	APIM       APIM       `yaml:"APIM"`
+	Kubernetes Kubernetes `yaml:"Kubernetes" doc:"Probes of a kubernetes cluster"`
+	Storage    Storage    `yaml:"Storage" doc:"Probes of cloud storage accounts"`
+	APIM       APIM       `yaml:"APIM" doc:"Probes of cloud API management services"`
 }
 
 // Kubernetes config options
 type Kubernetes struct {
 	exclusionLogged                   bool
-	KeepPods                          string   `yaml:"KeepPods"` // TODO: Change type to bool, this would allow us to remove logic from kubernetes.GetKeepPodsFromConfig()
-	Probes                            []Probe  `yaml:"Probes"`
-	KubeConfigPath                    string   `yaml:"KubeConfig"`
-	KubeContext                       string   `yaml:"KubeContext"`
-	SystemClusterRoles                []string `yaml:"SystemClusterRoles"`
-	AuthorisedContainerRegistry       string   `yaml:"AuthorisedContainerRegistry"`
-	UnauthorisedContainerImage        string   `yaml:"UnauthorisedContainerImage"`
-	ProbeImage                        string   `yaml:"ProbeImage"`
-	ContainerRequiredDropCapabilities []string `yaml:"ContainerRequiredDropCapabilities"`
-	ContainerAllowedAddCapabilities   []string `yaml:"ContainerAllowedAddCapabilities"`
-	ApprovedVolumeTypes               []string `yaml:"ApprovedVolumeTypes"`
-	UnapprovedHostPort                string   `yaml:"UnapprovedHostPort"`
-	SystemNamespace                   string   `yaml:"SystemNamespace"`
-	ProbeNamespace                    string   `yaml:"ProbeNamespace"`
-	DashboardPodNamePrefix            string   `yaml:"DashboardPodNamePrefix"`
-	Azure                             K8sAzure `yaml:"Azure"`
+	KeepPods                          Bool     `yaml:"KeepPods" env:"PROBR_KEEP_PODS" default:"false" doc:"Leave the pods created by probes running after each scenario, e.g. for debugging"`
+	Probes                            []Probe  `yaml:"Probes" doc:"Probes and scenarios to exclude, each with a Waiver or an Excluded justification"`
+	KubeConfigPath                    string   `yaml:"KubeConfig" env:"KUBE_CONFIG" default:"~/.kube/config" doc:"Path to the kubernetes config"`
+	KubeContext                       string   `yaml:"KubeContext" env:"KUBE_CONTEXT" doc:"Context of the kubernetes config to use. If empty, its current context is used."`
+	SystemClusterRoles                []string `yaml:"SystemClusterRoles" env:"PROBR_K8S_SYSTEM_CLUSTER_ROLES" default:"system:,aks,cluster-admin,policy-agent" doc:"Name prefixes of the cluster roles that belong to the system"`
+	AuthorisedContainerRegistry       string   `yaml:"AuthorisedContainerRegistry" env:"PROBR_AUTHORISED_REGISTRY" doc:"Registry that pods are allowed to pull images from"`
+	UnauthorisedContainerImage        string   `yaml:"UnauthorisedContainerImage" env:"PROBR_UNAUTHORISED_REGISTRY" doc:"Image from a registry that pods should not be allowed to pull from"`
+	ProbeImage                        string   `yaml:"ProbeImage" env:"PROBR_PROBE_IMAGE" default:"citihub/probr-probe" doc:"Image of the pods created by probes"`
+	ContainerRequiredDropCapabilities []string `yaml:"ContainerRequiredDropCapabilities" env:"PROBR_REQUIRED_DROP_CAPABILITIES" default:"NET_RAW" doc:"Capabilities that every container must drop"`
+	ContainerAllowedAddCapabilities   []string `yaml:"ContainerAllowedAddCapabilities" env:"PROBR_ALLOWED_ADD_CAPABILITIES" default:"" doc:"Capabilities that containers are allowed to add"`
+	ApprovedVolumeTypes               []string `yaml:"ApprovedVolumeTypes" env:"PROBR_APPROVED_VOLUME_TYPES" default:"configmap,emptydir,persistentvolumeclaim" doc:"Volume types that pods are allowed to use"`
+	UnapprovedHostPort                string   `yaml:"UnapprovedHostPort" env:"PROBR_UNAPPROVED_HOSTPORT" default:"22" doc:"Host port that pods should not be allowed to use"`
+	SystemNamespace                   string   `yaml:"SystemNamespace" env:"PROBR_K8S_SYSTEM_NAMESPACE" default:"kube-system" doc:"Namespace of the kubernetes system pods"`
+	ProbeNamespace                    string   `yaml:"ProbeNamespace" env:"PROBR_K8S_PROBE_NAMESPACE" default:"probr-general-test-ns" doc:"Namespace in which probes create their pods"`
+	DashboardPodNamePrefix            string   `yaml:"DashboardPodNamePrefix" env:"PROBR_K8S_DASHBOARD_PODNAMEPREFIX" default:"kubernetes-dashboard" doc:"Name prefix of the kubernetes dashboard pods"`
+	PodWaitTimeout                    Duration `yaml:"PodWaitTimeout" env:"PROBR_K8S_POD_WAIT_TIMEOUT" default:"30s" doc:"How long to wait for a probe pod to be running"`
+	ExecTimeout                       Duration `yaml:"ExecTimeout" env:"PROBR_K8S_EXEC_TIMEOUT" default:"1m" doc:"How long a command executed within a probe pod may take"`
+	Azure                             K8sAzure `yaml:"Azure" doc:"Settings for clusters hosted by Azure"`
 }
 
 // K8sAzure contains Azure-specific options for the Kubernetes service pack
 type K8sAzure struct {
-	DefaultNamespaceAIB string
-	IdentityNamespace   string
+	DefaultNamespaceAIB string `yaml:"DefaultNamespaceAIB" env:"DEFAULT_NS_AZURE_IDENTITY_BINDING" default:"probr-aib" doc:"Namespace of the Azure identity binding used by the iam probe"`
+	IdentityNamespace   string `yaml:"IdentityNamespace" env:"PROBR_K8S_AZURE_IDENTITY_NAMESPACE" default:"kube-system" doc:"Namespace of the Azure identity components"`
 }
 
 // Storage service pack config options
 type Storage struct {
 	exclusionLogged bool
-	Provider        string  `yaml:"Provider"` // Placeholder!
-	Probes          []Probe `yaml:"Probes"`
+	Provider        string  `yaml:"Provider" enum:"Azure" env:"PROBR_STORAGE_PROVIDER" doc:"Cloud provider of the storage accounts"` // Placeholder!
+	Probes          []Probe `yaml:"Probes" doc:"Probes and scenarios to exclude, each with a Waiver or an Excluded justification"`
 }
 
 // APIM service pack config options
 type APIM struct {
 	exclusionLogged bool
-	Provider        string  `yaml:"Provider"` // Placeholder!
-	Probes          []Probe `yaml:"Probes"`
+	Provider        string  `yaml:"Provider" enum:"Azure" env:"PROBR_APIM_PROVIDER" doc:"Cloud provider of the API management services"` // Placeholder!
+	Probes          []Probe `yaml:"Probes" doc:"Probes and scenarios to exclude, each with a Waiver or an Excluded justification"`
 }
 
 // Probe config options
 type Probe struct {
-	Name      string     `yaml:"Name"`
-	Excluded  string     `yaml:"Excluded"`
-	Scenarios []Scenario `yaml:"Scenarios"`
+	Name      string     `yaml:"Name" doc:"Name of the probe, as shown by 'probr list'"`
+	Excluded  string     `yaml:"Excluded" doc:"Justification for excluding the probe indefinitely. Prefer a Waiver."`
+	Waiver    Waiver     `yaml:"Waiver" doc:"Excludes the probe until the waiver expires"`
+	Scenarios []Scenario `yaml:"Scenarios" doc:"Scenarios of the probe to exclude"`
 }
 
 // Scenario config options
 type Scenario struct {
-	Name     string `yaml:"Name"`
-	Excluded string `yaml:"Excluded"`
+	Name     string `yaml:"Name" doc:"A tag that the scenario declares in its feature file, e.g. k-iam-001"`
+	Excluded string `yaml:"Excluded" doc:"Justification for excluding the scenario indefinitely. Prefer a Waiver."`
+	Waiver   Waiver `yaml:"Waiver" doc:"Excludes the scenario until the waiver expires"`
 }
 
 // CloudProviders config options
 type CloudProviders struct {
-	Azure Azure `yaml:"Azure"`
+	Azure Azure `yaml:"Azure" doc:"Azure credentials and settings"`
 }
 
 // Azure config options that may be required by any service pack
 type Azure struct {
-	Excluded         string `yaml:"Excluded"`
-	TenantID         string `yaml:"TenantID"`
-	SubscriptionID   string `yaml:"SubscriptionID"`
-	ClientID         string `yaml:"ClientID"`
-	ClientSecret     string `yaml:"ClientSecret"`
-	ResourceGroup    string `yaml:"ResourceGroup"`
-	ResourceLocation string `yaml:"ResourceLocation"`
-	ManagementGroup  string `yaml:"ManagementGroup"`
+	Excluded         string `yaml:"Excluded" env:"PROBR_AZURE_EXCLUDED" doc:"Justification for excluding Azure"`
+	TenantID         string `yaml:"TenantID" env:"AZURE_TENANT_ID" doc:"Azure tenant ID"`
+	SubscriptionID   string `yaml:"SubscriptionID" env:"AZURE_SUBSCRIPTION_ID" doc:"Azure subscription ID"`
+	ClientID         string `yaml:"ClientID" env:"AZURE_CLIENT_ID" doc:"Client ID of the service principal used by probes"`
+	ClientSecret     string `yaml:"ClientSecret" env:"AZURE_CLIENT_SECRET" sensitive:"true" doc:"Client secret of the service principal used by probes"`
+	ResourceGroup    string `yaml:"ResourceGroup" env:"AZURE_RESOURCE_GROUP" doc:"Resource group in which probes create resources"`
+	ResourceLocation string `yaml:"ResourceLocation" env:"AZURE_RESOURCE_LOCATION" doc:"Location of the resources created by probes, e.g. westeurope"`
+	ManagementGroup  string `yaml:"ManagementGroup" env:"AZURE_MANAGEMENT_GROUP" doc:"Azure management group"`
 }
 
 // Excludable is used for testing purposes only
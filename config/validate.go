@@ -0,0 +1,272 @@
+package config
+
+import (
+	"encoding"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/citihub/probr/utils"
+	"gopkg.in/yaml.v3"
+)
+
+// ValidationError describes a problem with a single key or value in a vars file
+type ValidationError struct {
+	Line    int
+	Key     string // Path to the key, e.g. "ServicePacks.Kubernetes.KubeConfig"
+	Message string
+}
+
+func (e ValidationError) Error() string {
+	return fmt.Sprintf("line %v: %s: %s", e.Line, e.Key, e.Message)
+}
+
+// ValidationErrors holds every problem that was found in a vars file, in the order they appear
+type ValidationErrors []ValidationError
+
+func (e ValidationErrors) Error() string {
+	var lines []string
+	for _, err := range e {
+		lines = append(lines, err.Error())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ValidateVarsFile checks the vars file at the provided path against the schema of VarOptions,
+// without connecting to any cluster or cloud provider. Any problems are returned as ValidationErrors.
+func ValidateVarsFile(path string) error {
+	if err := ValidateConfigPath(path); err != nil {
+		return err
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return validateVars(data)
+}
+
+// validateVars reports keys that are not part of VarOptions or Profile, values of the wrong type,
+// and values that are not among those allowed by a field's enum tag
+func validateVars(data []byte) error {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	if len(doc.Content) == 0 {
+		return nil // Empty file
+	}
+	var errs ValidationErrors
+	root := doc.Content[0]
+	if profiles := takeProfiles(root); profiles != nil {
+		validateProfiles(profiles, &errs)
+	}
+	validateNode(root, reflect.TypeOf(VarOptions{}), "", &errs)
+	if len(errs) > 0 {
+		sort.SliceStable(errs, func(i, j int) bool { return errs[i].Line < errs[j].Line })
+		return errs
+	}
+	return nil
+}
+
+func validateNode(node *yaml.Node, t reflect.Type, key string, errs *ValidationErrors) {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	if node.Kind == yaml.ScalarNode && node.Value == "" {
+		return // Empty values are left to the env vars and defaults
+	}
+	fail := func(format string, v ...interface{}) {
+		*errs = append(*errs, ValidationError{Line: node.Line, Key: displayKey(key), Message: fmt.Sprintf(format, v...)})
+	}
+
+	// Types such as Bool and Duration are validated by their own parsing
+	if u, ok := reflect.New(t).Interface().(encoding.TextUnmarshaler); ok {
+		if node.Kind != yaml.ScalarNode {
+			fail("expected a single value, but found %s", describeNode(node))
+		} else if err := u.UnmarshalText([]byte(node.Value)); err != nil {
+			fail("%v", err)
+		}
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			fail("expected a map of keys, but found %s", describeNode(node))
+			return
+		}
+		fields := yamlFields(t)
+		seen := make(map[string]bool)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			k, v := node.Content[i], node.Content[i+1]
+			child := joinKey(key, k.Value)
+			if seen[k.Value] {
+				*errs = append(*errs, ValidationError{Line: k.Line, Key: child, Message: "key is defined more than once"})
+				continue
+			}
+			seen[k.Value] = true
+			field, known := fields[k.Value]
+			if !known {
+				*errs = append(*errs, ValidationError{Line: k.Line, Key: child, Message: unknownKeyMessage(k.Value, fields)})
+				continue
+			}
+			validateNode(v, field.Type, child, errs)
+			if values := field.Tag.Get("enum"); values != "" && v.Kind == yaml.ScalarNode && v.Value != "" {
+				validateEnum(v, child, strings.Split(values, ","), errs)
+			}
+		}
+	case reflect.Slice:
+		if node.Kind != yaml.SequenceNode {
+			fail("expected a list, but found %s", describeNode(node))
+			return
+		}
+		for i, item := range node.Content {
+			validateNode(item, t.Elem(), fmt.Sprintf("%s[%v]", key, i), errs)
+		}
+	case reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			fail("expected a map of keys, but found %s", describeNode(node))
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			validateNode(node.Content[i+1], t.Elem(), joinKey(key, node.Content[i].Value), errs)
+		}
+	case reflect.String:
+		if node.Kind != yaml.ScalarNode {
+			fail("expected a single value, but found %s", describeNode(node))
+		}
+	case reflect.Int, reflect.Int64:
+		if node.Kind != yaml.ScalarNode || node.Tag != "!!int" {
+			fail("expected a whole number, but found %s", describeNode(node))
+		}
+	case reflect.Bool:
+		if node.Kind != yaml.ScalarNode || node.Tag != "!!bool" {
+			fail("expected true or false, but found %s", describeNode(node))
+		}
+	}
+}
+
+func validateEnum(node *yaml.Node, key string, values []string, errs *ValidationErrors) {
+	if _, found := utils.FindString(values, node.Value); found {
+		return
+	}
+	message := fmt.Sprintf("'%s' is not one of %v", node.Value, values)
+	if suggestions := suggest(node.Value, values); len(suggestions) > 0 {
+		message = fmt.Sprintf("%s, did you mean %s?", message, quoteJoin(suggestions))
+	}
+	*errs = append(*errs, ValidationError{Line: node.Line, Key: key, Message: message})
+}
+
+func unknownKeyMessage(name string, fields map[string]reflect.StructField) string {
+	var names []string
+	for n := range fields {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	if suggestions := suggest(name, names); len(suggestions) > 0 {
+		return fmt.Sprintf("unknown key, did you mean %s?", quoteJoin(suggestions))
+	}
+	return fmt.Sprintf("unknown key, expected one of %v", names)
+}
+
+// yamlFields maps the keys that may be used in a vars file to the fields of a struct. Fields
+// without an explicit yaml key, such as those that are only set by flags, are not included.
+func yamlFields(t reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		if field.PkgPath != "" || name == "" || name == "-" {
+			continue
+		}
+		fields[name] = field
+	}
+	return fields
+}
+
+// suggest returns up to three candidates that are similar to name, ignoring case, for "did you mean" messages.
+// More than one may be returned, as a misspelling of one key is often closest to another, similarly named key.
+func suggest(name string, candidates []string) []string {
+	limit := len(name) / 3
+	if limit < 2 {
+		limit = 2
+	}
+	distances := make(map[string]int)
+	var suggestions []string
+	for _, c := range candidates {
+		if d := editDistance(strings.ToLower(name), strings.ToLower(c)); d <= limit {
+			distances[c] = d
+			suggestions = append(suggestions, c)
+		}
+	}
+	sort.SliceStable(suggestions, func(i, j int) bool { return distances[suggestions[i]] < distances[suggestions[j]] })
+	if len(suggestions) > 3 {
+		suggestions = suggestions[:3]
+	}
+	return suggestions
+}
+
+// editDistance returns the Levenshtein distance between two strings
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = minInt(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+func minInt(values ...int) int {
+	min := values[0]
+	for _, v := range values[1:] {
+		if v < min {
+			min = v
+		}
+	}
+	return min
+}
+
+func quoteJoin(values []string) string {
+	var quoted []string
+	for _, v := range values {
+		quoted = append(quoted, "'"+v+"'")
+	}
+	return strings.Join(quoted, " or ")
+}
+
+func describeNode(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.MappingNode:
+		return "a map of keys"
+	case yaml.SequenceNode:
+		return "a list"
+	}
+	return fmt.Sprintf("'%s'", node.Value)
+}
+
+func joinKey(parent, child string) string {
+	if parent == "" {
+		return child
+	}
+	return parent + "." + child
+}
+
+func displayKey(key string) string {
+	if key == "" {
+		return "(top level)"
+	}
+	return key
+}
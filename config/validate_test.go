@@ -0,0 +1,87 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateVars(t *testing.T) {
+	tests := []struct {
+		testName       string
+		yaml           string
+		expectedErrors []string
+	}{
+		{testName: "Empty", yaml: "", expectedErrors: nil},
+		{testName: "Valid", yaml: "LogLevel: DEBUG\nProbeConcurrency: 2\nTags: '@k-iam'\nServicePacks:\n  Kubernetes:\n    KubeConfig:\n    Azure:\n      IdentityNamespace: probr\n", expectedErrors: nil},
+		{testName: "MisspelledKey", yaml: "ServicePacks:\n  Kubernetes:\n    UnauthorisedContainerRegistry: docker.io\n", expectedErrors: []string{"line 3: ServicePacks.Kubernetes.UnauthorisedContainerRegistry: unknown key, did you mean 'AuthorisedContainerRegistry' or 'UnauthorisedContainerImage'?"}},
+		{testName: "UnknownKey", yaml: "Colour: blue\n", expectedErrors: []string{"line 1: Colour: unknown key, expected one of"}},
+		{testName: "FlagOnlyKey", yaml: "Silent: true\n", expectedErrors: []string{"line 1: Silent: unknown key"}},
+		{testName: "InvalidEnum", yaml: "OutputType: inmem\n", expectedErrors: []string{"line 1: OutputType: 'inmem' is not one of [IO INMEM], did you mean 'INMEM'?"}},
+		{testName: "WrongTypes", yaml: "ProbeConcurrency: two\nTagExclusions: k-iam\nServicePacks: kubernetes\n", expectedErrors: []string{
+			"line 1: ProbeConcurrency: expected a whole number, but found 'two'",
+			"line 2: TagExclusions: expected a list, but found 'k-iam'",
+			"line 3: ServicePacks: expected a map of keys, but found 'kubernetes'",
+		}},
+		{testName: "ListItems", yaml: "ServicePacks:\n  Storage:\n    Probes:\n      - Name: access_whitelisting\n        Exclude: true\n", expectedErrors: []string{"line 5: ServicePacks.Storage.Probes[0].Exclude: unknown key, did you mean 'Excluded'?"}},
+		{testName: "TypedValues", yaml: "AuditEnabled: false\nRunTimeout: 1h30m\nServicePacks:\n  Kubernetes:\n    KeepPods: \"true\"\n", expectedErrors: nil},
+		{testName: "InvalidTypedValues", yaml: "RunTimeout: soon\nServicePacks:\n  Kubernetes:\n    KeepPods: yes please\n", expectedErrors: []string{
+			"line 1: RunTimeout: 'soon' is not a duration",
+			"line 4: ServicePacks.Kubernetes.KeepPods: 'yes please' is not a boolean",
+		}},
+		{testName: "Waiver", yaml: "ServicePacks:\n  Kubernetes:\n    Probes:\n      - Name: iam\n        Waiver:\n          Justification: No pod identity\n          Ticket: SEC-1\n          Expires: 2021-06-30\n", expectedErrors: nil},
+		{testName: "WaiverInvalidDate", yaml: "ServicePacks:\n  Kubernetes:\n    Probes:\n      - Name: iam\n        Waiver:\n          Justification: No pod identity\n          Expires: 30/06/2021\n", expectedErrors: []string{"line 7: ServicePacks.Kubernetes.Probes[0].Waiver.Expires: '30/06/2021' is not a date"}},
+		{testName: "Profiles", yaml: "Profiles:\n  prod:\n    ServicePacks:\n      Kubernetes:\n        KubeContext: prod\n    CloudProviders:\n      Azure:\n        SubscriptionID: abc\nLogLevel: INFO\n", expectedErrors: nil},
+		{testName: "ProfileInvalid", yaml: "Colour: blue\nProfiles:\n  prod:\n    LogLevel: DEBUG\n    ServicePacks:\n      Kubernetes:\n        KeepPods: maybe\n", expectedErrors: []string{
+			"line 1: Colour: unknown key",
+			"line 4: Profiles.prod.LogLevel: unknown key, expected one of [CloudProviders ServicePacks]",
+			"line 7: Profiles.prod.ServicePacks.Kubernetes.KeepPods: 'maybe' is not a boolean",
+		}},
+		{testName: "ProfilesNotMap", yaml: "Profiles: prod\n", expectedErrors: []string{"line 1: Profiles: expected a map of profiles by name, but found 'prod'"}},
+		{testName: "DuplicateKey", yaml: "LogLevel: INFO\nLogLevel: DEBUG\n", expectedErrors: []string{"line 2: LogLevel: key is defined more than once"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			err := validateVars([]byte(tt.yaml))
+			if len(tt.expectedErrors) == 0 {
+				if err != nil {
+					t.Errorf("validateVars() returned unexpected errors:\n%v", err)
+				}
+				return
+			}
+			errs, ok := err.(ValidationErrors)
+			if !ok || len(errs) != len(tt.expectedErrors) {
+				t.Fatalf("validateVars() = %v, expected %v errors", err, len(tt.expectedErrors))
+			}
+			for i, expected := range tt.expectedErrors {
+				if !strings.HasPrefix(errs[i].Error(), expected) {
+					t.Errorf("validateVars() error = '%v', expected '%s'", errs[i], expected)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateVarsFile_Example(t *testing.T) {
+	if err := ValidateVarsFile("../examples/config.yml"); err != nil {
+		t.Errorf("The example vars file is not valid:\n%v", err)
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected []string
+	}{
+		{name: "kubeconfig", expected: []string{"KubeConfig"}},
+		{name: "KubConfig", expected: []string{"KubeConfig"}},
+		{name: "Nothing", expected: nil},
+	}
+	candidates := []string{"KubeConfig", "KubeContext", "Probes"}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if s := suggest(tt.name, candidates); strings.Join(s, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("suggest('%s') = %v, expected %v", tt.name, s, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Bool is a config flag that may be set in a vars file or env var as a YAML boolean, or as a
+// string such as "true" or "false", which is how these values were configured in the past
+type Bool bool
+
+// UnmarshalText parses any value accepted by strconv.ParseBool
+func (b *Bool) UnmarshalText(text []byte) error {
+	value, err := strconv.ParseBool(string(text))
+	if err != nil {
+		return fmt.Errorf("'%s' is not a boolean. Expected true or false", text)
+	}
+	*b = Bool(value)
+	return nil
+}
+
+// Duration is a length of time that may be set in a vars file or env var in any format
+// accepted by time.ParseDuration, such as "90s" or "1h30m". Zero means no limit.
+type Duration time.Duration
+
+// UnmarshalText parses a non-negative duration, such as "90s" or "1h30m"
+func (d *Duration) UnmarshalText(text []byte) error {
+	value, err := time.ParseDuration(string(text))
+	if err != nil || value < 0 {
+		return fmt.Errorf("'%s' is not a duration. Expected a value such as '90s' or '1h30m'", text)
+	}
+	*d = Duration(value)
+	return nil
+}
+
+// MarshalText writes the duration in the same format as it is read, e.g. "1h30m0s"
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// Date is a calendar day, written as YYYY-MM-DD
+type Date time.Time
+
+// dateLayout is the format of a Date
+const dateLayout = "2006-01-02"
+
+// UnmarshalText parses a date such as "2021-06-30"
+func (d *Date) UnmarshalText(text []byte) error {
+	value, err := time.ParseInLocation(dateLayout, string(text), time.Local)
+	if err != nil {
+		return fmt.Errorf("'%s' is not a date. Expected a value such as '2021-06-30'", text)
+	}
+	*d = Date(value)
+	return nil
+}
+
+// MarshalText writes the date in the same format as it is read, or nothing if it is not set
+func (d Date) MarshalText() ([]byte, error) {
+	if d.IsZero() {
+		return []byte{}, nil
+	}
+	return []byte(d.String()), nil
+}
+
+// IsZero returns whether the date is not set
+func (d Date) IsZero() bool {
+	return time.Time(d).IsZero()
+}
+
+func (d Date) String() string {
+	return time.Time(d).Format(dateLayout)
+}
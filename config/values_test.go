@@ -0,0 +1,83 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBool_UnmarshalText(t *testing.T) {
+	tests := []struct {
+		testName    string
+		text        string
+		expected    Bool
+		expectError bool
+	}{
+		{testName: "True", text: "true", expected: true},
+		{testName: "False", text: "false", expected: false},
+		{testName: "Capitalised", text: "True", expected: true},
+		{testName: "Invalid", text: "yes", expectError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			var b Bool
+			err := b.UnmarshalText([]byte(tt.text))
+			if (err != nil) != tt.expectError {
+				t.Fatalf("UnmarshalText(%s) error = %v, expected error: %v", tt.text, err, tt.expectError)
+			}
+			if b != tt.expected {
+				t.Errorf("UnmarshalText(%s) = %v, expected %v", tt.text, b, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDuration_UnmarshalText(t *testing.T) {
+	tests := []struct {
+		testName    string
+		text        string
+		expected    Duration
+		expectError bool
+	}{
+		{testName: "Seconds", text: "90s", expected: Duration(90 * time.Second)},
+		{testName: "Compound", text: "1h30m", expected: Duration(90 * time.Minute)},
+		{testName: "Zero", text: "0", expected: 0},
+		{testName: "MissingUnit", text: "90", expectError: true},
+		{testName: "Negative", text: "-1m", expectError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			var d Duration
+			err := d.UnmarshalText([]byte(tt.text))
+			if (err != nil) != tt.expectError {
+				t.Fatalf("UnmarshalText(%s) error = %v, expected error: %v", tt.text, err, tt.expectError)
+			}
+			if d != tt.expected {
+				t.Errorf("UnmarshalText(%s) = %v, expected %v", tt.text, time.Duration(d), time.Duration(tt.expected))
+			}
+		})
+	}
+}
+
+func TestDate_UnmarshalText(t *testing.T) {
+	tests := []struct {
+		testName    string
+		text        string
+		expectError bool
+	}{
+		{testName: "Date", text: "2021-06-30"},
+		{testName: "DateTime", text: "2021-06-30T12:00:00Z", expectError: true},
+		{testName: "DayFirst", text: "30/06/2021", expectError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			var d Date
+			err := d.UnmarshalText([]byte(tt.text))
+			if (err != nil) != tt.expectError {
+				t.Fatalf("UnmarshalText(%s) error = %v, expected error: %v", tt.text, err, tt.expectError)
+			}
+			if !tt.expectError && d.String() != tt.text {
+				t.Errorf("UnmarshalText(%s) = %v", tt.text, d)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package config
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// now is replaced in tests, so that waivers can be checked against a fixed date
+var now = time.Now
+
+// Waiver excludes a probe or scenario from the run, until it expires
+type Waiver struct {
+	Justification string `yaml:"Justification" doc:"Required. A waiver without a justification is ignored."`
+	Approver      string `yaml:"Approver" doc:"Who approved the waiver"`
+	Ticket        string `yaml:"Ticket" doc:"Reference to the approval of the waiver, such as a ticket number"`
+	Expires       Date   `yaml:"Expires" doc:"Last day on which the waiver applies, e.g. 2021-06-30. If empty, it never expires."`
+}
+
+// WaiverRecord is a waiver along with the probe or scenario that it applies to, as listed in the summary
+type WaiverRecord struct {
+	Pack     string
+	Probe    string
+	Scenario string `json:",omitempty"`
+	Waiver
+}
+
+// Active returns whether the waiver currently excludes its probe or scenario
+func (w Waiver) Active() bool {
+	return w.Justification != "" && !w.Expired()
+}
+
+// Expired returns whether the waiver had an expiry date that has passed
+func (w Waiver) Expired() bool {
+	if w.Justification == "" || w.Expires.IsZero() {
+		return false
+	}
+	return !now().Before(time.Time(w.Expires).AddDate(0, 0, 1))
+}
+
+// String describes the waiver for logs and the plan, e.g. "Not deployed (approved by J. Smith, ticket SEC-1, expires 2021-06-30)"
+func (w Waiver) String() string {
+	var details []string
+	if w.Approver != "" {
+		details = append(details, "approved by "+w.Approver)
+	}
+	if w.Ticket != "" {
+		details = append(details, "ticket "+w.Ticket)
+	}
+	if !w.Expires.IsZero() {
+		details = append(details, "expires "+w.Expires.String())
+	}
+	if len(details) == 0 {
+		return w.Justification
+	}
+	return fmt.Sprintf("%s (%s)", w.Justification, strings.Join(details, ", "))
+}
+
+// Justification returns why the probe is excluded, or "" if it is not
+func (p Probe) Justification() string {
+	if p.Excluded != "" {
+		return p.Excluded
+	}
+	if p.Waiver.Active() {
+		return p.Waiver.String()
+	}
+	return ""
+}
+
+// Justification returns why the scenario is excluded, or "" if it is not
+func (s Scenario) Justification() string {
+	if s.Excluded != "" {
+		return s.Excluded
+	}
+	if s.Waiver.Active() {
+		return s.Waiver.String()
+	}
+	return ""
+}
+
+// logExpiredWaiver warns that a probe or scenario has been brought back into the run
+func logExpiredWaiver(name string, w Waiver) {
+	if w.Expired() {
+		log.Printf("[WARN] The waiver for %s expired on %s, so it is no longer excluded. Justification was: %s", name, w.Expires, w.String())
+	}
+}
+
+// Waivers returns the active and expired waivers of every service pack's probes and scenarios.
+// An Excluded value is listed as an active waiver that only has a justification.
+func (ctx *VarOptions) Waivers() (active, expired []WaiverRecord) {
+	active, expired = []WaiverRecord{}, []WaiverRecord{} // Listed as empty rather than null in the summary
+	add := func(record WaiverRecord, excluded string) {
+		if excluded != "" {
+			record.Waiver = Waiver{Justification: excluded}
+		}
+		switch {
+		case record.Waiver.Active():
+			active = append(active, record)
+		case record.Waiver.Expired():
+			expired = append(expired, record)
+		}
+	}
+	for _, name := range GetPacks() {
+		pc := getPackConfig(name)
+		if pc.Probes == nil {
+			continue
+		}
+		for _, probe := range pc.Probes(ctx) {
+			add(WaiverRecord{Pack: name, Probe: probe.Name, Waiver: probe.Waiver}, probe.Excluded)
+			if probe.Justification() != "" {
+				continue // Its scenarios are excluded along with it
+			}
+			for _, scenario := range probe.Scenarios {
+				add(WaiverRecord{Pack: name, Probe: probe.Name, Scenario: scenario.Name, Waiver: scenario.Waiver}, scenario.Excluded)
+			}
+		}
+	}
+	return
+}
@@ -0,0 +1,74 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func date(s string) Date {
+	var d Date
+	d.UnmarshalText([]byte(s))
+	return d
+}
+
+func TestWaiver_Active(t *testing.T) {
+	defer func() { now = time.Now }()
+	now = func() time.Time { return time.Date(2021, 6, 30, 15, 0, 0, 0, time.Local) }
+
+	tests := []struct {
+		testName        string
+		waiver          Waiver
+		expectedActive  bool
+		expectedExpired bool
+	}{
+		{testName: "NoWaiver", waiver: Waiver{}},
+		{testName: "NoJustification", waiver: Waiver{Ticket: "SEC-1", Expires: date("2021-07-01")}},
+		{testName: "NoExpiry", waiver: Waiver{Justification: "Not deployed"}, expectedActive: true},
+		{testName: "ExpiresLater", waiver: Waiver{Justification: "Not deployed", Expires: date("2021-07-01")}, expectedActive: true},
+		{testName: "ExpiresToday", waiver: Waiver{Justification: "Not deployed", Expires: date("2021-06-30")}, expectedActive: true},
+		{testName: "Expired", waiver: Waiver{Justification: "Not deployed", Expires: date("2021-06-29")}, expectedExpired: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			if active := tt.waiver.Active(); active != tt.expectedActive {
+				t.Errorf("Active() = %v, expected %v", active, tt.expectedActive)
+			}
+			if expired := tt.waiver.Expired(); expired != tt.expectedExpired {
+				t.Errorf("Expired() = %v, expected %v", expired, tt.expectedExpired)
+			}
+		})
+	}
+}
+
+func TestWaiver_String(t *testing.T) {
+	w := Waiver{Justification: "Not deployed", Approver: "J. Smith", Ticket: "SEC-1", Expires: date("2021-06-30")}
+	expected := "Not deployed (approved by J. Smith, ticket SEC-1, expires 2021-06-30)"
+	if s := w.String(); s != expected {
+		t.Errorf("String() = '%s', expected '%s'", s, expected)
+	}
+}
+
+func TestWaivers(t *testing.T) {
+	registerTestPacks()
+	defer func() { now = time.Now }()
+	now = func() time.Time { return time.Date(2021, 6, 30, 0, 0, 0, 0, time.Local) }
+
+	config, _ := NewConfig("")
+	config.ServicePacks.Kubernetes.Probes = []Probe{
+		{Name: "iam", Waiver: Waiver{Justification: "No pod identity", Expires: date("2021-12-31")}},
+		{Name: "general", Waiver: Waiver{Justification: "Pending fix", Expires: date("2021-01-31")}},
+		{Name: "internet_access", Scenarios: []Scenario{{Name: "1.0", Excluded: "Proxy is not in use"}}},
+		{Name: "pod_security_policy", Excluded: "out", Scenarios: []Scenario{{Name: "1.1", Excluded: "Covered by the probe"}}},
+	}
+
+	active, expired := config.Waivers()
+	if len(active) != 3 || len(expired) != 1 {
+		t.Fatalf("Waivers() = %v active and %v expired, expected 3 and 1", len(active), len(expired))
+	}
+	if active[0].Probe != "iam" || active[1].Scenario != "1.0" || active[2].Probe != "pod_security_policy" || active[2].Scenario != "" {
+		t.Errorf("Waivers() returned unexpected active waivers: %+v", active)
+	}
+	if expired[0].Probe != "general" {
+		t.Errorf("Waivers() returned unexpected expired waivers: %+v", expired)
+	}
+}
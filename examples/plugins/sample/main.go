@@ -0,0 +1,74 @@
+// Sample service pack plugin for probr.
+//
+// Build this file and place the binary in the directory specified by PluginDirectory:
+//
+//	go build -o ./plugins/sample ./examples/plugins/sample
+//	probr --plugindirectory=./plugins
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/citihub/probr/service_packs/plugin"
+)
+
+const feature = `@sample
+Feature: Sample plugin probe
+  As a plugin author
+  I want to see how a plugin reports its results
+  So that I can write my own
+
+  @sample-001
+  Scenario: Environment variable is set
+    Given the environment variable "HOME" is set
+    Then the sample control is met
+`
+
+type samplePlugin struct{}
+
+func (p samplePlugin) Manifest() plugin.Manifest {
+	return plugin.Manifest{
+		Name: "sample",
+		Probes: []plugin.ProbeManifest{
+			{Name: "sample_probe", Feature: feature},
+		},
+	}
+}
+
+func (p samplePlugin) BeforeScenario(params plugin.ScenarioParams) error {
+	log.Printf("[INFO] sample plugin starting scenario '%s'", params.Scenario)
+	return nil
+}
+
+func (p samplePlugin) AfterScenario(params plugin.ScenarioParams) error {
+	return nil
+}
+
+func (p samplePlugin) RunStep(params plugin.StepParams) plugin.StepResult {
+	var name string
+	if _, err := fmt.Sscanf(params.Step, "the environment variable %q is set", &name); err == nil {
+		value, found := os.LookupEnv(name)
+		payload, _ := json.Marshal(struct{ Name string }{name})
+		if !found || value == "" {
+			return plugin.StepResult{Result: plugin.StepFailed, Function: "environmentVariableIsSet", Payload: payload,
+				Error: fmt.Sprintf("environment variable '%s' is not set", name)}
+		}
+		return plugin.StepResult{Result: plugin.StepPassed, Function: "environmentVariableIsSet", Payload: payload,
+			Description: fmt.Sprintf("Checked that environment variable '%s' is set;", name)}
+	}
+	if params.Step == "the sample control is met" {
+		return plugin.StepResult{Result: plugin.StepPassed, Function: "sampleControlIsMet"}
+	}
+	return plugin.StepResult{Result: plugin.StepPending}
+}
+
+func main() {
+	// stdout is reserved for the plugin protocol, so logs must go to stderr
+	log.SetOutput(os.Stderr)
+	if err := plugin.Serve(samplePlugin{}); err != nil {
+		log.Fatalf("[ERROR] %v", err)
+	}
+}
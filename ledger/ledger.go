@@ -0,0 +1,227 @@
+// Package ledger records the resources that probes create, so that any left behind by an aborted or failed
+// run can be deleted later by 'probr cleanup'. The ledger is kept in the write directory of the run.
+package ledger
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/citihub/probr/config"
+	"github.com/citihub/probr/utils"
+)
+
+// Resource is a resource created by a probe, which remains in the ledger until it is deleted
+type Resource struct {
+	Kind      string            `json:"kind"`            // e.g. "kubernetes/pod", which selects the Deleter used by Cleanup
+	Group     string            `json:"group,omitempty"` // The Kubernetes namespace or Azure resource group
+	Name      string            `json:"name"`
+	Location  map[string]string `json:"location,omitempty"` // Where the resource was created, e.g. the kube context
+	Run       string            `json:"run,omitempty"`      // ID of the run that created the resource, see WithRun
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+type runKey struct{}
+
+// WithRun returns a copy of the context whose resources are recorded as created by the run with the given ID,
+// so that an aborted run can delete its own resources with CleanupRun, without those of other runs.
+func WithRun(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, runKey{}, id)
+}
+
+// RunID returns the ID of the run carried by the context, or "" if it carries none
+func RunID(ctx context.Context) string {
+	id, _ := ctx.Value(runKey{}).(string)
+	return id
+}
+
+// NewRunID returns an ID for a run, which is unique among the runs that share a write directory
+func NewRunID() string {
+	return time.Now().UTC().Format("20060102T150405") + "-" + strings.ToLower(utils.RandomString(8))
+}
+
+// Deleter deletes a resource that is listed in the ledger. It should return nil if the resource no longer exists.
+type Deleter func(ctx context.Context, r Resource) error
+
+// fileName is the name of the ledger in the write directory
+const fileName = "resources.json"
+
+var (
+	deleters = make(map[string]Deleter)
+	lock     sync.Mutex // guards the deleters and every ledger file, as runs may share a write directory
+)
+
+// RegisterDeleter is used by the packages that create resources to declare how each kind is deleted
+func RegisterDeleter(kind string, deleter Deleter) {
+	lock.Lock()
+	defer lock.Unlock()
+	deleters[kind] = deleter
+}
+
+// String identifies the resource in logs, e.g. "kubernetes/pod probr-general/probr-pod-abc"
+func (r Resource) String() string {
+	if r.Group == "" {
+		return fmt.Sprintf("%s %s", r.Kind, r.Name)
+	}
+	return fmt.Sprintf("%s %s/%s", r.Kind, r.Group, r.Name)
+}
+
+// same reports whether both entries refer to the same resource
+func (r Resource) same(other Resource) bool {
+	return r.Kind == other.Kind && r.Group == other.Group && r.Name == other.Name && reflect.DeepEqual(r.Location, other.Location)
+}
+
+// Path returns the path of the ledger in the write directory of the config carried by ctx
+func Path(ctx context.Context) string {
+	return filepath.Join(config.FromContext(ctx).GetWriteDirectory(), fileName)
+}
+
+// Record adds a resource to the ledger of the config carried by ctx. It should be called as soon as the
+// resource has been created, before waiting for it to become ready.
+func Record(ctx context.Context, r Resource) {
+	if r.CreatedAt.IsZero() {
+		r.CreatedAt = time.Now()
+	}
+	if r.Run == "" {
+		r.Run = RunID(ctx)
+	}
+	err := update(Path(ctx), func(resources []Resource) []Resource {
+		return append(remove(resources, r), r)
+	})
+	if err != nil {
+		log.Printf("[ERROR] Resource %s was not recorded, so will not be deleted by 'probr cleanup': %v", r, err)
+	}
+}
+
+// Remove drops a resource from the ledger of the config carried by ctx, once it has been deleted
+func Remove(ctx context.Context, r Resource) {
+	err := update(Path(ctx), func(resources []Resource) []Resource {
+		return remove(resources, r)
+	})
+	if err != nil {
+		log.Printf("[ERROR] Deleted resource %s could not be removed from the ledger: %v", r, err)
+	}
+}
+
+// Load returns the resources listed in the ledger of the config carried by ctx, oldest first
+func Load(ctx context.Context) ([]Resource, error) {
+	lock.Lock()
+	defer lock.Unlock()
+	return read(Path(ctx))
+}
+
+// Cleanup deletes every resource listed in the ledger of the config carried by ctx, and returns those that were
+// deleted. Resources that could not be deleted remain in the ledger, and are listed in the returned error.
+func Cleanup(ctx context.Context) (deleted []Resource, err error) {
+	return cleanup(ctx, func(Resource) bool { return true })
+}
+
+// CleanupRun deletes the resources listed in the ledger of the config carried by ctx that were created by the
+// named run, see Cleanup. Resources created by other runs that share the write directory are left in place.
+func CleanupRun(ctx context.Context, run string) (deleted []Resource, err error) {
+	if run == "" {
+		return nil, nil
+	}
+	return cleanup(ctx, func(r Resource) bool { return r.Run == run })
+}
+
+func cleanup(ctx context.Context, include func(Resource) bool) (deleted []Resource, err error) {
+	all, err := Load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var resources []Resource
+	for _, r := range all {
+		if include(r) {
+			resources = append(resources, r)
+		}
+	}
+	var failures []string
+	for _, r := range resources {
+		lock.Lock()
+		deleter := deleters[r.Kind]
+		lock.Unlock()
+		if deleter == nil {
+			failures = append(failures, fmt.Sprintf("%s: no service pack deletes resources of kind '%s'", r, r.Kind))
+			continue
+		}
+		log.Printf("[INFO] Deleting %s", r)
+		if err := deleter(ctx, r); err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", r, err))
+			continue
+		}
+		Remove(ctx, r)
+		deleted = append(deleted, r)
+	}
+	if len(failures) > 0 {
+		return deleted, utils.ReformatError("%d of %d resources could not be deleted:\n  %s",
+			len(failures), len(resources), strings.Join(failures, "\n  "))
+	}
+	return deleted, nil
+}
+
+// update applies the change to the ledger at path. The ledger is removed once it is empty.
+func update(path string, change func([]Resource) []Resource) error {
+	lock.Lock()
+	defer lock.Unlock()
+
+	resources, err := read(path)
+	if err != nil {
+		return err
+	}
+	resources = change(resources)
+	if len(resources) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	sort.SliceStable(resources, func(i, j int) bool {
+		return resources[i].CreatedAt.Before(resources[j].CreatedAt)
+	})
+	data, err := json.MarshalIndent(resources, "", "  ")
+	if err != nil {
+		return err
+	}
+	// The ledger is replaced in one step, so that it is not left half written if probr is killed
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func read(path string) ([]Resource, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return []Resource{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var resources []Resource
+	if err := json.Unmarshal(data, &resources); err != nil {
+		return nil, utils.ReformatError("Ledger '%s' could not be read: %v", path, err)
+	}
+	return resources, nil
+}
+
+// remove returns the resources other than r
+func remove(resources []Resource, r Resource) []Resource {
+	kept := []Resource{}
+	for _, resource := range resources {
+		if !resource.same(r) {
+			kept = append(kept, resource)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,99 @@
+package ledger
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/citihub/probr/config"
+)
+
+func testContext(t *testing.T) (context.Context, func()) {
+	dir, err := ioutil.TempDir("", "probr-ledger-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	vars := &config.VarOptions{WriteDirectory: dir}
+	return config.WithVars(context.Background(), vars), func() { os.RemoveAll(dir) }
+}
+
+func TestRecordAndRemove(t *testing.T) {
+	ctx, cleanup := testContext(t)
+	defer cleanup()
+
+	pod := Resource{Kind: "test/pod", Group: "ns", Name: "pod-1", Location: map[string]string{"kube_context": "dev"}}
+	otherCluster := Resource{Kind: "test/pod", Group: "ns", Name: "pod-1", Location: map[string]string{"kube_context": "prod"}}
+	Record(ctx, pod)
+	Record(ctx, otherCluster)
+	Record(ctx, pod) // Recording the same resource again should not list it twice
+
+	resources, err := Load(ctx)
+	if err != nil {
+		t.Fatalf("Load() returned unexpected error: %v", err)
+	}
+	if len(resources) != 2 {
+		t.Fatalf("Expected 2 resources in the ledger, got %v", resources)
+	}
+
+	Remove(ctx, pod)
+	resources, _ = Load(ctx)
+	if len(resources) != 1 || !resources[0].same(otherCluster) {
+		t.Errorf("Expected only the pod of the other cluster to remain, got %v", resources)
+	}
+
+	Remove(ctx, otherCluster)
+	if _, err := os.Stat(Path(ctx)); !os.IsNotExist(err) {
+		t.Errorf("The ledger should be removed once it is empty")
+	}
+}
+
+func TestCleanup(t *testing.T) {
+	ctx, cleanup := testContext(t)
+	defer cleanup()
+
+	RegisterDeleter("test/deletable", func(ctx context.Context, r Resource) error { return nil })
+	RegisterDeleter("test/stuck", func(ctx context.Context, r Resource) error { return fmt.Errorf("still in use") })
+	Record(ctx, Resource{Kind: "test/deletable", Name: "a"})
+	Record(ctx, Resource{Kind: "test/stuck", Name: "b"})
+	Record(ctx, Resource{Kind: "test/unknown", Name: "c"})
+
+	deleted, err := Cleanup(ctx)
+	if len(deleted) != 1 || deleted[0].Name != "a" {
+		t.Errorf("Expected only resource 'a' to be deleted, got %v", deleted)
+	}
+	if err == nil {
+		t.Errorf("Expected an error for the resources that could not be deleted")
+	}
+
+	resources, _ := Load(ctx)
+	if len(resources) != 2 {
+		t.Errorf("Resources that could not be deleted should remain in the ledger, got %v", resources)
+	}
+}
+
+func TestCleanupRun(t *testing.T) {
+	ctx, cleanup := testContext(t)
+	defer cleanup()
+
+	RegisterDeleter("test/deletable", func(ctx context.Context, r Resource) error { return nil })
+	aborted := WithRun(ctx, NewRunID())
+	other := WithRun(ctx, NewRunID())
+	Record(aborted, Resource{Kind: "test/deletable", Name: "a"})
+	Record(other, Resource{Kind: "test/deletable", Name: "b"})
+	Record(ctx, Resource{Kind: "test/deletable", Name: "c"})
+
+	deleted, err := CleanupRun(ctx, RunID(aborted))
+	if err != nil || len(deleted) != 1 || deleted[0].Name != "a" {
+		t.Errorf("CleanupRun() = %v, %v; expected only the resource of the aborted run to be deleted", deleted, err)
+	}
+	if deleted, _ := CleanupRun(ctx, ""); len(deleted) != 0 {
+		t.Errorf("CleanupRun() without a run deleted %v", deleted)
+	}
+
+	resources, _ := Load(ctx)
+	if len(resources) != 2 || resources[0].Run != RunID(other) || resources[1].Run != "" {
+		t.Errorf("Resources of other runs should remain in the ledger, got %+v", resources)
+	}
+}
@@ -1,28 +1,71 @@
 package probr
 
 import (
+	"context"
 	"log"
 	"os"
+	"path/filepath"
 
+	"github.com/citihub/probr/audit"
 	"github.com/citihub/probr/config"
+	"github.com/citihub/probr/ledger"
 	servicepacks "github.com/citihub/probr/service_packs"
 	"github.com/citihub/probr/service_packs/coreengine"
 )
 
-var tmpDirFunc = config.Vars.TmpDir // TODO: revise this
+// Version is the version of probr, which is set when building a release, e.g.
+// go build -ldflags "-X github.com/citihub/probr.Version=v1.0.0" ./cmd
+var Version = "development"
 
-// RunAllProbes retrieves and executes all probes that have been included
-func RunAllProbes() (int, *coreengine.ProbeStore, error) {
-	ts := coreengine.NewProbeStore()
+// RunAllProbes retrieves and executes all probes that have been included and selected, see coreengine.SelectProbes.
+// Probes that are still running when config.Vars.RunTimeout expires, or when ctx is cancelled, are recorded as timed out.
+// It returns once the scenarios that were in flight have finished.
+func RunAllProbes(ctx context.Context, selections ...string) (int, *coreengine.ProbeStore, error) {
+	var cancel context.CancelFunc
+	if timeout := config.Vars.GetRunTimeout(); timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+
+	ts := coreengine.NewProbeStore(&config.Vars, audit.State)
 
-	for _, probe := range servicepacks.GetAllProbes() {
+	servicepacks.LoadPlugins(&config.Vars)
+	defer servicepacks.ClosePlugins()
+
+	if err := coreengine.SelectProbes(&config.Vars, selections); err != nil {
+		return coreengine.ExitInternalError, ts, err
+	}
+	for _, probe := range servicepacks.GetAllProbes(&config.Vars, config.Vars.TmpDir()) {
 		ts.AddProbe(probe)
 	}
 
-	s, err := ts.ExecAllProbes() // Executes all added (queued) tests
+	s, err := ts.ExecAllProbes(ctx) // Executes all added (queued) tests
 	return s, ts, err
 }
 
+// PlanAllProbes reports which scenarios would be executed by RunAllProbes with the current config and
+// selections, without running them. Nothing is written to the write directory.
+func PlanAllProbes(selections ...string) (*coreengine.Plan, error) {
+	servicepacks.LoadPlugins(&config.Vars)
+	defer servicepacks.ClosePlugins()
+
+	if err := coreengine.SelectProbes(&config.Vars, selections); err != nil {
+		return nil, err
+	}
+	return coreengine.PlanServicePacks(&config.Vars), nil
+}
+
+// ListAllProbes returns every registered service pack, probe and scenario, regardless of the current config.
+// Nothing is written to the write directory.
+func ListAllProbes() *coreengine.Catalog {
+	servicepacks.LoadPlugins(&config.Vars)
+	defer servicepacks.ClosePlugins()
+
+	return coreengine.ListServicePacks()
+}
+
 //GetAllProbeResults maps ProbeStore results to strings
 func GetAllProbeResults(ps *coreengine.ProbeStore) map[string]string {
 	defer CleanupTmp()
@@ -49,10 +92,22 @@ func readProbeResults(ps *coreengine.ProbeStore, name string) (probeResults, pro
 	return
 }
 
+// CleanupResources deletes the resources that probes created but did not delete, such as the pods of an aborted
+// run, as listed in the ledger in config.Vars.WriteDirectory. Any that could not be deleted remain in the ledger.
+func CleanupResources(ctx context.Context) ([]ledger.Resource, error) {
+	return ledger.Cleanup(config.WithVars(ctx, &config.Vars))
+}
+
+// CleanupRun deletes the resources that were created by the run with the given ID, see ledger.WithRun, but were
+// not deleted by its probes. Resources created by other runs that share the write directory are left in place.
+func CleanupRun(ctx context.Context, runID string) ([]ledger.Resource, error) {
+	return ledger.CleanupRun(config.WithVars(ctx, &config.Vars), runID)
+}
+
 // CleanupTmp is used to dispose of any temp resources used during execution
 func CleanupTmp() {
-	// Remove tmp folder and its content
-	err := os.RemoveAll(tmpDirFunc())
+	// Remove tmp folder and its content. TmpDir is not used, as it would create the folder if it did not exist.
+	err := os.RemoveAll(filepath.Join(config.Vars.WriteDirectory, "tmp"))
 	if err != nil {
 		log.Printf("[ERROR] Error removing tmp folder %v", err)
 	}
@@ -7,23 +7,24 @@ import (
 	"reflect"
 	"testing"
 
+	"github.com/citihub/probr/audit"
 	"github.com/citihub/probr/config"
 	"github.com/citihub/probr/service_packs/coreengine"
 	"github.com/citihub/probr/utils"
 )
 
 func TestGetAllProbeResults(t *testing.T) {
-	testTmpDir := filepath.Join("testdata", utils.RandomString(10))
+	testWriteDir := filepath.Join("testdata", utils.RandomString(10))
+	testTmpDir := filepath.Join(testWriteDir, "tmp")
 
-	// Faking original behavior of config.Vars.tmpDir()
-	tmpDirFunc = func() string {
-		return testTmpDir
-	}
+	// Faking the write directory, which holds config.Vars.TmpDir()
+	writeDirectory := config.Vars.WriteDirectory
+	config.Vars.WriteDirectory = testWriteDir
 	defer func() {
-		tmpDirFunc = config.Vars.TmpDir //Restoring to original function after test
+		config.Vars.WriteDirectory = writeDirectory //Restoring to original value after test
 
 		// Delete test data after tests
-		os.RemoveAll(testTmpDir)
+		os.RemoveAll(testWriteDir)
 	}()
 
 	type args struct {
@@ -37,7 +38,7 @@ func TestGetAllProbeResults(t *testing.T) {
 	}{
 		{
 			testName:       "ShouldCleanupTmpDir",
-			testArgs:       args{coreengine.NewProbeStore()},
+			testArgs:       args{coreengine.NewProbeStore(&config.Vars, audit.State)},
 			expectedResult: map[string]string{},
 		},
 	}
@@ -0,0 +1,126 @@
+package probr
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/citihub/probr/audit"
+	"github.com/citihub/probr/config"
+	"github.com/citihub/probr/ledger"
+	servicepacks "github.com/citihub/probr/service_packs"
+	"github.com/citihub/probr/service_packs/coreengine"
+	"github.com/citihub/probr/utils"
+)
+
+// Options describe the config of a Runner. As with the CLI, the config is built from the defaults, the vars
+// file and its profile, and env vars, in that order, before Set is applied.
+type Options struct {
+	VarsFile   string            // Path of the vars file, if any
+	Profile    string            // Profile of the vars file to apply. If empty, PROBR_PROFILE is used
+	Set        map[string]string // Config vars to override, by key, e.g. "ServicePacks.Kubernetes.KubeContext"
+	Selections []string          // Packs, probes or scenarios to run, as accepted by 'probr run'. If empty, all are run
+}
+
+// Runner runs probes with its own config and audit summary, rather than config.Vars and audit.State, so
+// that runners with different configs may be used by the same process, one after the other or at the same time.
+// Service packs and plugins are registered once per process, so they are shared by every runner.
+type Runner struct {
+	lock       sync.Mutex // a runner's config holds the state of its run, so its runs take place one at a time
+	vars       config.VarOptions
+	selections []string
+}
+
+// Results are the outcome of a run, which may be serialised to JSON
+type Results struct {
+	ExitCode  int                       `json:"exit_code"` // The most severe exit code of any probe, e.g. coreengine.ExitControlFailure
+	Status    string                    `json:"status"`    // e.g. "Complete - 1 of 4 Probes Failed"
+	StartedAt time.Time                 `json:"started_at"`
+	Duration  config.Duration           `json:"duration"`
+	Probes    []*coreengine.ProbeResult `json:"probes"` // Every probe that was run or excluded, ordered by pack and name
+	Summary   *audit.Summary            `json:"-"`      // The audits of the run, which are also written to the write directory if AuditEnabled
+}
+
+// NewRunner builds the config described by the options, returning an error if it is not valid
+func NewRunner(opts Options) (*Runner, error) {
+	vars, err := config.NewProfileConfig(opts.VarsFile, opts.Profile)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(opts.Set))
+	for key := range opts.Set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys) // Report the same error for the same options
+	for _, key := range keys {
+		if err := vars.Set(key, opts.Set[key], "Options.Set"); err != nil {
+			return nil, err
+		}
+	}
+	return &Runner{
+		vars:       vars,
+		selections: append([]string{}, opts.Selections...),
+	}, nil
+}
+
+// Config returns the config used by the runner. It should not be modified while the runner is running.
+func (r *Runner) Config() *config.VarOptions {
+	return &r.vars
+}
+
+// Run executes the selected probes, which are cancelled when ctx is done or RunTimeout expires and are then
+// recorded as timed out. Secrets in the config are redacted from the process' logs for the duration of the run.
+func (r *Runner) Run(ctx context.Context) (*Results, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	vars := &r.vars
+	if timeout := vars.GetRunTimeout(); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	release := config.RedactLogs(vars)
+	defer release()
+	if ledger.RunID(ctx) == "" {
+		ctx = ledger.WithRun(ctx, ledger.NewRunID()) // The caller may set its own, to clean up after an aborted run
+	}
+
+	servicepacks.LoadPlugins(vars)
+	defer servicepacks.ClosePlugins()
+
+	if err := coreengine.SelectProbes(vars, r.selections); err != nil {
+		return nil, err
+	}
+
+	// Each run unpacks the feature files into its own dir, so that runs sharing a write directory do not collide
+	tmpDir, err := ioutil.TempDir(vars.TmpDir(), "run-")
+	if err != nil {
+		return nil, utils.ReformatError("Could not create tmp directory for the run: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	startedAt := time.Now()
+	summary := audit.NewSummary(vars)
+	ps := coreengine.NewProbeStore(vars, summary)
+	for _, probe := range servicepacks.GetAllProbes(vars, tmpDir) {
+		ps.AddProbe(probe)
+	}
+	exitCode, err := ps.ExecAllProbes(ctx)
+
+	summary.SetProbrStatus()
+	summary.SetWaivers()
+	summary.SetProfile()
+	summary.WriteSummary()
+	return &Results{
+		ExitCode:  exitCode,
+		Status:    summary.Status,
+		StartedAt: startedAt,
+		Duration:  config.Duration(time.Since(startedAt)),
+		Probes:    ps.Results(),
+		Summary:   summary,
+	}, err
+}
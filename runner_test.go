@@ -0,0 +1,296 @@
+package probr
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/cucumber/godog"
+
+	"github.com/citihub/probr/audit"
+	"github.com/citihub/probr/config"
+	"github.com/citihub/probr/service_packs/coreengine"
+	"github.com/citihub/probr/service_packs/plugin"
+	"github.com/citihub/probr/utils"
+)
+
+const runnerFeature = `Feature: Runner probe
+
+  Scenario: The kube context is checked
+    Then the kube context is "pass"
+`
+
+// runnerFeaturePath is the path of runnerFeature, which is written by each test that runs runnerProbe
+var runnerFeaturePath string
+
+// runnerProbe passes if the KubeContext of the config that it was run with is "pass", and fails otherwise
+type runnerProbe struct{}
+
+func (p runnerProbe) ProbeInitialize(ctx *godog.TestSuiteContext) {}
+func (p runnerProbe) Name() string                                { return "runner_probe" }
+func (p runnerProbe) Path() string                                { return runnerFeaturePath }
+
+func (p runnerProbe) ScenarioInitialize(ctx context.Context, sc *godog.ScenarioContext) {
+	var scenario *audit.ScenarioAudit
+	sc.BeforeScenario(func(s *godog.Scenario) {
+		scenario = audit.ProbeLog(ctx, p.Name()).InitializeAuditor(s.Name, s.Tags)
+	})
+	sc.Step(`^the kube context is "([^"]*)"$`, func(expected string) (err error) {
+		defer func() {
+			scenario.AuditScenarioStep("the kube context is checked", "", nil, err)
+		}()
+		vars := config.FromContext(ctx)
+		log.Printf("[DEBUG] Checking kube context with client secret '%s'", vars.CloudProviders.Azure.ClientSecret) // See TestRunner_Run_RedactsLogs
+		if actual := vars.ServicePacks.Kubernetes.KubeContext; actual != expected {
+			err = utils.ReformatError("Expected kube context '%s', but found '%s'", expected, actual)
+		}
+		return err
+	})
+}
+
+// runnerPluginEnv causes the test binary to act as a plugin, see TestMain
+const runnerPluginEnv = "PROBR_RUNNER_PLUGIN"
+
+// runnerPlugin provides a single probe whose steps all pass, in the pack named by runnerPluginEnv
+type runnerPlugin struct{}
+
+func (p runnerPlugin) Manifest() plugin.Manifest {
+	return plugin.Manifest{
+		Name:   os.Getenv(runnerPluginEnv),
+		Probes: []plugin.ProbeManifest{{Name: "plugin_probe", Feature: "Feature: Plugin probe\n\n  Scenario: The plugin is run\n    Then the step passes\n"}},
+	}
+}
+
+func (p runnerPlugin) BeforeScenario(params plugin.ScenarioParams) error { return nil }
+func (p runnerPlugin) AfterScenario(params plugin.ScenarioParams) error  { return nil }
+
+func (p runnerPlugin) RunStep(params plugin.StepParams) plugin.StepResult {
+	return plugin.StepResult{Result: plugin.StepPassed}
+}
+
+func TestMain(m *testing.M) {
+	if os.Getenv(runnerPluginEnv) != "" {
+		if err := plugin.Serve(runnerPlugin{}); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func init() {
+	coreengine.RegisterServicePack(coreengine.ServicePack{
+		Name:   "runner_pack",
+		Probes: map[string][]coreengine.Probe{"": {runnerProbe{}}},
+	})
+}
+
+func TestRunner_Run(t *testing.T) {
+	dir, err := ioutil.TempDir("", "probr-runner-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	runnerFeaturePath = filepath.Join(dir, "runner_probe.feature")
+	if err := ioutil.WriteFile(runnerFeaturePath, []byte(runnerFeature), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		testName         string
+		kubeContext      string
+		expectedExitCode int
+		expectedResult   string
+		expectedStatus   string
+	}{
+		{"PassingRun", "pass", coreengine.ExitSuccess, "Success", "Passed"},
+		{"FailingRun", "fail", coreengine.ExitControlFailure, "Failed", "Failed"},
+	}
+
+	// The runners are run at the same time, each with its own config and write directory
+	runners := make([]*Runner, len(tests))
+	for i, tt := range tests {
+		runners[i], err = NewRunner(Options{
+			Set: map[string]string{
+				"OutputType":                          "INMEM",
+				"WriteDirectory":                      filepath.Join(dir, tt.testName),
+				"ServicePacks.Kubernetes.KubeContext": tt.kubeContext,
+			},
+			Selections: []string{"runner_pack"},
+		})
+		if err != nil {
+			t.Fatalf("Runner could not be created: %v", err)
+		}
+	}
+	results := make([]*Results, len(tests))
+	errs := make([]error, len(tests))
+	var wg sync.WaitGroup
+	for i := range runners {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], errs[i] = runners[i].Run(context.Background())
+		}(i)
+	}
+	wg.Wait()
+
+	for i, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			if errs[i] != nil {
+				t.Fatalf("Unexpected error: %v", errs[i])
+			}
+			if results[i].ExitCode != tt.expectedExitCode {
+				t.Errorf("ExitCode = %v, Expected: %v", results[i].ExitCode, tt.expectedExitCode)
+			}
+			if len(results[i].Probes) != 1 {
+				t.Fatalf("Expected a single probe result, got %v", results[i].Probes)
+			}
+			p := results[i].Probes[0]
+			if p.Name != "runner_probe" || p.Pack.Name != "runner_pack" || p.Result != tt.expectedResult || len(p.Scenarios) != 1 {
+				t.Fatalf("Unexpected probe result: %+v", p)
+			}
+			scenario := p.Scenarios[0]
+			if scenario.Name != "The kube context is checked" || scenario.Status != tt.expectedStatus || scenario.AuditScenario != 1 || len(scenario.Steps) != 1 {
+				t.Fatalf("Unexpected scenario result: %+v", scenario)
+			}
+			step := scenario.Steps[0]
+			if step.Name != `the kube context is "pass"` || step.Status != tt.expectedStatus || step.AuditStep != 1 {
+				t.Errorf("Unexpected step result: %+v", step)
+			}
+			if (step.Error != "") != (tt.expectedStatus == "Failed") {
+				t.Errorf("Unexpected step error: '%s'", step.Error)
+			}
+
+			// The results should survive a round trip through JSON
+			b, err := json.Marshal(results[i])
+			if err != nil {
+				t.Fatalf("Results could not be marshalled: %v", err)
+			}
+			var decoded Results
+			if err := json.Unmarshal(b, &decoded); err != nil {
+				t.Fatalf("Results could not be unmarshalled: %v", err)
+			}
+			if again, _ := json.Marshal(decoded); string(again) != string(b) {
+				t.Errorf("Results changed in JSON:\n%s\n%s", b, again)
+			}
+			summary := filepath.Join(runners[i].Config().WriteDirectory, "summary.json")
+			if _, err := os.Stat(summary); err != nil {
+				t.Errorf("Summary was not written to the runner's write directory: %v", err)
+			}
+		})
+	}
+	if audit.State.Probes["runner_pack/runner_probe"] != nil {
+		t.Errorf("Runners should not write to audit.State")
+	}
+}
+
+func TestRunner_Run_Plugins(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugin wrapper script requires a POSIX shell")
+	}
+	dir, err := ioutil.TempDir("", "probr-runner-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Each runner loads its own plugin, so that its pack is registered while the other runner is running
+	const runs = 5
+	var wg sync.WaitGroup
+	errs := make(chan error, 2*runs)
+	for i := 0; i < 2; i++ {
+		packName := fmt.Sprintf("runner_plugin_%v", i)
+		pluginDir := filepath.Join(dir, packName)
+		if err := os.Mkdir(pluginDir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		script := fmt.Sprintf("#!/bin/sh\n%s=%s exec '%s'\n", runnerPluginEnv, packName, os.Args[0])
+		if err := ioutil.WriteFile(filepath.Join(pluginDir, "runner-plugin"), []byte(script), 0755); err != nil {
+			t.Fatal(err)
+		}
+		r, err := NewRunner(Options{
+			Set: map[string]string{
+				"OutputType":      "INMEM",
+				"WriteDirectory":  filepath.Join(dir, packName+"-output"),
+				"PluginDirectory": pluginDir,
+			},
+			Selections: []string{packName},
+		})
+		if err != nil {
+			t.Fatalf("Runner could not be created: %v", err)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < runs; j++ {
+				results, err := r.Run(context.Background())
+				if err == nil && (results.ExitCode != coreengine.ExitSuccess || len(results.Probes) != 1) {
+					err = fmt.Errorf("unexpected results: exit code %v, probes %+v", results.ExitCode, results.Probes)
+				}
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("Run() = %v", err)
+		}
+	}
+}
+
+func TestRunner_Run_RedactsLogs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "probr-runner-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	runnerFeaturePath = filepath.Join(dir, "runner_probe.feature")
+	if err := ioutil.WriteFile(runnerFeaturePath, []byte(runnerFeature), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The logs are not filtered by SetLogFilter, as is the case for a library caller
+	var logs bytes.Buffer
+	output := log.Writer()
+	log.SetOutput(&logs)
+	defer log.SetOutput(output)
+
+	r, err := NewRunner(Options{
+		Set: map[string]string{
+			"OutputType":                        "INMEM",
+			"WriteDirectory":                    dir,
+			"CloudProviders.Azure.ClientSecret": "runner-secret",
+		},
+		Selections: []string{"runner_pack"},
+	})
+	if err != nil {
+		t.Fatalf("Runner could not be created: %v", err)
+	}
+	if _, err := r.Run(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if strings.Contains(logs.String(), "runner-secret") {
+		t.Errorf("Client secret was not redacted from the logs:\n%s", logs.String())
+	}
+	if !strings.Contains(logs.String(), "client secret '"+config.RedactedText+"'") {
+		t.Errorf("Expected the redacted client secret to be logged:\n%s", logs.String())
+	}
+}
+
+func TestNewRunner_InvalidOption(t *testing.T) {
+	if _, err := NewRunner(Options{Set: map[string]string{"NotAVar": "value"}}); err == nil {
+		t.Errorf("Expected an error for an unknown config var")
+	}
+}
@@ -8,15 +8,16 @@ import (
 )
 
 func TestGetProbes(t *testing.T) {
+	vars := config.VarOptions{}
 	pack := make([]coreengine.Probe, 0)
-	pack = GetProbes()
+	pack = GetProbes(&vars)
 	if len(pack) > 0 {
 		t.Logf("Unexpected value returned from GetProbes")
 		t.Fail()
 	}
 
-	config.Vars.ServicePacks.APIM.Provider = "Azure"
-	pack = GetProbes()
+	vars.ServicePacks.APIM.Provider = "Azure"
+	pack = GetProbes(&vars)
 	if len(pack) == 0 {
 		t.Logf("Expected value not returned from GetProbes")
 		t.Fail()
@@ -2,7 +2,6 @@ package azurees
 
 import (
 	"context"
-	"fmt"
 	"strings"
 
 	"github.com/citihub/probr/audit"
@@ -26,10 +25,11 @@ type ProbeStruct struct {
 // Probe allows this probe to be added to the ProbeStore
 var Probe ProbeStruct
 
-func (s *scenarioState) beforeScenario(probeName string, gs *godog.Scenario) {
+func (s *scenarioState) beforeScenario(ctx context.Context, probeName string, gs *godog.Scenario) {
 	s.name = gs.Name
-	s.probe = audit.State.GetProbeLog(probeName)
-	s.audit = audit.State.GetProbeLog(probeName).InitializeAuditor(gs.Name, gs.Tags)
+	s.ctx = ctx
+	s.probe = audit.ProbeLog(ctx, probeName)
+	s.audit = s.probe.InitializeAuditor(gs.Name, gs.Tags)
 	coreengine.LogScenarioStart(gs)
 }
 
@@ -64,10 +64,10 @@ func (s *scenarioState) anAPIIsDeployedToAPIM() error {
 	defer func() {
 		s.audit.AuditScenarioStep(s.currentStep, stepTrace.String(), payload, err)
 	}()
-	err = fmt.Errorf("Not Implemented")
+	err = godog.ErrPending
 	stepTrace.WriteString("TODO: Pending implementation;")
 
-	return godog.ErrPending
+	return err
 
 }
 
@@ -81,10 +81,10 @@ func (s *scenarioState) eachEndpointHasMTLSEmabled() error {
 	defer func() {
 		s.audit.AuditScenarioStep(s.currentStep, stepTrace.String(), payload, err)
 	}()
-	err = fmt.Errorf("Not Implemented")
+	err = godog.ErrPending
 	stepTrace.WriteString("TODO: Pending implementation;")
 
-	return godog.ErrPending
+	return err
 
 }
 
@@ -98,33 +98,33 @@ func (s *scenarioState) allEndpointsAreRetrievedFromAPIM() error {
 	defer func() {
 		s.audit.AuditScenarioStep(s.currentStep, stepTrace.String(), payload, err)
 	}()
-	err = fmt.Errorf("Not Implemented")
+	err = godog.ErrPending
 	stepTrace.WriteString("TODO: Pending implementation;")
 
-	return godog.ErrPending
+	return err
 
 }
 
 // ScenarioInitialize initialises the scenario
-func (p ProbeStruct) ScenarioInitialize(ctx *godog.ScenarioContext) {
+func (p ProbeStruct) ScenarioInitialize(ctx context.Context, sc *godog.ScenarioContext) {
 
-	ctx.BeforeScenario(func(s *godog.Scenario) {
-		p.state.beforeScenario(p.Name(), s)
+	sc.BeforeScenario(func(s *godog.Scenario) {
+		p.state.beforeScenario(ctx, p.Name(), s)
 	})
 
-	ctx.Step(`^an API that is deployed to APIM$`, p.state.anAPIIsDeployedToAPIM)
-	ctx.Step(`^all endpoints are retrieved from APIM$`, p.state.allEndpointsAreRetrievedFromAPIM)
-	ctx.Step(`^each endpoint has mTLS enabled$`, p.state.eachEndpointHasMTLSEmabled)
+	sc.Step(`^an API that is deployed to APIM$`, p.state.anAPIIsDeployedToAPIM)
+	sc.Step(`^all endpoints are retrieved from APIM$`, p.state.allEndpointsAreRetrievedFromAPIM)
+	sc.Step(`^each endpoint has mTLS enabled$`, p.state.eachEndpointHasMTLSEmabled)
 
-	ctx.AfterScenario(func(s *godog.Scenario, err error) {
+	sc.AfterScenario(func(s *godog.Scenario, err error) {
 		coreengine.LogScenarioEnd(s)
 	})
 
-	ctx.BeforeStep(func(st *godog.Step) {
+	sc.BeforeStep(func(st *godog.Step) {
 		p.state.currentStep = st.Text
 	})
 
-	ctx.AfterStep(func(st *godog.Step, err error) {
+	sc.AfterStep(func(st *godog.Step, err error) {
 		p.state.currentStep = ""
 	})
 }
@@ -7,23 +7,30 @@ import (
 	"github.com/markbates/pkger"
 )
 
-// GetProbes returns a list of probe objects
-func GetProbes() []coreengine.Probe {
-	//	config.Vars.SetTags(tags)
-	if config.Vars.ServicePacks.APIM.IsExcluded() {
-		return nil
-	}
-	switch config.Vars.ServicePacks.APIM.Provider {
-	case "Azure":
-		return []coreengine.Probe{
+var pack = coreengine.ServicePack{
+	Name: "apim",
+	Config: config.PackConfig{
+		RequiredVars: []string{"Provider"},
+		Settings:     func(v *config.VarOptions) interface{} { return v.ServicePacks.APIM },
+		Probes:       func(v *config.VarOptions) []config.Probe { return v.ServicePacks.APIM.Probes },
+	},
+	Provider: func(v *config.VarOptions) string { return v.ServicePacks.APIM.Provider },
+	Probes: map[string][]coreengine.Probe{
+		"Azure": {
 			azurees.Probe,
-		}
-	default:
-		return nil
-	}
+		},
+	},
+	Tags: tags,
+}
+
+// GetProbes returns a list of probe objects that may be run using the given config
+func GetProbes(vars *config.VarOptions) []coreengine.Probe {
+	return pack.GetProbes(vars)
 }
 
 func init() {
+	coreengine.RegisterServicePack(pack)
+
 	// This line will ensure that all static files are bundled into pked.go file when using pkger cli tool
 	// See: https://github.com/markbates/pkger
 	pkger.Include("/service_packs/apim/azure/endpoint_security/endpoint_security.feature")
@@ -0,0 +1,9 @@
+package apim
+
+var (
+	tags = map[string][]string{
+		"@probes/apim/endpoint_security": {"@aapim-es"},
+		"@csp/azure":                     {"@aapim-es"},
+		"@control_type/detective":        {"@aapim-es"},
+	}
+)
@@ -0,0 +1,168 @@
+package coreengine
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/citihub/probr/config"
+)
+
+// Catalog lists every registered service pack along with its probes and scenarios, regardless of the current config
+type Catalog struct {
+	Packs []*PackListing `json:"packs" yaml:"packs"`
+}
+
+// PackListing describes a service pack and the config vars it requires
+type PackListing struct {
+	Name         string          `json:"name" yaml:"name"`
+	RequiredVars []string        `json:"required_vars,omitempty" yaml:"required_vars,omitempty"`
+	Probes       []*ProbeListing `json:"probes" yaml:"probes"`
+}
+
+// ProbeListing describes a probe for a single provider variant of its pack
+type ProbeListing struct {
+	Name      string             `json:"name" yaml:"name"`
+	Provider  string             `json:"provider,omitempty" yaml:"provider,omitempty"`
+	Feature   string             `json:"feature,omitempty" yaml:"feature,omitempty"`
+	Tags      []string           `json:"tags,omitempty" yaml:"tags,omitempty"` // Tags shared by all of the probe's scenarios
+	Error     string             `json:"error,omitempty" yaml:"error,omitempty"`
+	Scenarios []*ScenarioListing `json:"scenarios,omitempty" yaml:"scenarios,omitempty"`
+}
+
+// ScenarioListing describes a scenario, along with the security standards it refers to
+type ScenarioListing struct {
+	Name       string   `json:"name" yaml:"name"`
+	Tags       []string `json:"tags,omitempty" yaml:"tags,omitempty"` // Tags in addition to those of the probe
+	References []string `json:"references,omitempty" yaml:"references,omitempty"`
+	Examples   int      `json:"examples,omitempty" yaml:"examples,omitempty"` // Number of example rows, for scenario outlines
+}
+
+// ListServicePacks builds a Catalog of all registered service packs and all of their provider variants
+func ListServicePacks() *Catalog {
+	catalog := &Catalog{}
+	for _, pack := range GetServicePacks() {
+		listing := &PackListing{Name: pack.Name, RequiredVars: config.RequiredVars(pack.Name)}
+		var providers []string
+		for provider := range pack.Probes {
+			providers = append(providers, provider)
+		}
+		sort.Strings(providers)
+		for _, provider := range providers {
+			for _, probe := range pack.Probes[provider] {
+				listing.Probes = append(listing.Probes, listProbe(probe, strings.ToLower(provider)))
+			}
+		}
+		catalog.Packs = append(catalog.Packs, listing)
+	}
+	return catalog
+}
+
+func listProbe(probe Probe, provider string) *ProbeListing {
+	listing := &ProbeListing{Name: probe.Name(), Provider: provider}
+	feature, err := ReadProbeFeature(probe)
+	if err != nil {
+		listing.Error = err.Error()
+		return listing
+	}
+	listing.Feature = feature.Name
+	listing.Tags = feature.Tags
+
+	// Outlines are parsed as one scenario per example, which are listed together
+	scenarios := make(map[int]*ScenarioListing)
+	for _, s := range feature.Scenarios {
+		scenario, exists := scenarios[s.Line]
+		if !exists {
+			scenario = &ScenarioListing{Name: s.Name, References: standardReferences(s.Description)}
+			scenarios[s.Line] = scenario
+			listing.Scenarios = append(listing.Scenarios, scenario)
+		}
+		if s.Example != "" {
+			scenario.Examples++
+		}
+		for _, tag := range s.Tags {
+			if name := strings.TrimPrefix(tag, "@"); !hasTag(feature.Tags, name) && !hasTag(scenario.Tags, name) {
+				scenario.Tags = append(scenario.Tags, tag)
+			}
+		}
+	}
+	return listing
+}
+
+// standardReferences returns the items listed beneath "Security Standard References:" in a scenario description
+func standardReferences(description string) []string {
+	var references []string
+	inReferences := false
+	for _, line := range strings.Split(description, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(strings.ToLower(line), "security standard references"):
+			inReferences = true
+		case inReferences && strings.HasPrefix(line, "-"):
+			references = append(references, strings.TrimSpace(strings.TrimPrefix(line, "-")))
+		case inReferences && line != "":
+			inReferences = false
+		}
+	}
+	return references
+}
+
+// ScaffoldProbes returns the probes of each pack, keyed by pack name, for config.WriteScaffold. A probe
+// with more than one provider variant is listed once, and scenarios are identified by their first tag.
+func (catalog *Catalog) ScaffoldProbes() map[string][]config.ScaffoldProbe {
+	probes := make(map[string][]config.ScaffoldProbe)
+	for _, pack := range catalog.Packs {
+		name := strings.ToLower(pack.Name)
+		listed := make(map[string]bool)
+		for _, probe := range pack.Probes {
+			if listed[probe.Name] {
+				continue
+			}
+			listed[probe.Name] = true
+			scaffold := config.ScaffoldProbe{Name: probe.Name}
+			for _, scenario := range probe.Scenarios {
+				if len(scenario.Tags) > 0 {
+					scaffold.Scenarios = append(scaffold.Scenarios, config.ScaffoldScenario{Tag: strings.TrimPrefix(scenario.Tags[0], "@"), Name: scenario.Name})
+				}
+			}
+			probes[name] = append(probes[name], scaffold)
+		}
+	}
+	return probes
+}
+
+// WriteTable writes the catalog as a table of scenarios for each pack
+func (catalog *Catalog) WriteTable(w io.Writer) {
+	for i, pack := range catalog.Packs {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "Service pack: %s\n", pack.Name)
+		if len(pack.RequiredVars) > 0 {
+			fmt.Fprintf(w, "Required vars: %s\n", strings.Join(pack.RequiredVars, ", "))
+		}
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "PROVIDER\tPROBE\tSCENARIO TAGS\tSCENARIO\tREFERENCES")
+		for _, probe := range pack.Probes {
+			provider := probe.Provider
+			if provider == "" {
+				provider = "-"
+			}
+			if probe.Error != "" {
+				fmt.Fprintf(tw, "%s\t%s\t\t%s\t\n", provider, probe.Name, probe.Error)
+				continue
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t\n", provider, probe.Name, strings.Join(probe.Tags, " "), probe.Feature)
+			for _, scenario := range probe.Scenarios {
+				name := scenario.Name
+				if scenario.Examples > 0 {
+					name = fmt.Sprintf("%s (%v examples)", name, scenario.Examples)
+				}
+				fmt.Fprintf(tw, "\t\t%s\t%s\t%s\n", strings.Join(scenario.Tags, " "), name, strings.Join(scenario.References, ", "))
+			}
+		}
+		tw.Flush()
+	}
+}
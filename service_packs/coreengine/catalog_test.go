@@ -0,0 +1,76 @@
+package coreengine
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestListProbe(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "probr-list")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "fake_probe.feature")
+	ioutil.WriteFile(path, []byte(testFeature), 0644)
+	probe := featureProbe{fakeProbe: fakeProbe{name: "fake_probe"}, path: path}
+
+	listing := listProbe(probe, "azure")
+	if listing.Error != "" || listing.Feature != "Fake feature" || listing.Provider != "azure" {
+		t.Fatalf("listProbe() = %+v", listing)
+	}
+	if len(listing.Scenarios) != 2 {
+		t.Fatalf("listProbe() listed %v scenarios, want the outline's examples to be listed together as 2", len(listing.Scenarios))
+	}
+	outline := listing.Scenarios[1]
+	if outline.Examples != 2 || strings.Join(outline.Tags, " ") != "@fake-002" {
+		t.Errorf("Outline listed as %+v, want 2 examples and only its own tag", outline)
+	}
+	if refs := listing.Scenarios[0].References; len(refs) != 1 || refs[0] != "FAKE-1.0" {
+		t.Errorf("Scenario references = %v, want [FAKE-1.0]", refs)
+	}
+
+	missing := listProbe(featureProbe{fakeProbe: fakeProbe{name: "missing"}, path: filepath.Join(dir, "missing.feature")}, "")
+	if missing.Error == "" {
+		t.Errorf("Expected an error for a probe without a feature file")
+	}
+}
+
+func TestStandardReferences(t *testing.T) {
+	tests := []struct {
+		testName    string
+		description string
+		expected    []string
+	}{
+		{testName: "NoReferences", description: "Just a description", expected: nil},
+		{testName: "References", description: "Security Standard References:\n  - CIS 5.2.5\n  - https://example.com", expected: []string{"CIS 5.2.5", "https://example.com"}},
+		{testName: "FollowedByText", description: "Security Standard References:\n  - CIS 5.2.5\n\nOther notes\n  - not a reference", expected: []string{"CIS 5.2.5"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			refs := standardReferences(tt.description)
+			if strings.Join(refs, "|") != strings.Join(tt.expected, "|") {
+				t.Errorf("standardReferences() = %v, want %v", refs, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCatalog_ScaffoldProbes(t *testing.T) {
+	catalog := &Catalog{Packs: []*PackListing{{
+		Name: "Fake_Pack",
+		Probes: []*ProbeListing{
+			{Name: "fake_probe", Provider: "aws", Scenarios: []*ScenarioListing{{Name: "Tagged", Tags: []string{"@fake-001", "@fake-extra"}}, {Name: "Untagged"}}},
+			{Name: "fake_probe", Provider: "azure"},
+			{Name: "missing", Error: "feature file for probe 'missing' could not be found"},
+		},
+	}}}
+
+	probes := catalog.ScaffoldProbes()["fake_pack"]
+	if len(probes) != 2 || probes[0].Name != "fake_probe" || probes[1].Name != "missing" {
+		t.Fatalf("ScaffoldProbes() = %+v, want fake_probe listed once followed by missing", probes)
+	}
+	if scenarios := probes[0].Scenarios; len(scenarios) != 1 || scenarios[0].Tag != "fake-001" || scenarios[0].Name != "Tagged" {
+		t.Errorf("Scenarios = %+v, want only the tagged scenario, identified by its first tag", scenarios)
+	}
+}
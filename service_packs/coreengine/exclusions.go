@@ -0,0 +1,102 @@
+package coreengine
+
+import (
+	"log"
+	"strings"
+
+	"github.com/citihub/probr/config"
+)
+
+// applyExclusions excludes the probes and scenarios that the config excludes for the pack, using the
+// tags that their feature files declare. Exclusions that do not match a probe or scenario are logged as a
+// warning, as they would otherwise be silently ignored.
+func (pack ServicePack) applyExclusions(vars *config.VarOptions) {
+	if pack.Config.Probes == nil {
+		return
+	}
+	probes := make(map[string]Probe)
+	for _, probe := range pack.allProbes() {
+		probes[probe.Name()] = probe
+	}
+	for _, configured := range pack.Config.Probes(vars) {
+		excluded := configured.IsExcluded()
+		var scenarios []config.Scenario
+		if !excluded {
+			for _, scenario := range configured.Scenarios {
+				if scenario.IsExcluded() {
+					scenarios = append(scenarios, scenario)
+				}
+			}
+		}
+		if !excluded && len(scenarios) == 0 {
+			continue
+		}
+
+		probe, found := probes[configured.Name]
+		if !found {
+			log.Printf("[WARN] Excluded probe '%s' does not match any probe in the %s service pack. Must be one of %v", configured.Name, pack.Name, pack.probeNames())
+			continue
+		}
+		feature, err := ReadProbeFeature(probe)
+		if err != nil {
+			log.Printf("[WARN] Unable to apply the exclusions for the %s probe: %v", probe.Name(), err)
+			continue
+		}
+		if excluded {
+			excludeProbe(vars, feature, configured)
+			continue
+		}
+		for _, scenario := range scenarios {
+			excludeScenario(vars, feature, configured.Name, scenario)
+		}
+	}
+}
+
+func excludeProbe(vars *config.VarOptions, feature *Feature, probe config.Probe) {
+	tag := featureTag(feature)
+	if tag == "" {
+		log.Printf("[WARN] The %s probe could not be excluded, as its feature file declares no tags", probe.Name)
+		return
+	}
+	vars.ExcludeTag(tag, probe.Justification())
+}
+
+func excludeScenario(vars *config.VarOptions, feature *Feature, probe string, scenario config.Scenario) {
+	tag := strings.TrimPrefix(scenario.Name, "@")
+	for _, s := range feature.Scenarios {
+		if hasTag(s.Tags, tag) {
+			vars.ExcludeTag(tag, scenario.Justification())
+			return
+		}
+	}
+	log.Printf("[WARN] Excluded scenario '%s' does not match the tag of any scenario in the %s probe. Must be one of %v", scenario.Name, probe, scenarioTags(feature))
+}
+
+// featureTag returns a tag that the feature declares for all of its scenarios, preferring the
+// "@probes/<pack>/<probe>" form, or "" if the feature has no tags
+func featureTag(feature *Feature) string {
+	for _, tag := range feature.Tags {
+		if strings.HasPrefix(tag, "@probes/") {
+			return tag
+		}
+	}
+	if len(feature.Tags) > 0 {
+		return feature.Tags[0]
+	}
+	return ""
+}
+
+// scenarioTags returns the tags that the feature's scenarios declare in addition to those of the feature, without "@"
+func scenarioTags(feature *Feature) []string {
+	var tags []string
+	seen := make(map[string]bool)
+	for _, scenario := range feature.Scenarios {
+		for _, tag := range scenario.Tags {
+			if !hasTag(feature.Tags, strings.TrimPrefix(tag, "@")) && !seen[tag] {
+				seen[tag] = true
+				tags = append(tags, strings.TrimPrefix(tag, "@"))
+			}
+		}
+	}
+	return tags
+}
@@ -0,0 +1,95 @@
+package coreengine
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/citihub/probr/config"
+)
+
+const untaggedFeature = `Feature: Untagged feature
+
+    Scenario: Untagged scenario
+        Given a step
+`
+
+func TestServicePack_applyExclusions(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "probr-exclusions")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "fake_probe.feature")
+	ioutil.WriteFile(path, []byte(testFeature), 0644)
+	untagged := filepath.Join(dir, "untagged_probe.feature")
+	ioutil.WriteFile(untagged, []byte(untaggedFeature), 0644)
+
+	tests := []struct {
+		testName string
+		probes   []config.Probe
+		tags     string
+		warning  string
+	}{
+		{
+			testName: "ProbeExcludedByFeatureTag",
+			probes:   []config.Probe{{Name: "fake_probe", Excluded: "Not deployed"}},
+			tags:     "~@probes/fake_pack/fake_probe",
+		},
+		{
+			testName: "ScenarioExcludedByTag",
+			probes:   []config.Probe{{Name: "fake_probe", Scenarios: []config.Scenario{{Name: "fake-002", Excluded: "Not deployed"}, {Name: "@fake-001"}}}},
+			tags:     "~@fake-002",
+		},
+		{
+			testName: "ScenarioTagNotFound",
+			probes:   []config.Probe{{Name: "fake_probe", Scenarios: []config.Scenario{{Name: "1.0", Excluded: "Not deployed"}}}},
+			warning:  "Excluded scenario '1.0' does not match the tag of any scenario in the fake_probe probe. Must be one of [fake-001 fake-002]",
+		},
+		{
+			testName: "ProbeNotFound",
+			probes:   []config.Probe{{Name: "pod_security_policy", Excluded: "Not deployed"}},
+			warning:  "Excluded probe 'pod_security_policy' does not match any probe in the fake_pack service pack",
+		},
+		{
+			testName: "UntaggedFeature",
+			probes:   []config.Probe{{Name: "untagged_probe", Excluded: "Not deployed"}},
+			warning:  "The untagged_probe probe could not be excluded",
+		},
+		{
+			testName: "NotExcluded",
+			probes:   []config.Probe{{Name: "pod_security_policy"}, {Name: "fake_probe", Scenarios: []config.Scenario{{Name: "1.0"}}}},
+		},
+	}
+	defer log.SetOutput(os.Stderr)
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			vars, _ := config.NewConfig("")
+			var logs bytes.Buffer
+			log.SetOutput(&logs)
+
+			pack := ServicePack{
+				Name:   "fake_pack",
+				Config: config.PackConfig{Probes: func(*config.VarOptions) []config.Probe { return tt.probes }},
+				Probes: map[string][]Probe{"": {
+					featureProbe{fakeProbe: fakeProbe{name: "fake_probe"}, path: path},
+					featureProbe{fakeProbe: fakeProbe{name: "untagged_probe"}, path: untagged},
+				}},
+			}
+			pack.applyExclusions(&vars)
+			if vars.Tags != tt.tags {
+				t.Errorf("Tags = '%s', expected '%s'", vars.Tags, tt.tags)
+			}
+			if tt.tags != "" && vars.ExclusionJustification(tt.tags[1:]) != "Not deployed" {
+				t.Errorf("ExclusionJustification(%s) = '%s', expected the justification from the vars file", tt.tags[1:], vars.ExclusionJustification(tt.tags[1:]))
+			}
+			if tt.warning != "" && !strings.Contains(logs.String(), "[WARN] "+tt.warning) {
+				t.Errorf("Expected the warning '%s', got: %s", tt.warning, logs.String())
+			}
+			if tt.warning == "" && strings.Contains(logs.String(), "[WARN]") {
+				t.Errorf("Unexpected warning: %s", logs.String())
+			}
+		})
+	}
+}
@@ -2,9 +2,11 @@ package coreengine
 
 import (
 	"bytes"
+	"context"
 	"io"
 	"log"
 	"os"
+	"time"
 
 	"github.com/cucumber/godog"
 	"github.com/cucumber/godog/colors"
@@ -15,21 +17,28 @@ import (
 // GodogProbeHandler is a general implementation of ProbeHandlerFunc.  Based on the
 // output type, the test will either be executed using an in-memory or file output.  In
 // both cases, the handler uses the data supplied in GodogProbe to call the underlying
-// GoDog test suite.
-func GodogProbeHandler(probe *GodogProbe) (int, *bytes.Buffer, error) {
-	if config.Vars.OutputType == "INMEM" {
-		return inMemGodogProbeHandler(probe)
+// GoDog test suite. Each scenario is run with a context derived from ctx, whose config is used.
+func GodogProbeHandler(ctx context.Context, probe *GodogProbe) (int, *bytes.Buffer, error) {
+	if config.FromContext(ctx).OutputType == "INMEM" {
+		return inMemGodogProbeHandler(ctx, probe)
 	}
-	return toFileGodogProbeHandler(probe)
+	return toFileGodogProbeHandler(ctx, probe)
 }
 
-func toFileGodogProbeHandler(gd *GodogProbe) (int, *bytes.Buffer, error) {
-	o, err := getOutputPath(gd.ProbeDescriptor.Name)
+// abandonAfter is how long a suite is given to wind down once its context is done. Steps should
+// return promptly when their context is cancelled, so a suite that is still running after this is hung.
+var abandonAfter = 30 * time.Second
+
+func toFileGodogProbeHandler(ctx context.Context, gd *GodogProbe) (int, *bytes.Buffer, error) {
+	o, err := getOutputPath(config.FromContext(ctx), gd.ProbeDescriptor.OutputName())
 	if err != nil {
 		return -1, nil, err
 	}
 
-	status, err := runTestSuite(o, gd)
+	status, err := runTestSuite(ctx, o, gd)
+	if err != nil {
+		return status, nil, err // The abandoned suite may still be writing, so the file is left open
+	}
 
 	//FUDGE! If the tests are skipped due to tags, then an empty file may
 	//be left lingering.  This will have a non-zero size as we've actually
@@ -48,28 +57,72 @@ func toFileGodogProbeHandler(gd *GodogProbe) (int, *bytes.Buffer, error) {
 	return status, nil, err
 }
 
-func inMemGodogProbeHandler(gd *GodogProbe) (int, *bytes.Buffer, error) {
+func inMemGodogProbeHandler(ctx context.Context, gd *GodogProbe) (int, *bytes.Buffer, error) {
 	var t []byte
 	o := bytes.NewBuffer(t)
-	status, err := runTestSuite(o, gd)
+	status, err := runTestSuite(ctx, o, gd)
+	if err != nil {
+		return status, nil, err // The abandoned suite may still be writing to the buffer
+	}
 	return status, o, err
 }
 
-func runTestSuite(o io.Writer, gd *GodogProbe) (int, error) {
-	tags := config.Vars.GetTags()
+// runTestSuite runs the probe's feature and returns the godog status. If ctx is done and the suite
+// has not returned within abandonAfter, the suite is left running and ctx.Err() is returned.
+func runTestSuite(ctx context.Context, o io.Writer, gd *GodogProbe) (int, error) {
+	vars := config.FromContext(ctx)
+	tags := vars.ProbeTags(gd.ProbeDescriptor.Pack.Name, gd.ProbeDescriptor.Name)
 	opts := godog.Options{
-		Format: config.Vars.ResultsFormat,
+		Format: vars.ResultsFormat,
 		Output: colors.Colored(o),
 		Paths:  []string{gd.FeaturePath},
 		Tags:   tags,
+		Strict: vars.Strict,
 	}
 
-	status := godog.TestSuite{
+	suite := godog.TestSuite{
 		Name:                 gd.ProbeDescriptor.Name,
 		TestSuiteInitializer: gd.ProbeInitializer,
-		ScenarioInitializer:  gd.ScenarioInitializer,
+		ScenarioInitializer:  scenarioInitializer(ctx, gd),
 		Options:              &opts,
-	}.Run()
+	}
 
-	return status, nil
+	done := make(chan int, 1)
+	go func() {
+		done <- suite.Run()
+	}()
+
+	select {
+	case status := <-done:
+		return status, nil
+	case <-ctx.Done():
+	}
+	select {
+	case status := <-done:
+		return status, nil
+	case <-time.After(abandonAfter):
+		log.Printf("[ERROR] Probe '%s' did not stop within %v of being cancelled", gd.ProbeDescriptor.Name, abandonAfter)
+		return 1, ctx.Err()
+	}
+}
+
+// scenarioInitializer gives each scenario its own context, which is cancelled after ScenarioTimeout
+// or once the scenario has finished. godog initializes each scenario immediately before running it.
+// The outcome of the scenario and its steps is recorded around the probe's own hooks.
+func scenarioInitializer(ctx context.Context, gd *GodogProbe) func(*godog.ScenarioContext) {
+	return func(sc *godog.ScenarioContext) {
+		scenarioCtx, cancel := withTimeout(ctx, config.FromContext(ctx).GetScenarioTimeout())
+		var recorder *scenarioRecorder
+		sc.BeforeScenario(func(s *godog.Scenario) {
+			recorder = gd.recorder.newScenarioRecorder(ctx, gd)
+		})
+		gd.ScenarioInitializer(scenarioCtx, sc)
+		sc.BeforeScenario(func(s *godog.Scenario) { recorder.start(s) })
+		sc.BeforeStep(func(s *godog.Step) { recorder.beforeStep(s) })
+		sc.AfterStep(func(s *godog.Step, err error) { recorder.afterStep(s, err) })
+		sc.AfterScenario(func(s *godog.Scenario, err error) {
+			recorder.end(s, err)
+			cancel() // Registered last, so the probe's own AfterScenario hooks can still use the context
+		})
+	}
 }
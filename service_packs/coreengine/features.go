@@ -0,0 +1,163 @@
+package coreengine
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/cucumber/gherkin-go/v11"
+	"github.com/cucumber/messages-go/v10"
+)
+
+// Feature holds the parts of a feature file that describe what a probe will run, without running it
+type Feature struct {
+	Name        string            `json:"name"`
+	Description string            `json:"description,omitempty"`
+	Tags        []string          `json:"tags,omitempty"`
+	Scenarios   []FeatureScenario `json:"scenarios"`
+}
+
+// FeatureScenario is a single scenario as it will be run by Godog. A scenario outline has one
+// FeatureScenario for each row of its examples.
+type FeatureScenario struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"` // Usually holds the "Security Standard References"
+	Tags        []string `json:"tags"`                  // Includes the tags inherited from the feature and examples
+	Example     string   `json:"example,omitempty"`     // The outline example row, e.g. "Result=Fail, Whitelist Entry=nil"
+	Line        int      `json:"line"`                  // Line of the scenario, which is shared by each of an outline's examples
+}
+
+// ReadFeatureFile parses the feature file at the provided path
+func ReadFeatureFile(path string) (*Feature, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseFeature(f, path)
+}
+
+// ParseFeature parses the content of a feature file, using the same rules as Godog to
+// expand outlines and inherit tags. The uri is only used to describe errors.
+func ParseFeature(r io.Reader, uri string) (*Feature, error) {
+	ids := &messages.Incrementing{}
+	doc, err := gherkin.ParseGherkinDocument(r, ids.NewId)
+	if err != nil {
+		return nil, fmt.Errorf("%s - %v", uri, err)
+	}
+	if doc.Feature == nil {
+		return nil, fmt.Errorf("%s - no feature found", uri)
+	}
+
+	feature := &Feature{
+		Name:        doc.Feature.Name,
+		Description: strings.TrimSpace(doc.Feature.Description),
+	}
+	for _, tag := range doc.Feature.Tags {
+		feature.Tags = append(feature.Tags, tag.Name)
+	}
+
+	scenarios, examples := indexFeatureChildren(doc.Feature.Children)
+	for _, pickle := range gherkin.Pickles(*doc, uri, ids.NewId) {
+		if len(pickle.AstNodeIds) < 1 {
+			continue
+		}
+		s := FeatureScenario{Name: pickle.Name}
+		if scenario, ok := scenarios[pickle.AstNodeIds[0]]; ok {
+			s.Description = strings.TrimSpace(scenario.Description)
+			s.Line = int(scenario.Location.GetLine())
+		}
+		if len(pickle.AstNodeIds) > 1 {
+			if example, ok := examples[pickle.AstNodeIds[len(pickle.AstNodeIds)-1]]; ok {
+				s.Example = example.String()
+			}
+		}
+		for _, tag := range pickle.Tags {
+			s.Tags = append(s.Tags, tag.Name)
+		}
+		feature.Scenarios = append(feature.Scenarios, s)
+	}
+	return feature, nil
+}
+
+// exampleRow is a row of an outline's examples table, along with the header that names its cells
+type exampleRow struct {
+	header *messages.GherkinDocument_Feature_TableRow
+	row    *messages.GherkinDocument_Feature_TableRow
+}
+
+func (e exampleRow) String() string {
+	var cells []string
+	for i, cell := range e.row.Cells {
+		if e.header != nil && i < len(e.header.Cells) {
+			cells = append(cells, fmt.Sprintf("%s=%s", e.header.Cells[i].Value, cell.Value))
+		} else {
+			cells = append(cells, cell.Value)
+		}
+	}
+	return strings.Join(cells, ", ")
+}
+
+// indexFeatureChildren maps the AST ids of scenarios and example rows, which pickles refer to
+func indexFeatureChildren(children []*messages.GherkinDocument_Feature_FeatureChild) (map[string]*messages.GherkinDocument_Feature_Scenario, map[string]exampleRow) {
+	scenarios := make(map[string]*messages.GherkinDocument_Feature_Scenario)
+	examples := make(map[string]exampleRow)
+
+	add := func(scenario *messages.GherkinDocument_Feature_Scenario) {
+		if scenario == nil {
+			return
+		}
+		scenarios[scenario.Id] = scenario
+		for _, e := range scenario.Examples {
+			for _, row := range e.TableBody {
+				examples[row.Id] = exampleRow{header: e.TableHeader, row: row}
+			}
+		}
+	}
+	for _, child := range children {
+		add(child.GetScenario())
+		if rule := child.GetRule(); rule != nil {
+			for _, ruleChild := range rule.Children {
+				add(ruleChild.GetScenario())
+			}
+		}
+	}
+	return scenarios, examples
+}
+
+// matchTags reports whether a scenario with the provided tags would be run by Godog using the tag
+// filter, e.g. "@probes/kubernetes,@k-iam && ~@k-iam-001". It follows Godog's own evaluation,
+// where each "&&" clause must be met by at least one of its comma separated tags. If the scenario
+// does not match, the first clause that it did not meet is returned.
+func matchTags(filter string, tags []string) (bool, string) {
+	if strings.TrimSpace(filter) == "" {
+		return true, ""
+	}
+	for _, clause := range strings.Split(filter, "&&") {
+		met := false
+		for _, tag := range strings.Split(clause, ",") {
+			tag = strings.Replace(strings.TrimSpace(tag), "@", "", -1)
+			if tag == "" {
+				continue
+			}
+			met = hasTag(tags, tag) || met
+			if tag[0] == '~' {
+				met = !hasTag(tags, tag[1:]) || met
+			}
+		}
+		if !met {
+			return false, strings.TrimSpace(clause)
+		}
+	}
+	return true, ""
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if strings.Replace(t, "@", "", -1) == tag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,142 @@
+package coreengine
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/citihub/probr/config"
+)
+
+const testFeature = `@probes/fake_pack/fake_probe
+Feature: Fake feature
+
+    @fake-001
+    Scenario: Single scenario
+
+        Security Standard References:
+            - FAKE-1.0
+
+        Given a step
+
+    @fake-002
+    Scenario Outline: Outline scenario
+        Given a step with "<VALUE>"
+
+        Examples:
+            | VALUE | RESULT |
+            | one   | Fail   |
+            | two   | Pass   |
+`
+
+func TestParseFeature(t *testing.T) {
+	feature, err := ParseFeature(strings.NewReader(testFeature), "fake.feature")
+	if err != nil {
+		t.Fatalf("ParseFeature() returned an error: %v", err)
+	}
+	if feature.Name != "Fake feature" || len(feature.Scenarios) != 3 {
+		t.Fatalf("ParseFeature() = %v with %v scenarios, want 'Fake feature' with 3 scenarios", feature.Name, len(feature.Scenarios))
+	}
+
+	single := feature.Scenarios[0]
+	if !strings.Contains(single.Description, "FAKE-1.0") || single.Example != "" {
+		t.Errorf("Scenario description or example not parsed: %+v", single)
+	}
+	if strings.Join(single.Tags, " ") != "@probes/fake_pack/fake_probe @fake-001" {
+		t.Errorf("Scenario did not inherit the feature tags: %v", single.Tags)
+	}
+	if example := feature.Scenarios[2].Example; example != "VALUE=two, RESULT=Pass" {
+		t.Errorf("Outline example = '%s', want 'VALUE=two, RESULT=Pass'", example)
+	}
+
+	if _, err := ParseFeature(strings.NewReader("not gherkin"), "bad.feature"); err == nil {
+		t.Errorf("Expected an error for an invalid feature")
+	}
+}
+
+func TestMatchTags(t *testing.T) {
+	tags := []string{"@probes/kubernetes/iam", "@k-iam-001"}
+	tests := []struct {
+		testName       string
+		filter         string
+		expected       bool
+		expectedClause string
+	}{
+		{testName: "NoFilter", filter: "", expected: true},
+		{testName: "Included", filter: "@k-iam-001", expected: true},
+		{testName: "AnyOf", filter: "@k-pod,@probes/kubernetes/iam", expected: true},
+		{testName: "NotIncluded", filter: "@k-pod", expected: false, expectedClause: "@k-pod"},
+		{testName: "Excluded", filter: "~@k-pod && ~@probes/kubernetes/iam", expected: false, expectedClause: "~@probes/kubernetes/iam"},
+		{testName: "ExclusionOfOtherTag", filter: "~@k-iam-002", expected: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			matched, clause := matchTags(tt.filter, tags)
+			if matched != tt.expected || clause != tt.expectedClause {
+				t.Errorf("matchTags('%s') = %v, '%s', want %v, '%s'", tt.filter, matched, clause, tt.expected, tt.expectedClause)
+			}
+		})
+	}
+}
+
+type featureProbe struct {
+	fakeProbe
+	path string
+}
+
+func (p featureProbe) Path() string { return p.path }
+
+func TestPlan_planProbe(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "probr-plan")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "fake_probe.feature")
+	ioutil.WriteFile(path, []byte(testFeature), 0644)
+	probe := featureProbe{fakeProbe: fakeProbe{name: "fake_probe"}, path: path}
+
+	vars, _ := config.NewConfig("")
+	vars.Tags = "~@fake-002"
+	plan := &Plan{Tags: vars.GetTags()}
+	probePlan := plan.planProbe(&vars, "fake_pack", probe, true)
+	if !probePlan.Included || len(probePlan.Scenarios) != 3 {
+		t.Fatalf("planProbe() = %+v, want an included probe with 3 scenarios", probePlan)
+	}
+	if !probePlan.Scenarios[0].Included || probePlan.Scenarios[1].Included || probePlan.Scenarios[2].Included {
+		t.Errorf("Only the first scenario should be included")
+	}
+	if reason := probePlan.Scenarios[1].Reason; reason != "excluded by '~@fake-002'" {
+		t.Errorf("Scenario exclusion reason = '%s'", reason)
+	}
+	if plan.ScenariosIncluded != 1 || plan.ScenariosExcluded != 2 {
+		t.Errorf("Plan counted %v included and %v excluded scenarios, want 1 and 2", plan.ScenariosIncluded, plan.ScenariosExcluded)
+	}
+
+	probePlan = plan.planProbe(&vars, "fake_pack", probe, false)
+	if probePlan.Included || probePlan.Scenarios[0].Reason != "service pack is excluded" {
+		t.Errorf("Scenarios of an excluded pack should be excluded, got %+v", probePlan.Scenarios[0])
+	}
+
+	vars.Meta.Selections = []config.Selection{{Pack: "fake_pack", Probe: "other_probe"}}
+	probePlan = plan.planProbe(&vars, "fake_pack", probe, true)
+	if probePlan.Included || probePlan.Scenarios[0].Reason != "probe was not selected" {
+		t.Errorf("Scenarios of a probe that was not selected should be excluded, got %+v", probePlan.Scenarios[0])
+	}
+
+	vars.Meta.Selections = []config.Selection{{Pack: "fake_pack", Probe: "fake_probe", Tag: "fake-001"}}
+	probePlan = plan.planProbe(&vars, "fake_pack", probe, true)
+	if !probePlan.Scenarios[0].Included || probePlan.Scenarios[2].Included {
+		t.Errorf("Only the selected scenario should be included, got %+v", probePlan.Scenarios)
+	}
+}
+
+func TestTagExclusionReason(t *testing.T) {
+	vars, _ := config.NewConfig("")
+	vars.TagExclusions = []string{"k-iam-001"}
+	if reason := tagExclusionReason(&vars, "~@k-iam-001"); reason != "excluded by '~@k-iam-001': listed in TagExclusions" {
+		t.Errorf("tagExclusionReason() = '%s'", reason)
+	}
+	if reason := tagExclusionReason(&vars, "@k-pod,@k-gen"); reason != "does not match '@k-pod,@k-gen'" {
+		t.Errorf("tagExclusionReason() = '%s'", reason)
+	}
+}
@@ -0,0 +1,144 @@
+package coreengine
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/citihub/probr/config"
+)
+
+// Plan describes which scenarios would be run with the current config, and the reason for excluding any others
+type Plan struct {
+	Tags              string      `json:"tags"` // The combined tag filter passed to Godog
+	ScenariosIncluded int         `json:"scenarios_included"`
+	ScenariosExcluded int         `json:"scenarios_excluded"`
+	Packs             []*PackPlan `json:"packs"`
+}
+
+// PackPlan describes the planned probes of a single service pack
+type PackPlan struct {
+	Pack     PackIdentity `json:"pack"`
+	Included bool         `json:"included"`
+	Reason   string       `json:"reason,omitempty"`
+	Probes   []*ProbePlan `json:"probes,omitempty"`
+}
+
+// ProbePlan describes the planned scenarios of a single probe. A probe is included if any of its scenarios are.
+type ProbePlan struct {
+	Name      string          `json:"name"`
+	Included  bool            `json:"included"`
+	Error     string          `json:"error,omitempty"` // Set if the probe's feature file could not be read
+	Scenarios []*ScenarioPlan `json:"scenarios,omitempty"`
+}
+
+// ScenarioPlan describes whether a scenario, or a single example of a scenario outline, would be run
+type ScenarioPlan struct {
+	FeatureScenario
+	Included bool   `json:"included"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// PlanServicePacks evaluates the config and tags for every registered service pack against its
+// feature files, in the same way as a run would, but without running any probes.
+func PlanServicePacks(vars *config.VarOptions) *Plan {
+	packs := GetServicePacks()
+	for _, pack := range packs {
+		pack.GetProbes(vars) // Applies each pack's exclusions from the vars file, as is done before a run
+	}
+
+	plan := &Plan{Tags: vars.GetTags()}
+	for _, pack := range packs {
+		packPlan := &PackPlan{Pack: pack.Identity(vars), Reason: pack.ExclusionReason(vars)}
+		packPlan.Included = packPlan.Reason == ""
+		probes, _ := pack.providerProbes(vars)
+		for _, probe := range probes {
+			packPlan.Probes = append(packPlan.Probes, plan.planProbe(vars, pack.Name, probe, packPlan.Included))
+		}
+		plan.Packs = append(plan.Packs, packPlan)
+	}
+	return plan
+}
+
+func (plan *Plan) planProbe(vars *config.VarOptions, pack string, probe Probe, packIncluded bool) *ProbePlan {
+	probePlan := &ProbePlan{Name: probe.Name()}
+	feature, err := ReadProbeFeature(probe)
+	if err != nil {
+		probePlan.Error = err.Error()
+		return probePlan
+	}
+	selected := vars.ProbeIsSelected(pack, probe.Name())
+	tags := vars.ProbeTags(pack, probe.Name())
+	for _, scenario := range feature.Scenarios {
+		scenarioPlan := &ScenarioPlan{FeatureScenario: scenario}
+		switch {
+		case !packIncluded:
+			scenarioPlan.Reason = "service pack is excluded"
+		case !selected:
+			scenarioPlan.Reason = "probe was not selected"
+		default:
+			matched, clause := matchTags(tags, scenario.Tags)
+			scenarioPlan.Included = matched
+			if !matched {
+				scenarioPlan.Reason = tagExclusionReason(vars, clause)
+			}
+		}
+		if scenarioPlan.Included {
+			probePlan.Included = true
+			plan.ScenariosIncluded++
+		} else {
+			plan.ScenariosExcluded++
+		}
+		probePlan.Scenarios = append(probePlan.Scenarios, scenarioPlan)
+	}
+	return probePlan
+}
+
+// tagExclusionReason describes the tag filter clause that a scenario did not meet, along with the
+// justification from the vars file if the clause is an exclusion that was configured there
+func tagExclusionReason(vars *config.VarOptions, clause string) string {
+	if strings.HasPrefix(clause, "~") && !strings.Contains(clause, ",") {
+		if justification := vars.ExclusionJustification(strings.TrimPrefix(clause, "~")); justification != "" {
+			return fmt.Sprintf("excluded by '%s': %s", clause, justification)
+		}
+		return fmt.Sprintf("excluded by '%s'", clause)
+	}
+	return fmt.Sprintf("does not match '%s'", clause)
+}
+
+// WriteText writes the plan as an indented list of packs, probes and scenarios
+func (plan *Plan) WriteText(w io.Writer) {
+	tags := plan.Tags
+	if tags == "" {
+		tags = "(none)"
+	}
+	fmt.Fprintf(w, "Tags: %s\n", tags)
+	for _, pack := range plan.Packs {
+		fmt.Fprintf(w, "\n%s %s%s\n", planMarker(pack.Included), pack.Pack, planReason(pack.Reason))
+		for _, probe := range pack.Probes {
+			fmt.Fprintf(w, "  %s %s%s\n", planMarker(probe.Included), probe.Name, planReason(probe.Error))
+			for _, scenario := range probe.Scenarios {
+				name := scenario.Name
+				if scenario.Example != "" {
+					name = fmt.Sprintf("%s [%s]", name, scenario.Example)
+				}
+				fmt.Fprintf(w, "    %s %s (%s)%s\n", planMarker(scenario.Included), name, strings.Join(scenario.Tags, " "), planReason(scenario.Reason))
+			}
+		}
+	}
+	fmt.Fprintf(w, "\n%v scenarios would be run, %v excluded\n", plan.ScenariosIncluded, plan.ScenariosExcluded)
+}
+
+func planMarker(included bool) string {
+	if included {
+		return "+"
+	}
+	return "-"
+}
+
+func planReason(reason string) string {
+	if reason == "" {
+		return ""
+	}
+	return " - " + reason
+}
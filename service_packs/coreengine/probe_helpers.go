@@ -1,12 +1,15 @@
 package coreengine
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	"github.com/cucumber/godog"
 
@@ -14,10 +17,12 @@ import (
 	"github.com/citihub/probr/utils"
 )
 
-// Probe is an interface used by probes that are to be exported from any service pack
+// Probe is an interface used by probes that are to be exported from any service pack.
+// The context passed to ScenarioInitialize is cancelled when the scenario times out or is
+// finished, and should be used by the scenario's steps for any calls that may block.
 type Probe interface {
 	ProbeInitialize(*godog.TestSuiteContext)
-	ScenarioInitialize(*godog.ScenarioContext)
+	ScenarioInitialize(context.Context, *godog.ScenarioContext)
 	Name() string
 	Path() string
 }
@@ -26,10 +31,11 @@ const rootDirName = "probr"
 
 var outputDir *string
 
-// These variables points to the functions. they are used in oder to be able to mock oiginal behavior during testing.
-var cucumberDirFunc = config.Vars.CucumberDir // see TestGetOutputPath
-var getTmpFeatureFileFunc = getTmpFeatureFile // See TestGeatFeaturePath
-var tmpDirFunc = config.Vars.TmpDir           // See Test_getTmpFeatureFile
+// bundledFeatures holds each path returned by GetFeaturePath, which is the path of the feature within the bundle
+var (
+	bundledFeatures     = make(map[string]bool)
+	bundledFeaturesLock sync.Mutex
+)
 
 // getRootDir gets the root directory of the probr executable.
 func getRootDir() (string, error) {
@@ -60,37 +66,58 @@ func getRootDir() (string, error) {
 
 // getOutputPath gets the output path for the test based on the output directory
 // plus the test name supplied
-func getOutputPath(t string) (*os.File, error) {
+func getOutputPath(vars *config.VarOptions, t string) (*os.File, error) {
 
 	////filename is test name (supplied) + .json
 	fn := t + ".json"
-	return os.Create(filepath.Join(cucumberDirFunc(), fn))
+	return os.Create(filepath.Join(vars.CucumberDir(), fn))
 }
 
-// GetFeaturePath parses a list of strings into a standardized file path
+// GetFeaturePath parses a list of strings into the standardized path of a feature file within the bundle.
+// The feature is read from the bundle by ReadProbeFeature, and unpacked to the tmp folder of each run by NewGodogProbe.
 func GetFeaturePath(path ...string) string {
 	featureName := path[len(path)-1] + ".feature"
 	dirPath := ""
 	for _, folder := range path {
 		dirPath = filepath.Join(dirPath, folder)
 	}
-	//return filepath.Join(dirPath, featureName)
 	featurePath := filepath.Join(dirPath, featureName) // This is the original path to feature file in source code
 
-	// Unpacking/copying feature file to tmp location
-	tmpFeaturePath, err := getTmpFeatureFileFunc(featurePath)
+	bundledFeaturesLock.Lock()
+	bundledFeatures[featurePath] = true
+	bundledFeaturesLock.Unlock()
+	return featurePath
+}
+
+// isBundled returns whether the path was returned by GetFeaturePath
+func isBundled(path string) bool {
+	bundledFeaturesLock.Lock()
+	defer bundledFeaturesLock.Unlock()
+	return bundledFeatures[path]
+}
+
+// ReadProbeFeature parses the feature file of the provided probe. Features from GetFeaturePath
+// are read from the bundle with utils.ReadStaticFile, and any others from disk.
+func ReadProbeFeature(probe Probe) (*Feature, error) {
+	path := probe.Path()
+	if path == "" {
+		return nil, fmt.Errorf("feature file for probe '%s' could not be found", probe.Name())
+	}
+	if !isBundled(path) {
+		return ReadFeatureFile(path)
+	}
+	b, err := utils.ReadStaticFile(path)
 	if err != nil {
-		log.Printf("Error unpacking feature file '%v' - Error: %v", featurePath, err)
-		return ""
+		return nil, err
 	}
-	return tmpFeaturePath
+	return ParseFeature(bytes.NewReader(b), path)
 }
 
-// getTmpFeatureFile checks if feature file exists in -tmp- folder.
+// getTmpFeatureFile checks if feature file exists in the provided -tmp- folder.
 // If so returns the file path, otherwise unpacks the original file using pkger and copies it to -tmp- location before returning file path.
-func getTmpFeatureFile(featurePath string) (string, error) {
+func getTmpFeatureFile(featurePath, tmpDir string) (string, error) {
 
-	tmpFeaturePath := filepath.Join(tmpDirFunc(), featurePath)
+	tmpFeaturePath := filepath.Join(tmpDir, featurePath)
 
 	// If file already exists return it
 	_, e := os.Stat(tmpFeaturePath)
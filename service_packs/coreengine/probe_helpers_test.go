@@ -25,13 +25,13 @@ func TestGetOutputPath(t *testing.T) {
 	// Using -testdata- folder to ensure no test resources are included in build
 	// Once we migrate to go v1.15, we should use t.TempDir() to ensure built-in test directory is automatically removed by cleanup when test and subtests complete. See: https://golang.org/pkg/testing/#pkg-subdirectories
 	d := filepath.Join("testdata", "test_output_dir")
+	_ = os.MkdirAll(d, 0755)
+	vars := config.VarOptions{WriteDirectory: d}
 
 	f := "test_file"
-	desiredFile := filepath.Join(d, f) + ".json"
+	desiredFile := filepath.Join(d, "cucumber", f) + ".json"
 	defer func() {
 
-		cucumberDirFunc = config.Vars.CucumberDir //Restoring to original function after test
-
 		// Cleanup test assets
 		file.Close()
 		err := os.RemoveAll(d)
@@ -45,12 +45,7 @@ func TestGetOutputPath(t *testing.T) {
 			t.Fail()
 		}
 	}()
-	// Faking result for config.CucumberDir(). This is used inside getOutputPath.
-	cucumberDirFunc = func() string {
-		_ = os.MkdirAll(d, 0755) // Creates if not already existing
-		return d
-	}
-	file, _ = getOutputPath(f)
+	file, _ = getOutputPath(&vars, f)
 	if desiredFile != file.Name() {
 		t.Logf("Desired filepath '%s' does not match '%s'", desiredFile, file.Name())
 		t.Fail()
@@ -78,15 +73,6 @@ func TestScenarioString(t *testing.T) {
 }
 
 func TestGetFeaturePath(t *testing.T) {
-	// Faking result for getTmpFeatureFileFunc() to avoid creating -tmp- folder and feature file.
-	getTmpFeatureFileFunc = func(featurePath string) (string, error) {
-		tmpFeaturePath := filepath.Join("tmp", featurePath)
-		return tmpFeaturePath, nil
-	}
-	defer func() {
-		getTmpFeatureFileFunc = getTmpFeatureFile //Restoring to original function after test
-	}()
-
 	type args struct {
 		path []string
 	}
@@ -98,7 +84,7 @@ func TestGetFeaturePath(t *testing.T) {
 		{
 			testName:       "GetFeaturePath_WithTwoSubfoldersAndFeatureName_ShouldReturnFeatureFilePath",
 			testArgs:       args{path: []string{"service_packs", "kubernetes", "container_registry_access"}},
-			expectedResult: filepath.Join("tmp", "service_packs", "kubernetes", "container_registry_access", "container_registry_access.feature"), // Using filepath.join() instead of literal string in order to run test in Windows (\\) and Linux (/)
+			expectedResult: filepath.Join("service_packs", "kubernetes", "container_registry_access", "container_registry_access.feature"), // Using filepath.join() instead of literal string in order to run test in Windows (\\) and Linux (/)
 		},
 	}
 	for _, tt := range tests {
@@ -106,20 +92,35 @@ func TestGetFeaturePath(t *testing.T) {
 			if got := GetFeaturePath(tt.testArgs.path...); got != tt.expectedResult {
 				t.Errorf("GetFeaturePath() = %v, Expected: %v", got, tt.expectedResult)
 			}
+			if !isBundled(tt.expectedResult) {
+				t.Errorf("GetFeaturePath() did not record %v as bundled", tt.expectedResult)
+			}
 		})
 	}
 }
 
-func Test_getTmpFeatureFile(t *testing.T) {
+func TestNewGodogProbe_UnpacksBundledFeature(t *testing.T) {
 	testTmpDir := filepath.Join("testdata", utils.RandomString(10))
+	defer os.RemoveAll(testTmpDir)
 
-	// Faking original behavior
-	tmpDirFunc = func() string {
-		return testTmpDir
+	path := GetFeaturePath("service_packs", "kubernetes", "container_registry_access")
+	probe := NewGodogProbe(PackIdentity{Name: "kubernetes"}, featureProbe{fakeProbe: fakeProbe{name: "container_registry_access"}, path: path}, testTmpDir)
+	if expected := filepath.Join(testTmpDir, path); probe.FeaturePath != expected {
+		t.Errorf("NewGodogProbe() FeaturePath = %v, expected %v", probe.FeaturePath, expected)
 	}
-	defer func() {
-		tmpDirFunc = config.Vars.TmpDir //Restoring to original function after test
+	if _, err := os.Stat(probe.FeaturePath); err != nil {
+		t.Errorf("Feature was not unpacked to the tmp folder: %v", err)
+	}
+
+	probe = NewGodogProbe(PackIdentity{Name: "fake_pack"}, featureProbe{fakeProbe: fakeProbe{name: "fake_probe"}, path: "fake_probe.feature"}, testTmpDir)
+	if probe.FeaturePath != "fake_probe.feature" {
+		t.Errorf("NewGodogProbe() FeaturePath = %v, expected a feature that is not bundled to be read in place", probe.FeaturePath)
+	}
+}
 
+func Test_getTmpFeatureFile(t *testing.T) {
+	testTmpDir := filepath.Join("testdata", utils.RandomString(10))
+	defer func() {
 		// Delete test data after tests
 		os.RemoveAll(testTmpDir)
 	}()
@@ -142,7 +143,7 @@ func Test_getTmpFeatureFile(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.testName, func(t *testing.T) {
-			got, err := getTmpFeatureFile(tt.testArgs.featurePath)
+			got, err := getTmpFeatureFile(tt.testArgs.featurePath, testTmpDir)
 			if (err != nil) != tt.expectedErr {
 				t.Errorf("getTmpFeatureFile() error = %v, expected error: %v", err, tt.expectedErr)
 				return
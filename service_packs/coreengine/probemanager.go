@@ -4,14 +4,20 @@
 package coreengine
 
 import (
+	"context"
 	"errors"
 	"log"
+	"sort"
+	"strings"
 	"sync"
 
 	"github.com/citihub/probr/audit"
+	"github.com/citihub/probr/config"
 )
 
-// ProbeStatus type describes the status of the test, e.g. Pending, Running, CompleteSuccess, CompleteFail and Error
+// ProbeStatus type describes the status of the test, e.g. Pending, Running, CompleteSuccess, CompleteFail and Error.
+// GivenNotMet is used when the probe's preconditions did not hold, so its controls could not be applied.
+// CompletePending is used when the probe ran, but some of its steps have not been implemented yet.
 type ProbeStatus int
 
 //ProbeStatus enumeration for the ProbeStatus type.
@@ -22,44 +28,107 @@ const (
 	CompleteFail
 	Error
 	Excluded
+	TimedOut
+	GivenNotMet
+	CompletePending
 )
 
 func (s ProbeStatus) String() string {
-	return [...]string{"Pending", "Running", "CompleteSuccess", "CompleteFail", "Error", "Excluded"}[s]
+	return [...]string{"Pending", "Running", "CompleteSuccess", "CompleteFail", "Error", "Excluded", "TimedOut", "GivenNotMet", "CompletePending"}[s]
 }
 
-// Group type describes the group to which the test belongs, e.g. kubernetes, clouddriver, coreengine, etc.
-type Group int
-
-// Group type enumeration
+// Exit codes returned by RunProbe and ExecAllProbes, intended to be used as the process exit code
 const (
-	Kubernetes Group = iota
-	CloudDriver
-	CoreEngine
+	ExitSuccess        = 0 // All probes passed, were excluded, were pending, or their preconditions were not met
+	ExitControlFailure = 1 // At least one control was not met, or was pending in strict mode
+	ExitInternalError  = 2 // Probr was unable to run a probe
+	ExitInconclusive   = 3 // No control failed, but at least one could not be evaluated due to an infrastructure error or timeout
 )
 
-func (g Group) String() string {
-	return [...]string{"kubernetes", "clouddriver", "coreengine"}[g]
+// exitPrecedence orders the exit codes from least to most severe. A control failure is more severe than
+// an inconclusive result, so that a failing control is never reported as only an infrastructure problem.
+var exitPrecedence = map[int]int{ExitSuccess: 0, ExitInconclusive: 1, ExitControlFailure: 2, ExitInternalError: 3}
+
+// ExitCode returns the exit code that represents a probe with this status.
+// Pending steps are only treated as a control failure in strict mode.
+func (s ProbeStatus) ExitCode(strict bool) int {
+	switch s {
+	case CompleteFail:
+		return ExitControlFailure
+	case CompletePending:
+		if strict {
+			return ExitControlFailure
+		}
+		return ExitSuccess
+	case Error, TimedOut:
+		return ExitInconclusive
+	default:
+		return ExitSuccess
+	}
+}
+
+// worstExitCode returns the more severe of the provided exit codes
+func worstExitCode(a, b int) int {
+	if exitPrecedence[b] > exitPrecedence[a] {
+		return b
+	}
+	return a
+}
+
+// PackIdentity identifies the service pack, and the provider variant of that pack, that a probe belongs to
+type PackIdentity struct {
+	Name     string `json:"name"`
+	Provider string `json:"provider,omitempty"`
+}
+
+// String returns the pack name, followed by the provider if the pack has one, e.g. "storage/azure"
+func (p PackIdentity) String() string {
+	if p.Provider == "" {
+		return p.Name
+	}
+	return p.Name + "/" + p.Provider
 }
 
-// ProbeDescriptor describes the specific test case and includes name and group.
+// ProbeDescriptor describes the specific test case and includes name and the pack it belongs to.
 type ProbeDescriptor struct {
-	Group Group  `json:"group,omitempty"`
-	Name  string `json:"name,omitempty"`
+	Pack PackIdentity `json:"pack,omitempty"`
+	Name string       `json:"name,omitempty"`
+}
+
+// OutputName returns a file name for the probe's results that is unique across packs and providers, e.g. "storage-azure-access_whitelisting"
+func (d ProbeDescriptor) OutputName() string {
+	if d.Pack.Name == "" {
+		return d.Name
+	}
+	return strings.Replace(d.Pack.String(), "/", "-", -1) + "-" + d.Name
+}
+
+// Key identifies the probe in a ProbeStore and in the audit summary, e.g. "storage/azure/access_whitelisting",
+// as probes in different packs may share a name
+func (d ProbeDescriptor) Key() string {
+	if d.Pack.Name == "" {
+		return d.Name
+	}
+	return d.Pack.String() + "/" + d.Name
 }
 
 // ProbeStore maintains a collection of probes to be run and their status.  FailedProbes is an explicit
 // collection of failed probes.
 type ProbeStore struct {
-	Probes       map[string]*GodogProbe
+	Probes       map[string]*GodogProbe // Keyed by ProbeDescriptor.Key
 	FailedProbes map[ProbeStatus]*GodogProbe
 	Lock         sync.RWMutex
+	vars         *config.VarOptions
+	summary      *audit.Summary
 }
 
-// NewProbeStore creates a new object to store GodogProbes
-func NewProbeStore() *ProbeStore {
+// NewProbeStore creates a new object to store GodogProbes, which are run using the provided config and
+// audited in the provided summary. The CLI uses config.Vars and audit.State.
+func NewProbeStore(vars *config.VarOptions, summary *audit.Summary) *ProbeStore {
 	return &ProbeStore{
-		Probes: make(map[string]*GodogProbe),
+		Probes:  make(map[string]*GodogProbe),
+		vars:    vars,
+		summary: summary,
 	}
 }
 
@@ -70,13 +139,17 @@ func (ps *ProbeStore) AddProbe(probe *GodogProbe) {
 
 	status := Pending
 	probe.Status = &status
-	ps.Probes[probe.ProbeDescriptor.Name] = probe
+	key := probe.ProbeDescriptor.Key()
+	ps.Probes[key] = probe
 
-	audit.State.GetProbeLog(probe.ProbeDescriptor.Name).Result = probe.Status.String()
-	audit.State.LogProbeMeta(probe.ProbeDescriptor.Name, "group", probe.ProbeDescriptor.Group.String())
+	ps.summary.AddProbe(key, probe.ProbeDescriptor.OutputName()).Result = probe.Status.String()
+	ps.summary.LogProbeMeta(key, "pack", probe.ProbeDescriptor.Pack.Name)
+	if probe.ProbeDescriptor.Pack.Provider != "" {
+		ps.summary.LogProbeMeta(key, "provider", probe.ProbeDescriptor.Pack.Provider)
+	}
 }
 
-// GetProbe returns the test identified by the given name.
+// GetProbe returns the test identified by the given key, see ProbeDescriptor.Key.
 func (ps *ProbeStore) GetProbe(name string) (*GodogProbe, error) {
 	ps.Lock.Lock()
 	defer ps.Lock.Unlock()
@@ -90,33 +163,110 @@ func (ps *ProbeStore) GetProbe(name string) (*GodogProbe, error) {
 	return p, nil
 }
 
-// ExecProbe executes the test identified by the specified name.
-func (ps *ProbeStore) ExecProbe(name string) (int, error) {
+// ExecProbe executes the test identified by the specified key.
+func (ps *ProbeStore) ExecProbe(ctx context.Context, name string) (int, error) {
 	p, err := ps.GetProbe(name)
 	if err != nil {
 		return 1, err // Failure
 	}
-	if p.Status.String() != Excluded.String() {
-		return ps.RunProbe(p) // Return test results
+	if ps.GetStatus(p) != Excluded {
+		ps.SetStatus(p, Running)
+		return ps.RunProbe(ctx, p) // Return test results
 	}
 	return 0, nil // Succeed if test is excluded
 }
 
 // ExecAllProbes executes all tests that are present in the ProbeStore.
-func (ps *ProbeStore) ExecAllProbes() (int, error) {
-	status := 0
-	var err error
+// Up to ProbeConcurrency probes are executed at the same time. The returned
+// status is the most severe exit code returned by any probe, regardless of the order they complete in.
+// Probes that are still queued or running when ctx is done are recorded as timed out.
+// The store's config and summary are passed to the probes through ctx. See config.FromContext and audit.FromContext.
+func (ps *ProbeStore) ExecAllProbes(ctx context.Context) (int, error) {
+	ctx = audit.WithSummary(config.WithVars(ctx, ps.vars), ps.summary)
 
+	ps.Lock.RLock()
+	names := make([]string, 0, len(ps.Probes))
 	for name := range ps.Probes {
-		st, err := ps.ExecProbe(name)
-		audit.State.ProbeComplete(name)
-		if err != nil {
-			//log but continue with remaining probe
-			log.Printf("[ERROR] error executing probe: %v", err)
-		}
-		if st > status {
-			status = st
-		}
+		names = append(names, name)
+	}
+	ps.Lock.RUnlock()
+	sort.Strings(names) // Queue probes in a predictable order
+
+	ps.vars.GetTags() // Resolve any tag exclusions before the probes begin sharing them
+
+	workers := ps.vars.ProbeConcurrency
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(names) {
+		workers = len(names)
+	}
+
+	var (
+		status   int
+		firstErr error
+		mutex    sync.Mutex
+		wg       sync.WaitGroup
+	)
+	queue := make(chan string)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for name := range queue {
+				var st int
+				var err error
+				if ctx.Err() != nil {
+					// Probes are not started once the run has ended, as each could take until abandonAfter to stop
+					st = ps.timeOut(name)
+				} else {
+					st, err = ps.ExecProbe(ctx, name)
+				}
+				ps.summary.ProbeComplete(name)
+
+				mutex.Lock()
+				if err != nil {
+					//log but continue with remaining probe
+					log.Printf("[ERROR] error executing probe: %v", err)
+					if firstErr == nil {
+						firstErr = err
+					}
+				}
+				status = worstExitCode(status, st)
+				mutex.Unlock()
+			}
+		}()
+	}
+	for _, name := range names {
+		queue <- name
+	}
+	close(queue)
+	wg.Wait()
+
+	return status, firstErr
+}
+
+// timeOut records a queued probe as timed out without running it, unless it is excluded
+func (ps *ProbeStore) timeOut(name string) int {
+	p, err := ps.GetProbe(name)
+	if err != nil || ps.GetStatus(p) == Excluded {
+		return 0
 	}
-	return status, err
+	ps.SetStatus(p, TimedOut)
+	ps.summary.GetProbeLog(name).Result = "TimedOut"
+	return TimedOut.ExitCode(ps.vars.Strict)
+}
+
+// GetStatus returns the current status of the provided probe
+func (ps *ProbeStore) GetStatus(probe *GodogProbe) ProbeStatus {
+	ps.Lock.RLock()
+	defer ps.Lock.RUnlock()
+	return *probe.Status
+}
+
+// SetStatus updates the status of the provided probe
+func (ps *ProbeStore) SetStatus(probe *GodogProbe, status ProbeStatus) {
+	ps.Lock.Lock()
+	defer ps.Lock.Unlock()
+	*probe.Status = status
 }
@@ -1,7 +1,19 @@
 package coreengine
 
 import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
+
+	"github.com/cucumber/godog"
+
+	"github.com/citihub/probr/audit"
+	"github.com/citihub/probr/config"
+	"github.com/citihub/probr/utils"
 )
 
 const (
@@ -11,14 +23,14 @@ const (
 func createProbeObj(name string) *GodogProbe {
 	return &GodogProbe{
 		ProbeDescriptor: &ProbeDescriptor{
-			Name:  name,
-			Group: Kubernetes,
+			Name: name,
+			Pack: PackIdentity{Name: "kubernetes"},
 		},
 	}
 }
 
 func TestNewProbeStore(t *testing.T) {
-	ts := NewProbeStore()
+	ts := NewProbeStore(&config.Vars, audit.State)
 	if ts == nil {
 		t.Logf("Probe store was not initialized")
 		t.Fail()
@@ -29,25 +41,46 @@ func TestNewProbeStore(t *testing.T) {
 }
 
 func TestAddProbe(t *testing.T) {
-	ps := NewProbeStore()
+	ps := NewProbeStore(&config.Vars, audit.State)
 	ps.AddProbe(createProbeObj(probeName))
 
 	// Verify correct conditions succeed
-	if ps.Probes[probeName] == nil {
+	if ps.Probes["kubernetes/"+probeName] == nil {
 		t.Logf("Probe not added to probe store")
 		t.Fail()
-	} else if ps.Probes[probeName].ProbeDescriptor.Name != probeName {
+	} else if ps.Probes["kubernetes/"+probeName].ProbeDescriptor.Name != probeName {
 		t.Logf("Probe name not set properly in test store")
 		t.Fail()
 	}
 }
 
+func TestAddProbe_SameNameInTwoPacks(t *testing.T) {
+	vars, _ := config.NewConfig("")
+	summary := audit.NewSummary(&vars)
+	ps := NewProbeStore(&vars, summary)
+	azure := &GodogProbe{ProbeDescriptor: &ProbeDescriptor{Name: probeName, Pack: PackIdentity{Name: "storage", Provider: "azure"}}}
+	aws := &GodogProbe{ProbeDescriptor: &ProbeDescriptor{Name: probeName, Pack: PackIdentity{Name: "storage", Provider: "aws"}}}
+	ps.AddProbe(azure)
+	ps.AddProbe(aws)
+
+	if len(ps.Probes) != 2 || ps.Probes["storage/azure/"+probeName] != azure || ps.Probes["storage/aws/"+probeName] != aws {
+		t.Errorf("Probes with the same name in different packs should both be stored, got %v", ps.Probes)
+	}
+	azureLog, awsLog := summary.GetProbeLog("storage/azure/"+probeName), summary.GetProbeLog("storage/aws/"+probeName)
+	if azureLog == awsLog || len(summary.Probes) != 2 {
+		t.Errorf("Probes with the same name in different packs should be audited separately")
+	}
+	if filepath.Base(azureLog.AuditPath()) != "storage-azure-"+probeName+".json" {
+		t.Errorf("Unexpected audit path: %v", azureLog.AuditPath())
+	}
+}
+
 func TestGetProbe(t *testing.T) {
-	ps := NewProbeStore()
+	ps := NewProbeStore(&config.Vars, audit.State)
 	probe := createProbeObj(probeName)
 	ps.AddProbe(probe)
 
-	retrievedProbe, err := ps.GetProbe(probeName)
+	retrievedProbe, err := ps.GetProbe(probe.ProbeDescriptor.Key())
 	if err != nil {
 		t.Logf(err.Error())
 		t.Fail()
@@ -58,6 +91,311 @@ func TestGetProbe(t *testing.T) {
 	}
 }
 
+func TestExecAllProbes(t *testing.T) {
+	tests := []struct {
+		testName    string
+		concurrency int
+		probeCount  int
+	}{
+		{testName: "Sequential", concurrency: 1, probeCount: 3},
+		{testName: "Concurrent", concurrency: 4, probeCount: 10},
+		{testName: "ConcurrencyUnset", concurrency: 0, probeCount: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			vars, _ := config.NewConfig("")
+			vars.ProbeConcurrency = tt.concurrency
+			summary := audit.NewSummary(&vars)
+			ps := NewProbeStore(&vars, summary)
+			for i := 0; i < tt.probeCount; i++ {
+				p := createProbeObj(fmt.Sprintf("%s_probe_%v", tt.testName, i))
+				ps.AddProbe(p)
+				ps.SetStatus(p, Excluded) // Excluded probes complete without invoking godog
+			}
+
+			s, err := ps.ExecAllProbes(context.Background())
+			if s != 0 || err != nil {
+				t.Errorf("ExecAllProbes() = %v, %v; want 0, nil", s, err)
+			}
+			for name := range ps.Probes {
+				if summary.GetProbeLog(name).Result == "Pending" {
+					t.Errorf("Probe '%s' was not completed", name)
+				}
+			}
+		})
+	}
+}
+
+func TestExecAllProbes_Cancelled(t *testing.T) {
+	vars, _ := config.NewConfig("")
+	summary := audit.NewSummary(&vars)
+	ps := NewProbeStore(&vars, summary)
+	queued := createProbeObj("queued_probe") // Has no feature, so would fail if it were run
+	excluded := createProbeObj("excluded_probe")
+	ps.AddProbe(queued)
+	ps.AddProbe(excluded)
+	ps.SetStatus(excluded, Excluded)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	s, err := ps.ExecAllProbes(ctx)
+	if s != ExitInconclusive || err != nil {
+		t.Errorf("ExecAllProbes() = %v, %v; want %v, nil", s, err, ExitInconclusive)
+	}
+	if ps.GetStatus(queued) != TimedOut || summary.GetProbeLog(queued.ProbeDescriptor.Key()).Result != "TimedOut" {
+		t.Errorf("A probe queued after the run ended should be recorded as timed out, got %v", ps.GetStatus(queued))
+	}
+	if ps.GetStatus(excluded) != Excluded {
+		t.Errorf("An excluded probe should remain excluded, got %v", ps.GetStatus(excluded))
+	}
+}
+
 // Integration methods:
 // TestExecProbe
-// TestExecAllProbes
+
+func TestWorstExitCode(t *testing.T) {
+	tests := []struct {
+		testName string
+		statuses []ProbeStatus
+		expected int
+	}{
+		{testName: "allPassed", statuses: []ProbeStatus{CompleteSuccess, Excluded}, expected: ExitSuccess},
+		{testName: "givenNotMetIsNotAFailure", statuses: []ProbeStatus{GivenNotMet, CompleteSuccess}, expected: ExitSuccess},
+		{testName: "inconclusive", statuses: []ProbeStatus{CompleteSuccess, Error}, expected: ExitInconclusive},
+		{testName: "timedOutIsInconclusive", statuses: []ProbeStatus{TimedOut, CompleteSuccess}, expected: ExitInconclusive},
+		{testName: "failureBeforeInconclusive", statuses: []ProbeStatus{CompleteFail, Error}, expected: ExitControlFailure},
+		{testName: "failureAfterInconclusive", statuses: []ProbeStatus{Error, CompleteFail, TimedOut}, expected: ExitControlFailure},
+	}
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			status := ExitSuccess
+			for _, s := range tt.statuses {
+				status = worstExitCode(status, s.ExitCode(false))
+			}
+			if status != tt.expected {
+				t.Errorf("worstExitCode() = %v, want %v", status, tt.expected)
+			}
+		})
+	}
+	if worstExitCode(ExitControlFailure, ExitInternalError) != ExitInternalError {
+		t.Errorf("An internal error should take precedence over a control failure")
+	}
+}
+
+func TestProbeStatus_ExitCode_Pending(t *testing.T) {
+	tests := []struct {
+		testName string
+		strict   bool
+		expected int
+	}{
+		{testName: "pendingDoesNotFailRun", strict: false, expected: ExitSuccess},
+		{testName: "pendingFailsStrictRun", strict: true, expected: ExitControlFailure},
+	}
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			if code := CompletePending.ExitCode(tt.strict); code != tt.expected {
+				t.Errorf("CompletePending.ExitCode() = %v, want %v", code, tt.expected)
+			}
+		})
+	}
+}
+
+func TestProbeDescriptor_OutputName(t *testing.T) {
+	tests := []struct {
+		testName   string
+		descriptor ProbeDescriptor
+		expected   string
+	}{
+		{
+			testName:   "packWithoutProvider",
+			descriptor: ProbeDescriptor{Pack: PackIdentity{Name: "kubernetes"}, Name: "iam_control"},
+			expected:   "kubernetes-iam_control",
+		},
+		{
+			testName:   "packWithProvider",
+			descriptor: ProbeDescriptor{Pack: PackIdentity{Name: "storage", Provider: "azure"}, Name: "access_whitelisting"},
+			expected:   "storage-azure-access_whitelisting",
+		},
+		{
+			testName:   "noPack",
+			descriptor: ProbeDescriptor{Name: "iam_control"},
+			expected:   "iam_control",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			if name := tt.descriptor.OutputName(); name != tt.expected {
+				t.Errorf("ProbeDescriptor.OutputName() = %v, want %v", name, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRunProbe_TimedOut(t *testing.T) {
+	vars, _ := config.NewConfig("")
+	vars.OutputType = "INMEM"
+	vars.ResultsFormat = "progress"
+	vars.ProbeTimeout = config.Duration(100 * time.Millisecond)
+	summary := audit.NewSummary(&vars)
+
+	dir, err := ioutil.TempDir("", "probr-timeout")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	featurePath := filepath.Join(dir, "slow.feature")
+	feature := "Feature: Slow probe\n\n  Scenario: A step that never finishes\n    Given a step that waits for its context\n"
+	if err = ioutil.WriteFile(featurePath, []byte(feature), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	p := createProbeObj("slow_probe")
+	p.FeaturePath = featurePath
+	p.ProbeInitializer = func(*godog.TestSuiteContext) {}
+	p.ScenarioInitializer = func(ctx context.Context, sc *godog.ScenarioContext) {
+		sc.Step(`^a step that waits for its context$`, func() error {
+			<-ctx.Done()
+			return ctx.Err()
+		})
+	}
+	ps := NewProbeStore(&vars, summary)
+	ps.AddProbe(p)
+
+	s, err := ps.RunProbe(context.Background(), p)
+	if s == 0 || err != nil {
+		t.Errorf("RunProbe() = %v, %v; want a non-zero status and no error", s, err)
+	}
+	if ps.GetStatus(p) != TimedOut {
+		t.Errorf("Probe status = %v, want %v", ps.GetStatus(p), TimedOut)
+	}
+	if result := summary.GetProbeLog(p.ProbeDescriptor.Key()).Result; result != "TimedOut" {
+		t.Errorf("Audit result = %v, want TimedOut", result)
+	}
+}
+
+func TestRunProbe_Nil(t *testing.T) {
+	vars, _ := config.NewConfig("")
+	summary := audit.NewSummary(&vars)
+	ps := NewProbeStore(&vars, summary)
+
+	if s, err := ps.RunProbe(context.Background(), nil); s != ExitInternalError || err == nil {
+		t.Errorf("RunProbe(nil) = %v, %v; want %v and an error", s, err, ExitInternalError)
+	}
+	if len(summary.Probes) != 0 {
+		t.Errorf("A nil probe should not be recorded in the summary: %v", summary.Probes)
+	}
+
+	p := createProbeObj("no_descriptor")
+	p.ProbeDescriptor = nil
+	status := Pending
+	p.Status = &status
+	if s, err := ps.RunProbe(context.Background(), p); s != ExitInternalError || err == nil {
+		t.Errorf("RunProbe() without a descriptor = %v, %v; want %v and an error", s, err, ExitInternalError)
+	}
+	if *p.Status != Error {
+		t.Errorf("Probe status = %v, want %v", *p.Status, Error)
+	}
+	if result := summary.GetProbeLog(unknownProbeKey).Result; result != "Internal Error - Probe descriptor not found" {
+		t.Errorf("Audit result = %v, want an internal error", result)
+	}
+}
+
+func TestRunProbe_PassedAndGivenNotMet(t *testing.T) {
+	vars, _ := config.NewConfig("")
+	vars.OutputType = "INMEM"
+	vars.ResultsFormat = "progress"
+	summary := audit.NewSummary(&vars)
+
+	dir, err := ioutil.TempDir("", "probr-given-not-met")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	featurePath := filepath.Join(dir, "mixed.feature")
+	feature := "Feature: Mixed probe\n\n  Scenario: A control that applies\n    Given a precondition that holds\n\n" +
+		"  Scenario: A control that does not apply\n    Given a precondition that does not hold\n"
+	if err = ioutil.WriteFile(featurePath, []byte(feature), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	p := createProbeObj("mixed_probe")
+	p.FeaturePath = featurePath
+	p.ProbeInitializer = func(*godog.TestSuiteContext) {}
+	p.ScenarioInitializer = func(ctx context.Context, sc *godog.ScenarioContext) {
+		var scenario *audit.ScenarioAudit
+		sc.BeforeScenario(func(s *godog.Scenario) {
+			scenario = audit.ProbeLog(ctx, "mixed_probe").InitializeAuditor(s.Name, s.Tags)
+		})
+		sc.Step(`^a precondition that holds$`, func() error {
+			scenario.AuditScenarioStep("a precondition that holds", "", nil, nil)
+			return nil
+		})
+		sc.Step(`^a precondition that does not hold$`, func() error {
+			err := utils.GivenNotMet("the service is not deployed")
+			scenario.AuditScenarioStep("a precondition that does not hold", "", nil, err)
+			return err
+		})
+	}
+	ps := NewProbeStore(&vars, summary)
+	ps.AddProbe(p)
+
+	s, err := ps.RunProbe(context.Background(), p)
+	summary.ProbeComplete(p.ProbeDescriptor.Key())
+	if s != ExitSuccess || err != nil {
+		t.Errorf("RunProbe() = %v, %v; want %v, nil", s, err, ExitSuccess)
+	}
+	// The probe's status and its result in the summary follow the same rule
+	if ps.GetStatus(p) != GivenNotMet {
+		t.Errorf("Probe status = %v, want %v", ps.GetStatus(p), GivenNotMet)
+	}
+	if result := summary.GetProbeLog(p.ProbeDescriptor.Key()).Result; result != "Given Not Met" {
+		t.Errorf("Audit result = %v, want Given Not Met", result)
+	}
+}
+
+func TestRunProbe_UndefinedStep(t *testing.T) {
+	tests := []struct {
+		testName     string
+		strict       bool
+		expectedExit int
+	}{
+		{testName: "NotStrict", strict: false, expectedExit: ExitSuccess},
+		{testName: "Strict", strict: true, expectedExit: ExitControlFailure},
+	}
+	dir, err := ioutil.TempDir("", "probr-undefined")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	featurePath := filepath.Join(dir, "undefined.feature")
+	feature := "Feature: Undefined probe\n\n  Scenario: A step that is not implemented\n    Given a step that is not implemented\n"
+	if err = ioutil.WriteFile(featurePath, []byte(feature), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			vars, _ := config.NewConfig("")
+			vars.OutputType = "INMEM"
+			vars.ResultsFormat = "progress"
+			vars.Strict = tt.strict
+			summary := audit.NewSummary(&vars)
+
+			p := createProbeObj("undefined_probe")
+			p.FeaturePath = featurePath
+			p.ProbeInitializer = func(*godog.TestSuiteContext) {}
+			p.ScenarioInitializer = func(ctx context.Context, sc *godog.ScenarioContext) {}
+			ps := NewProbeStore(&vars, summary)
+			ps.AddProbe(p)
+
+			s, _ := ps.RunProbe(context.Background(), p)
+			summary.ProbeComplete(p.ProbeDescriptor.Key())
+			if s != tt.expectedExit || ps.GetStatus(p) != CompletePending {
+				t.Errorf("RunProbe() = %v with status %v; want %v with status %v", s, ps.GetStatus(p), tt.expectedExit, CompletePending)
+			}
+			if result := summary.GetProbeLog(p.ProbeDescriptor.Key()).Result; result != "Pending" {
+				t.Errorf("Audit result = %v, want Pending", result)
+			}
+		})
+	}
+}
@@ -2,12 +2,19 @@ package coreengine
 
 import (
 	"bytes"
+	"context"
 	"fmt"
+	"log"
+	"time"
 
 	"github.com/citihub/probr/audit"
+	"github.com/citihub/probr/config"
 	"github.com/cucumber/godog"
 )
 
+// unknownProbeKey is used in the summary for a probe that has no descriptor, and so no key
+const unknownProbeKey = "unknown"
+
 // ProbeRunner describes the interface that should be implemented to support the execution of tests.
 type ProbeRunner interface {
 	RunProbe(t *GodogProbe) error
@@ -22,38 +29,103 @@ type ProbeHandlerFunc func(t *GodogProbe) (int, *bytes.Buffer, error)
 type GodogProbe struct {
 	ProbeDescriptor     *ProbeDescriptor
 	ProbeInitializer    func(*godog.TestSuiteContext)
-	ScenarioInitializer func(*godog.ScenarioContext)
+	ScenarioInitializer func(context.Context, *godog.ScenarioContext)
 	FeaturePath         string
 	Status              *ProbeStatus `json:"status,omitempty"`
 	Results             *bytes.Buffer
+
+	recorder  resultRecorder // the outcome of each scenario, which is reported by ProbeStore.Results
+	startedAt time.Time
+	duration  time.Duration
+	err       string // set if the probe could not be run
+}
+
+// NewGodogProbe prepares a probe from the identified service pack to be added to a ProbeStore.
+// A feature from GetFeaturePath is unpacked to tmpDir, so that it can be read by Godog.
+func NewGodogProbe(pack PackIdentity, p Probe, tmpDir string) *GodogProbe {
+	descriptor := ProbeDescriptor{Pack: pack, Name: p.Name()}
+	featurePath := p.Path()
+	if isBundled(featurePath) {
+		tmpFeaturePath, err := getTmpFeatureFile(featurePath, tmpDir)
+		if err != nil {
+			log.Printf("[ERROR] Error unpacking feature file '%v' - Error: %v", featurePath, err)
+		}
+		featurePath = tmpFeaturePath
+	}
+	return &GodogProbe{
+		ProbeDescriptor:     &descriptor,
+		ProbeInitializer:    p.ProbeInitialize,
+		ScenarioInitializer: p.ScenarioInitialize,
+		FeaturePath:         featurePath,
+	}
 }
 
 // RunProbe runs the test case described by the supplied Probe.  It looks in it's test register (the handlers global
 // variable) for an entry with the same ProbeDescriptor as the supplied test.  If found, it uses the provided GodogProbe
-func (ps *ProbeStore) RunProbe(probe *GodogProbe) (int, error) {
+// The probe is given until ProbeTimeout to complete, and is recorded as timed out if ctx is done first.
+// The store's config and summary are passed to the probe through ctx.
+func (ps *ProbeStore) RunProbe(ctx context.Context, probe *GodogProbe) (int, error) {
+	ctx = audit.WithSummary(config.WithVars(ctx, ps.vars), ps.summary)
 
 	if probe == nil {
-		audit.State.GetProbeLog(probe.ProbeDescriptor.Name).Result = "Internal Error - Probe not found"
-		return 2, fmt.Errorf("probe is nil - cannot run test")
+		// There is no key to record the error against in the summary
+		return ExitInternalError, fmt.Errorf("probe is nil - cannot run test")
 	}
 
 	if probe.ProbeDescriptor == nil {
 		//update status
-		*probe.Status = Error
-		audit.State.GetProbeLog(probe.ProbeDescriptor.Name).Result = "Internal Error - Probe descriptor not found"
-		return 3, fmt.Errorf("probe descriptor is nil - cannot run test")
+		if probe.Status != nil {
+			ps.SetStatus(probe, Error)
+		}
+		ps.summary.GetProbeLog(unknownProbeKey).Result = "Internal Error - Probe descriptor not found"
+		return ExitInternalError, fmt.Errorf("probe descriptor is nil - cannot run test")
 	}
 
-	s, o, err := GodogProbeHandler(probe)
+	ctx = audit.WithProbe(ctx, probe.ProbeDescriptor.Key())
+	ctx, cancel := withTimeout(ctx, ps.vars.GetProbeTimeout())
+	defer cancel()
 
-	if s == 0 {
-		// success
-		*probe.Status = CompleteSuccess
-	} else {
-		// fail
-		*probe.Status = CompleteFail
+	probe.startedAt = time.Now()
+	s, o, err := GodogProbeHandler(ctx, probe)
+	probe.duration = time.Since(probe.startedAt)
+	if err != nil {
+		probe.err = err.Error()
 	}
 
+	if ctx.Err() != nil {
+		// The probe's scenarios were cut short, so its results are incomplete
+		log.Printf("[WARN] Probe '%s' timed out: %v", probe.ProbeDescriptor.Name, ctx.Err())
+		ps.SetStatus(probe, TimedOut)
+		ps.summary.GetProbeLog(probe.ProbeDescriptor.Key()).Result = "TimedOut"
+		probe.Results = o
+		return TimedOut.ExitCode(ps.vars.Strict), nil
+	}
+
+	status := CompleteSuccess
+	probeLog := ps.summary.GetProbeLog(probe.ProbeDescriptor.Key())
+	switch {
+	case probeLog.IsInconclusive():
+		// No control failed, but at least one could not be evaluated
+		status = Error
+	case probeLog.IsPending():
+		// Some steps are yet to be implemented. Godog only reports these as failures in strict mode.
+		status = CompletePending
+	case probeLog.IsGivenNotMet():
+		// Godog reports the unmet Given steps as failures, but no control failed
+		status = GivenNotMet
+	case s != 0:
+		status = CompleteFail
+	}
+	ps.SetStatus(probe, status)
+
 	probe.Results = o // If in-mem output provided, store as Results
-	return s, err
+	return status.ExitCode(ps.vars.Strict), err
+}
+
+// withTimeout derives a context that is cancelled after the provided duration. A duration of 0 means no deadline.
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout > 0 {
+		return context.WithTimeout(ctx, timeout)
+	}
+	return context.WithCancel(ctx)
 }
@@ -0,0 +1,260 @@
+package coreengine
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cucumber/godog"
+	"github.com/cucumber/messages-go/v10"
+
+	"github.com/citihub/probr/audit"
+	"github.com/citihub/probr/config"
+)
+
+// Statuses of scenarios and steps in addition to the audit results, e.g. "Passed" or "Inconclusive"
+const (
+	StatusSkipped   = "Skipped"   // The step was not run, as an earlier step of the scenario did not pass
+	StatusUndefined = "Undefined" // The scenario has a step that is not implemented by the probe
+)
+
+// ProbeResult is the outcome of a probe and of each of its scenarios, which may be serialised to JSON.
+// Step payloads are not copied from the audit, which is referred to instead.
+type ProbeResult struct {
+	Pack      PackIdentity      `json:"pack"`
+	Name      string            `json:"name"`
+	Status    string            `json:"status"` // The ProbeStatus, e.g. "CompleteFail"
+	Result    string            `json:"result"` // The result in the summary, e.g. "Failed" or "Given Not Met"
+	StartedAt time.Time         `json:"started_at,omitempty"`
+	Duration  config.Duration   `json:"duration"`
+	Error     string            `json:"error,omitempty"` // Set if the probe could not be run
+	Audit     string            `json:"audit,omitempty"` // Path of the probe's audit, if it was written
+	Scenarios []*ScenarioResult `json:"scenarios"`
+}
+
+// ScenarioResult is the outcome of a scenario and of each of its steps
+type ScenarioResult struct {
+	Name          string          `json:"name"`
+	Tags          []string        `json:"tags,omitempty"`
+	Status        string          `json:"status"` // The audit result, e.g. "Passed" or "Inconclusive", StatusSkipped or StatusUndefined
+	StartedAt     time.Time       `json:"started_at"`
+	Duration      config.Duration `json:"duration"`
+	Error         string          `json:"error,omitempty"`
+	AuditScenario int             `json:"audit_scenario,omitempty"` // Key of the scenario in the Scenarios of the probe's audit, if it was audited
+	Steps         []*StepResult   `json:"steps"`
+}
+
+// StepResult is the outcome of a step
+type StepResult struct {
+	Name      string          `json:"name"`
+	Status    string          `json:"status"` // As for ScenarioResult
+	StartedAt time.Time       `json:"started_at,omitempty"`
+	Duration  config.Duration `json:"duration"`
+	Error     string          `json:"error,omitempty"`
+	AuditStep int             `json:"audit_step,omitempty"` // Key of the step in the Steps of the audit scenario, which holds its payload
+}
+
+// resultRecorder records the outcome of each scenario of a probe as Godog runs it
+type resultRecorder struct {
+	lock      sync.Mutex // an abandoned suite may still be recording while the results are read
+	scenarios []*ScenarioResult
+}
+
+// scenarioRecorder records a single scenario. Godog runs the hooks of a scenario one after the other.
+type scenarioRecorder struct {
+	results   *resultRecorder
+	probe     *audit.Probe
+	audited   int // Number of scenarios audited by the probe before this one
+	scenario  *ScenarioResult
+	tags      []*messages.Pickle_PickleTag
+	stepIDs   map[string]*StepResult
+	step      *StepResult // The step being run, if any
+	stepStart time.Time
+	stepFrom  int  // Number of steps audited in the scenario before the current step
+	stopped   bool // Set once a step has not passed, after which Godog skips the remaining steps
+}
+
+// newScenarioRecorder prepares to record a scenario of the probe, before the probe's own hooks are run
+func (r *resultRecorder) newScenarioRecorder(ctx context.Context, gd *GodogProbe) *scenarioRecorder {
+	probe := audit.FromContext(ctx).GetProbeLog(gd.ProbeDescriptor.Key())
+	return &scenarioRecorder{results: r, probe: probe, audited: probe.ScenarioCount()}
+}
+
+// start records the scenario and its steps, which are skipped unless they are run
+func (s *scenarioRecorder) start(gs *godog.Scenario) {
+	s.scenario = &ScenarioResult{Name: gs.Name, Status: StatusSkipped, StartedAt: time.Now(), Steps: []*StepResult{}}
+	s.tags = gs.Tags
+	s.stepIDs = make(map[string]*StepResult)
+	for _, tag := range gs.Tags {
+		s.scenario.Tags = append(s.scenario.Tags, tag.Name)
+	}
+	for _, step := range gs.Steps {
+		result := &StepResult{Name: step.Text, Status: StatusSkipped}
+		s.scenario.Steps = append(s.scenario.Steps, result)
+		s.stepIDs[step.Id] = result
+	}
+	if s.probe.ScenarioCount() > s.audited {
+		s.scenario.AuditScenario = s.probe.ScenarioCount() // The probe began auditing the scenario in its own hook
+	}
+
+	s.results.lock.Lock()
+	defer s.results.lock.Unlock()
+	s.results.scenarios = append(s.results.scenarios, s.scenario)
+}
+
+// beforeStep starts to record the step, unless Godog is going to skip it
+func (s *scenarioRecorder) beforeStep(st *godog.Step) {
+	s.results.lock.Lock()
+	defer s.results.lock.Unlock()
+
+	s.markUndefined() // Godog does not run the AfterStep hooks of an undefined step
+	if s.stopped || s.scenario == nil {
+		return
+	}
+	s.step = s.stepIDs[st.Id]
+	s.stepStart = time.Now()
+	if auditScenario := s.auditScenario(); auditScenario != nil {
+		s.stepFrom = auditScenario.StepCount()
+	}
+}
+
+// afterStep records the result of the step, which is the most severe of the result of its error and any
+// result that it audited
+func (s *scenarioRecorder) afterStep(st *godog.Step, err error) {
+	s.results.lock.Lock()
+	defer s.results.lock.Unlock()
+
+	if s.step == nil {
+		return
+	}
+	s.step.StartedAt = s.stepStart
+	s.step.Duration = config.Duration(time.Since(s.stepStart))
+	s.step.Status = audit.ResultOf(err)
+	if err != nil {
+		s.step.Error = audit.ErrorText(err)
+		s.stopped = true
+	}
+	if auditScenario := s.auditScenario(); auditScenario != nil {
+		for n := s.stepFrom + 1; n <= auditScenario.StepCount(); n++ {
+			result, errText := auditScenario.StepResult(n)
+			if audit.WorseResult(s.step.Status, result) == result {
+				s.step.Status = result
+				if errText != "" {
+					s.step.Error = errText
+				}
+			}
+			s.step.AuditStep = n
+		}
+	}
+	s.scenario.Status = worseStatus(s.scenario.Status, s.step.Status)
+	s.step = nil
+}
+
+// markUndefined records a step that was started but not completed as undefined. The step is audited as
+// pending, as it has not been implemented yet, so that it fails the probe in strict mode.
+func (s *scenarioRecorder) markUndefined() {
+	if s.step == nil {
+		return
+	}
+	s.step.Status = StatusUndefined
+	s.scenario.Status = StatusUndefined
+	auditScenario := s.auditScenario()
+	if auditScenario == nil {
+		// The probe did not audit the scenario, which must still be counted as pending
+		auditScenario = s.probe.InitializeAuditor(s.scenario.Name, s.tags)
+		s.scenario.AuditScenario = s.probe.ScenarioCount()
+	}
+	auditScenario.AuditStep("", s.step.Name, "The step is not implemented by the probe", nil, godog.ErrUndefined)
+	s.step.AuditStep = auditScenario.StepCount()
+	s.stopped = true
+	s.step = nil
+}
+
+// end records the result of the scenario, after the probe's own hooks have been run
+func (s *scenarioRecorder) end(gs *godog.Scenario, err error) {
+	s.results.lock.Lock()
+	defer s.results.lock.Unlock()
+
+	if s.scenario == nil {
+		return
+	}
+	s.markUndefined()
+	s.scenario.Duration = config.Duration(time.Since(s.scenario.StartedAt))
+	if auditScenario := s.auditScenario(); auditScenario != nil && auditScenario.Result != "" && s.scenario.Status != StatusUndefined {
+		s.scenario.Status = worseStatus(s.scenario.Status, auditScenario.Result)
+	}
+	if err != nil {
+		s.scenario.Error = audit.ErrorText(err)
+	}
+}
+
+// auditScenario returns the probe's audit of the scenario, or nil if the probe did not audit it
+func (s *scenarioRecorder) auditScenario() *audit.ScenarioAudit {
+	if s.scenario == nil || s.scenario.AuditScenario == 0 {
+		return nil
+	}
+	return s.probe.Scenario(s.scenario.AuditScenario)
+}
+
+// worseStatus returns the more severe of two statuses. A skipped scenario takes the status of any step that was run.
+func worseStatus(a, b string) string {
+	if a == StatusSkipped {
+		return b
+	}
+	if b == StatusSkipped {
+		return a
+	}
+	return audit.WorseResult(a, b)
+}
+
+// copyScenarios returns a copy of the scenarios recorded so far
+func (r *resultRecorder) copyScenarios() []*ScenarioResult {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	scenarios := []*ScenarioResult{}
+	for _, scenario := range r.scenarios {
+		c := *scenario
+		c.Steps = []*StepResult{}
+		for _, step := range scenario.Steps {
+			s := *step
+			c.Steps = append(c.Steps, &s)
+		}
+		scenarios = append(scenarios, &c)
+	}
+	return scenarios
+}
+
+// Results returns the outcome of each probe in the store, ordered by pack and name. It should be called once the
+// probes have completed, as the result of each probe is read from the summary.
+func (ps *ProbeStore) Results() []*ProbeResult {
+	ps.Lock.RLock()
+	defer ps.Lock.RUnlock()
+
+	results := []*ProbeResult{}
+	for key, probe := range ps.Probes {
+		probeLog := ps.summary.GetProbeLog(key)
+		result := &ProbeResult{
+			Pack:      probe.ProbeDescriptor.Pack,
+			Name:      probe.ProbeDescriptor.Name,
+			Status:    probe.Status.String(),
+			Result:    probeLog.Result,
+			StartedAt: probe.startedAt,
+			Duration:  config.Duration(probe.duration),
+			Error:     probe.err,
+			Scenarios: probe.recorder.copyScenarios(),
+		}
+		if ps.vars.AuditEnabled && probeLog.ScenarioCount() > 0 {
+			result.Audit = probeLog.AuditPath()
+		}
+		results = append(results, result)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Pack.String() != results[j].Pack.String() {
+			return results[i].Pack.String() < results[j].Pack.String()
+		}
+		return results[i].Name < results[j].Name
+	})
+	return results
+}
@@ -0,0 +1,136 @@
+package coreengine
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cucumber/godog"
+	"github.com/cucumber/messages-go/v10"
+
+	"github.com/citihub/probr/audit"
+	"github.com/citihub/probr/config"
+	"github.com/citihub/probr/utils"
+)
+
+func TestScenarioRecorder(t *testing.T) {
+	steps := []*messages.Pickle_PickleStep{
+		{Id: "1", Text: "a step that passes"},
+		{Id: "2", Text: "a step that is pending"},
+		{Id: "3", Text: "a step that is skipped"},
+	}
+	pickle := &messages.Pickle{Name: "A scenario", Steps: steps, Tags: []*messages.Pickle_PickleTag{{Name: "@tag"}}}
+
+	tests := []struct {
+		testName        string
+		run             func(s *scenarioRecorder)
+		expectedStatus  string
+		expectedResults []string
+	}{
+		{
+			testName: "StepPending",
+			run: func(s *scenarioRecorder) {
+				s.beforeStep(steps[0])
+				s.afterStep(steps[0], nil)
+				s.beforeStep(steps[1])
+				s.afterStep(steps[1], godog.ErrPending)
+				s.beforeStep(steps[2]) // Godog runs the BeforeStep hooks of skipped steps
+				s.end(pickle, godog.ErrPending)
+			},
+			expectedStatus:  "Pending",
+			expectedResults: []string{"Passed", "Pending", StatusSkipped},
+		},
+		{
+			testName: "StepUndefined",
+			run: func(s *scenarioRecorder) {
+				s.beforeStep(steps[0])
+				s.afterStep(steps[0], nil)
+				s.beforeStep(steps[1]) // Godog does not run the AfterStep hooks of an undefined step
+				s.beforeStep(steps[2])
+				s.end(pickle, godog.ErrUndefined)
+			},
+			expectedStatus:  StatusUndefined,
+			expectedResults: []string{"Passed", StatusUndefined, StatusSkipped},
+		},
+		{
+			testName: "NoStepRun",
+			run: func(s *scenarioRecorder) {
+				s.end(pickle, nil)
+			},
+			expectedStatus:  StatusSkipped,
+			expectedResults: []string{StatusSkipped, StatusSkipped, StatusSkipped},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			ctx := audit.WithSummary(context.Background(), audit.NewSummary(&config.VarOptions{}))
+			gd := createProbeObj(probeName)
+			s := gd.recorder.newScenarioRecorder(ctx, gd)
+			s.start(pickle)
+			tt.run(s)
+
+			scenarios := gd.recorder.copyScenarios()
+			if len(scenarios) != 1 {
+				t.Fatalf("Expected a single scenario, got %v", scenarios)
+			}
+			if scenarios[0].Status != tt.expectedStatus || len(scenarios[0].Tags) != 1 {
+				t.Errorf("Unexpected scenario result: %+v", scenarios[0])
+			}
+			for i, step := range scenarios[0].Steps {
+				if step.Status != tt.expectedResults[i] {
+					t.Errorf("Step %d status = %v, Expected: %v", i+1, step.Status, tt.expectedResults[i])
+				}
+				if step.Status == StatusSkipped && !step.StartedAt.IsZero() {
+					t.Errorf("Step %d was skipped, so should not have a start time", i+1)
+				}
+			}
+			if tt.expectedStatus == StatusUndefined {
+				// The undefined step is audited as pending, even though the probe did not audit the scenario
+				probeLog := audit.FromContext(ctx).GetProbeLog(gd.ProbeDescriptor.Key())
+				if scenarios[0].AuditScenario != 1 || probeLog.Scenario(1).Result != "Pending" || scenarios[0].Steps[1].AuditStep != 1 {
+					t.Errorf("Undefined step was not audited as pending: %+v", scenarios[0])
+				}
+			}
+		})
+	}
+}
+
+func TestScenarioRecorder_AuditedStep(t *testing.T) {
+	ctx := audit.WithSummary(context.Background(), audit.NewSummary(&config.VarOptions{}))
+	gd := createProbeObj(probeName)
+	step := &messages.Pickle_PickleStep{Id: "1", Text: "a step that is audited"}
+	pickle := &messages.Pickle{Name: "An audited scenario", Steps: []*messages.Pickle_PickleStep{step}}
+
+	s := gd.recorder.newScenarioRecorder(ctx, gd)
+	scenario := audit.FromContext(ctx).GetProbeLog(gd.ProbeDescriptor.Key()).InitializeAuditor(pickle.Name, pickle.Tags)
+	s.start(pickle)
+	s.beforeStep(step)
+	// The step returns no error, but audits its result as inconclusive
+	scenario.AuditStep("step", "a step that is audited", "", nil, utils.InfrastructureError("the cluster could not be reached"))
+	s.afterStep(step, nil)
+	s.end(pickle, nil)
+
+	result := gd.recorder.copyScenarios()[0]
+	if result.AuditScenario != 1 || result.Status != "Inconclusive" {
+		t.Errorf("Unexpected scenario result: %+v", result)
+	}
+	if result.Steps[0].AuditStep != 1 || result.Steps[0].Status != "Inconclusive" || result.Steps[0].Error == "" {
+		t.Errorf("Unexpected step result: %+v", result.Steps[0])
+	}
+}
+
+func TestWorseStatus(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected string
+	}{
+		{StatusSkipped, "Passed", "Passed"},
+		{"Failed", StatusSkipped, "Failed"},
+		{"Passed", "Inconclusive", "Inconclusive"},
+		{"Failed", "Passed", "Failed"},
+	}
+	for _, tt := range tests {
+		if got := worseStatus(tt.a, tt.b); got != tt.expected {
+			t.Errorf("worseStatus(%v, %v) = %v, Expected: %v", tt.a, tt.b, got, tt.expected)
+		}
+	}
+}
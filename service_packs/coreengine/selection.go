@@ -0,0 +1,98 @@
+package coreengine
+
+import (
+	"strings"
+
+	"github.com/citihub/probr/config"
+	"github.com/citihub/probr/utils"
+)
+
+// SelectProbes restricts a run to the service packs, probes and scenarios named by the selections,
+// each of which takes one of the following forms. If there are no selections, everything is selected.
+//
+//	PACK               e.g. kubernetes
+//	PACK/PROBE         e.g. kubernetes/iam
+//	PACK/PROBE/@TAG    e.g. kubernetes/iam/@k-iam-001
+//	PACK/@TAG          every probe within the pack that has a scenario with the tag
+//	@TAG               every probe within any pack that has a scenario with the tag
+//
+// A tag may also be an alias, such as @standard/cis/5.2, in which case the scenarios with any of
+// the tags that it stands for are selected.
+//
+// The selections are stored in the Meta of the config. They are validated against the registered packs,
+// so plugins must be loaded first.
+func SelectProbes(vars *config.VarOptions, selections []string) error {
+	var selected []config.Selection
+	for _, s := range selections {
+		resolved, err := resolveSelection(s)
+		if err != nil {
+			return err
+		}
+		selected = append(selected, resolved...)
+	}
+	vars.Meta.Selections = selected
+	return nil
+}
+
+func resolveSelection(s string) ([]config.Selection, error) {
+	if strings.HasPrefix(s, "@") {
+		return selectTag(GetServicePacks(), strings.TrimPrefix(s, "@"))
+	}
+	parts := strings.SplitN(s, "/", 3) // Tags may themselves contain "/"
+	pack, err := GetServicePack(parts[0])
+	if err != nil {
+		return nil, utils.ReformatError("Unknown service pack '%s'. Must be one of %v", parts[0], config.GetPacks())
+	}
+	if len(parts) == 1 {
+		return []config.Selection{{Pack: pack.Name}}, nil
+	}
+	if strings.HasPrefix(parts[1], "@") {
+		return selectTag([]ServicePack{pack}, strings.TrimPrefix(strings.Join(parts[1:], "/"), "@"))
+	}
+
+	if _, found := utils.FindString(pack.probeNames(), parts[1]); !found {
+		return nil, utils.ReformatError("Unknown probe '%s' in service pack '%s'. Must be one of %v", parts[1], pack.Name, pack.probeNames())
+	}
+	selection := config.Selection{Pack: pack.Name, Probe: parts[1]}
+	if len(parts) == 3 {
+		if !strings.HasPrefix(parts[2], "@") {
+			return nil, utils.ReformatError("Invalid selection '%s'. Scenarios must be selected by tag, e.g. '%s/%s/@<TAG>'", s, pack.Name, parts[1])
+		}
+		selection.Tag = strings.TrimPrefix(parts[2], "@")
+	}
+	return []config.Selection{selection}, nil
+}
+
+// selectTag returns a selection for each probe within the packs that has a scenario with the tag,
+// or with any of the tags that it stands for if it is an alias
+func selectTag(packs []ServicePack, tag string) ([]config.Selection, error) {
+	var selections []config.Selection
+	tags := config.ExpandTag(tag)
+	for _, pack := range packs {
+		for _, probe := range pack.allProbes() {
+			feature, err := ReadProbeFeature(probe)
+			if err != nil {
+				continue // Reported when the probe is run or planned
+			}
+			for _, scenario := range feature.Scenarios {
+				if hasAnyTag(scenario.Tags, tags) {
+					selections = append(selections, config.Selection{Pack: pack.Name, Probe: probe.Name(), Tag: tag})
+					break
+				}
+			}
+		}
+	}
+	if len(selections) == 0 {
+		return nil, utils.ReformatError("No scenarios were found with the tag '@%s'", tag)
+	}
+	return selections, nil
+}
+
+func hasAnyTag(tags []string, any []string) bool {
+	for _, tag := range any {
+		if hasTag(tags, tag) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,74 @@
+package coreengine
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/citihub/probr/config"
+)
+
+func TestSelectProbes(t *testing.T) {
+	tests := []struct {
+		testName    string
+		selections  []string
+		expected    []config.Selection
+		expectError bool
+	}{
+		{testName: "NoSelections", selections: nil, expected: nil},
+		{testName: "Pack", selections: []string{"Fake_Pack"}, expected: []config.Selection{{Pack: "fake_pack"}}},
+		{testName: "Probe", selections: []string{"fake_pack/aws_probe_1"}, expected: []config.Selection{{Pack: "fake_pack", Probe: "aws_probe_1"}}},
+		{testName: "ProbeOfOtherProvider", selections: []string{"fake_pack/azure_probe"}, expected: []config.Selection{{Pack: "fake_pack", Probe: "azure_probe"}}},
+		{testName: "Scenario", selections: []string{"fake_pack/aws_probe_1/@k-fake/001"}, expected: []config.Selection{{Pack: "fake_pack", Probe: "aws_probe_1", Tag: "k-fake/001"}}},
+		{testName: "ScenarioWithoutTag", selections: []string{"fake_pack/aws_probe_1/k-fake-001"}, expectError: true},
+		{testName: "UnknownPack", selections: []string{"fake_pack", "other_pack"}, expectError: true},
+		{testName: "UnknownProbe", selections: []string{"fake_pack/gcp_probe"}, expectError: true},
+		{testName: "UnknownTag", selections: []string{"@k-fake-999"}, expectError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			var vars config.VarOptions
+			err := SelectProbes(&vars, tt.selections)
+			if (err != nil) != tt.expectError {
+				t.Fatalf("SelectProbes(%v) error = %v, expected error: %v", tt.selections, err, tt.expectError)
+			}
+			selected := vars.Meta.Selections
+			if !tt.expectError && len(selected) != len(tt.expected) {
+				t.Fatalf("SelectProbes(%v) selected %v, expected %v", tt.selections, selected, tt.expected)
+			}
+			for i := range tt.expected {
+				if selected[i] != tt.expected[i] {
+					t.Errorf("SelectProbes(%v) selected %v, expected %v", tt.selections, selected, tt.expected)
+				}
+			}
+		})
+	}
+}
+
+func TestSelectTag(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "probr-select")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "fake_probe.feature")
+	ioutil.WriteFile(path, []byte(testFeature), 0644)
+	pack := ServicePack{Name: "fake_pack", Probes: map[string][]Probe{
+		"": {featureProbe{fakeProbe: fakeProbe{name: "fake_probe"}, path: path}, fakeProbe{name: "no_feature"}},
+	}}
+
+	selections, err := selectTag([]ServicePack{pack}, "fake-002")
+	if err != nil || len(selections) != 1 {
+		t.Fatalf("selectTag() = %v, %v, expected a single selection", selections, err)
+	}
+	if expected := (config.Selection{Pack: "fake_pack", Probe: "fake_probe", Tag: "fake-002"}); selections[0] != expected {
+		t.Errorf("selectTag() = %v, expected %v", selections[0], expected)
+	}
+	if _, err := selectTag([]ServicePack{pack}, "fake-003"); err == nil {
+		t.Errorf("Expected an error for a tag that no scenario has")
+	}
+
+	config.RegisterTagAliases(map[string][]string{"@standard/fake/1.1": {"@fake-002"}})
+	selections, err = selectTag([]ServicePack{pack}, "standard/fake")
+	if err != nil || len(selections) != 1 || selections[0].Tag != "standard/fake" {
+		t.Errorf("selectTag() = %v, %v, expected the probe to be selected by the alias of its scenario", selections, err)
+	}
+}
@@ -0,0 +1,161 @@
+package coreengine
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/citihub/probr/config"
+)
+
+// ServicePack describes a collection of probes, along with the config and tags required to run them.
+// A pack makes itself available to probr by calling RegisterServicePack from an init() function,
+// so packs maintained outside of this module only need to be imported by the binary that runs them.
+type ServicePack struct {
+	Name     string                          // Used by 'probr run <PACK>', show-requirements and config exclusions
+	Config   config.PackConfig               // Required vars and the location of the pack's settings within VarOptions
+	Provider func(*config.VarOptions) string // Returns the configured provider variant, or nil if the pack has no variants
+	Probes   map[string][]Probe              // Probes for each provider variant. Packs without variants use the "" key.
+	Tags     map[string][]string             // Tag aliases, e.g. "@standard/cis/5.2.5", expanded in the tag filter of every pack
+}
+
+var (
+	packs     = make(map[string]ServicePack)
+	packsLock sync.RWMutex
+)
+
+// RegisterServicePack makes a service pack available to probr. It panics if the
+// name is empty or a pack with the same name has already been registered.
+func RegisterServicePack(pack ServicePack) {
+	packsLock.Lock()
+	defer packsLock.Unlock()
+
+	name := strings.ToLower(pack.Name)
+	if name == "" {
+		panic("coreengine: RegisterServicePack called without a pack name")
+	}
+	if _, exists := packs[name]; exists {
+		panic(fmt.Sprintf("coreengine: RegisterServicePack called twice for pack '%s'", name))
+	}
+	pack.Name = name
+	packs[name] = pack
+	config.RegisterPackConfig(name, pack.Config)
+	config.RegisterTagAliases(pack.Tags)
+}
+
+// UnregisterServicePack removes a registered service pack, such as one provided by a plugin that has been
+// shut down. Its tag aliases remain registered, as they may be shared with other packs.
+func UnregisterServicePack(name string) {
+	packsLock.Lock()
+	defer packsLock.Unlock()
+
+	name = strings.ToLower(name)
+	delete(packs, name)
+	config.UnregisterPackConfig(name)
+}
+
+// GetServicePacks returns all registered service packs, sorted by name
+func GetServicePacks() []ServicePack {
+	packsLock.RLock()
+	defer packsLock.RUnlock()
+
+	var registered []ServicePack
+	for _, pack := range packs {
+		registered = append(registered, pack)
+	}
+	sort.Slice(registered, func(i, j int) bool { return registered[i].Name < registered[j].Name })
+	return registered
+}
+
+// GetServicePack returns the registered service pack with the provided name
+func GetServicePack(name string) (ServicePack, error) {
+	packsLock.RLock()
+	defer packsLock.RUnlock()
+
+	pack, exists := packs[strings.ToLower(name)]
+	if !exists {
+		return pack, fmt.Errorf("service pack '%s' has not been registered", name)
+	}
+	return pack, nil
+}
+
+// Identity returns the pack name along with the configured provider, if the pack has provider variants
+func (pack ServicePack) Identity(vars *config.VarOptions) PackIdentity {
+	id := PackIdentity{Name: pack.Name}
+	if pack.Provider != nil {
+		id.Provider = strings.ToLower(pack.Provider(vars))
+	}
+	return id
+}
+
+// GetProbes returns the selected probes that should be run for the configured provider, or nil if the pack is excluded.
+// The probe and scenario exclusions from the vars file are added to the tag filter of the config.
+func (pack ServicePack) GetProbes(vars *config.VarOptions) []Probe {
+	if vars.PackIsExcluded(pack.Name) {
+		return nil
+	}
+	pack.applyExclusions(vars)
+	probes, supported := pack.providerProbes(vars)
+	if !supported {
+		log.Printf("[WARN] Ignoring %s service pack due to unsupported provider '%s'", pack.Name, pack.Provider(vars))
+	}
+	var selected []Probe
+	for _, probe := range probes {
+		if vars.ProbeIsSelected(pack.Name, probe.Name()) {
+			selected = append(selected, probe)
+		}
+	}
+	return selected
+}
+
+// ExclusionReason returns the reason the pack will not be run, or "" if it will be
+func (pack ServicePack) ExclusionReason(vars *config.VarOptions) string {
+	if reason := vars.PackExclusionReason(pack.Name); reason != "" {
+		return reason
+	}
+	if _, supported := pack.providerProbes(vars); !supported {
+		return fmt.Sprintf("unsupported provider '%s'", pack.Provider(vars))
+	}
+	return ""
+}
+
+// providerProbes returns the probes for the configured provider, and whether the provider is supported by the pack
+func (pack ServicePack) providerProbes(vars *config.VarOptions) ([]Probe, bool) {
+	if pack.Provider == nil {
+		return pack.Probes[""], true
+	}
+	provider := pack.Provider(vars)
+	for name, probes := range pack.Probes {
+		if strings.EqualFold(name, provider) {
+			return probes, true
+		}
+	}
+	return nil, false
+}
+
+// allProbes returns the probes of every provider variant, without duplicates
+func (pack ServicePack) allProbes() []Probe {
+	var all []Probe
+	seen := make(map[string]bool)
+	for _, probes := range pack.Probes {
+		for _, probe := range probes {
+			if !seen[probe.Name()] {
+				seen[probe.Name()] = true
+				all = append(all, probe)
+			}
+		}
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Name() < all[j].Name() })
+	return all
+}
+
+// probeNames returns the sorted names of the probes of every provider variant
+func (pack ServicePack) probeNames() []string {
+	var names []string
+	for _, probe := range pack.allProbes() {
+		names = append(names, probe.Name())
+	}
+	return names
+}
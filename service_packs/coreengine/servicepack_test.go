@@ -0,0 +1,124 @@
+package coreengine
+
+import (
+	"context"
+	"testing"
+
+	"github.com/citihub/probr/config"
+	"github.com/cucumber/godog"
+)
+
+type fakeProbe struct{ name string }
+
+func (p fakeProbe) ProbeInitialize(ctx *godog.TestSuiteContext)                       {}
+func (p fakeProbe) ScenarioInitialize(ctx context.Context, sc *godog.ScenarioContext) {}
+func (p fakeProbe) Name() string                                                      { return p.name }
+func (p fakeProbe) Path() string                                                      { return "" }
+
+type fakePackSettings struct {
+	Provider string
+}
+
+var fakePackProvider string
+
+func init() {
+	RegisterServicePack(ServicePack{
+		Name: "Fake_Pack",
+		Config: config.PackConfig{
+			RequiredVars: []string{"Provider"},
+			Settings:     func(v *config.VarOptions) interface{} { return fakePackSettings{Provider: fakePackProvider} },
+		},
+		Provider: func(*config.VarOptions) string { return fakePackProvider },
+		Probes: map[string][]Probe{
+			"Azure": {fakeProbe{name: "azure_probe"}},
+			"AWS":   {fakeProbe{name: "aws_probe_1"}, fakeProbe{name: "aws_probe_2"}},
+		},
+	})
+}
+
+func TestGetServicePack(t *testing.T) {
+	pack, err := GetServicePack("fake_pack")
+	if err != nil {
+		t.Fatalf("Registered pack not found: %v", err)
+	}
+	if pack.Name != "fake_pack" {
+		t.Errorf("Pack name was not normalised, got '%s'", pack.Name)
+	}
+	if _, err := GetServicePack("unregistered_pack"); err == nil {
+		t.Errorf("Expected an error for an unregistered pack")
+	}
+	if len(config.RequiredVars("fake_pack")) != 1 {
+		t.Errorf("Pack requirements were not registered with config")
+	}
+}
+
+func TestRegisterServicePack_Duplicate(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected a panic when registering a duplicate pack")
+		}
+	}()
+	RegisterServicePack(ServicePack{Name: "fake_pack"})
+}
+
+func TestUnregisterServicePack(t *testing.T) {
+	RegisterServicePack(ServicePack{Name: "Unregistered_Pack"})
+	UnregisterServicePack("Unregistered_Pack")
+	if _, err := GetServicePack("unregistered_pack"); err == nil {
+		t.Errorf("Expected the pack to be unregistered")
+	}
+	for _, name := range config.GetPacks() {
+		if name == "unregistered_pack" {
+			t.Errorf("Expected the pack's config to be unregistered")
+		}
+	}
+	RegisterServicePack(ServicePack{Name: "unregistered_pack"}) // The name may be registered again
+	UnregisterServicePack("unregistered_pack")
+}
+
+func TestServicePack_GetProbes(t *testing.T) {
+	pack, _ := GetServicePack("fake_pack")
+	tests := []struct {
+		testName      string
+		provider      string
+		selections    []config.Selection
+		expectedCount int
+	}{
+		{testName: "RequiredVarMissing", provider: "", expectedCount: 0},
+		{testName: "UnsupportedProvider", provider: "GCP", expectedCount: 0},
+		{testName: "Azure", provider: "Azure", expectedCount: 1},
+		{testName: "ProviderCaseInsensitive", provider: "aws", expectedCount: 2},
+		{testName: "ProbeSelected", provider: "AWS", selections: []config.Selection{{Pack: "fake_pack", Probe: "aws_probe_2"}}, expectedCount: 1},
+		{testName: "OtherPackSelected", provider: "AWS", selections: []config.Selection{{Pack: "other_pack"}}, expectedCount: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			fakePackProvider = tt.provider
+			vars := config.VarOptions{Meta: config.Meta{Selections: tt.selections}}
+			if probes := pack.GetProbes(&vars); len(probes) != tt.expectedCount {
+				t.Errorf("GetProbes() returned %v probes, want %v", len(probes), tt.expectedCount)
+			}
+		})
+	}
+}
+
+func TestServicePack_ExclusionReason(t *testing.T) {
+	pack, _ := GetServicePack("fake_pack")
+	tests := []struct {
+		testName string
+		provider string
+		expected string
+	}{
+		{testName: "RequiredVarMissing", provider: "", expected: "required var 'Provider' not being present"},
+		{testName: "UnsupportedProvider", provider: "GCP", expected: "unsupported provider 'GCP'"},
+		{testName: "Included", provider: "Azure", expected: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			fakePackProvider = tt.provider
+			if reason := pack.ExclusionReason(&config.VarOptions{}); reason != tt.expected {
+				t.Errorf("ExclusionReason() = '%s', want '%s'", reason, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package coreengine_test
+
+import (
+	"strings"
+	"testing"
+
+	_ "github.com/citihub/probr/service_packs/apim"
+	"github.com/citihub/probr/service_packs/coreengine"
+	_ "github.com/citihub/probr/service_packs/kubernetes"
+	_ "github.com/citihub/probr/service_packs/storage"
+)
+
+// TestPackTags checks that the tag aliases of every registered pack refer to scenarios that the pack declares
+func TestPackTags(t *testing.T) {
+	for _, pack := range coreengine.GetServicePacks() {
+		if len(pack.Tags) == 0 {
+			continue
+		}
+		t.Run(pack.Name, func(t *testing.T) {
+			declared := make(map[string]bool)
+			for _, probes := range pack.Probes {
+				for _, probe := range probes {
+					feature, err := coreengine.ReadProbeFeature(probe)
+					if err != nil {
+						t.Fatalf("Unable to read the feature of probe '%s': %v", probe.Name(), err)
+					}
+					for _, scenario := range feature.Scenarios {
+						for _, tag := range scenario.Tags {
+							declared[tag] = true
+						}
+					}
+				}
+			}
+			for alias, targets := range pack.Tags {
+				for _, tag := range targets {
+					if !strings.HasPrefix(tag, "@") || !declared[tag] {
+						t.Errorf("Alias '%s' refers to '%s', which is not a tag of any scenario in the pack", alias, tag)
+					}
+				}
+			}
+		})
+	}
+}
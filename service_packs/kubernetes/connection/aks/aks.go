@@ -2,12 +2,21 @@
 package aks
 
 import (
+	"context"
 	"log"
 
 	aibv1 "github.com/Azure/aad-pod-identity/pkg/apis/aadpodidentity"
+	"github.com/citihub/probr/ledger"
 	"github.com/citihub/probr/service_packs/kubernetes/connection"
 )
 
+// aibResourceType is the custom resource type of AzureIdentityBindings, which are recorded in the ledger as "kubernetes/azureidentitybindings"
+const aibResourceType = "azureidentitybindings"
+
+func init() {
+	ledger.RegisterDeleter("kubernetes/"+aibResourceType, connection.DeleteRecorded)
+}
+
 // AKS implements the Azure Kubernetes Service wrapper
 type AKS struct {
 	conn connection.Connection
@@ -26,8 +35,9 @@ func NewAKS(connection connection.Connection) *AKS {
 	return aks
 }
 
-// CreateAIB creates an AzureIdentityBinding in the cluster, 409 error if it already exists
-func (aks *AKS) CreateAIB(namespace, aibName, aiName string) (resource connection.APIResource, err error) {
+// CreateAIB creates an AzureIdentityBinding in the cluster, 409 error if it already exists.
+// The binding is recorded in the ledger until it is deleted by DeleteAIB.
+func (aks *AKS) CreateAIB(ctx context.Context, namespace, aibName, aiName string) (resource connection.APIResource, err error) {
 
 	aib := aibv1.AzureIdentityBinding{}
 
@@ -43,34 +53,39 @@ func (aks *AKS) CreateAIB(namespace, aibName, aiName string) (resource connectio
 	// set the api path for the aadpodidentity package which include the azureidentitybindings custom resource definition
 	apiPath := "apis/aadpodidentity.k8s.io/v1"
 
-	resource, err = aks.conn.PostRawResource(apiPath, namespace, "azureidentitybindings", runtimeAib)
+	resource, err = aks.conn.PostRawResource(ctx, apiPath, namespace, aibResourceType, runtimeAib)
 	log.Printf("[DEBUG] Identity binding resource posted: %v", resource)
 
 	return
 }
 
+// DeleteAIB deletes an AzureIdentityBinding from the cluster, 404 error if it does not exist
+func (aks *AKS) DeleteAIB(ctx context.Context, namespace, aibName string) error {
+	return aks.conn.DeleteRawResource(ctx, "apis/aadpodidentity.k8s.io/v1", namespace, aibResourceType, aibName)
+}
+
 // GetIdentityByNameAndNamespace queries cluster and returns resource, 404 error if not found
-func (aks *AKS) GetIdentityByNameAndNamespace(azureIdentityName, namespace string) (resource connection.APIResource, err error) {
+func (aks *AKS) GetIdentityByNameAndNamespace(ctx context.Context, azureIdentityName, namespace string) (resource connection.APIResource, err error) {
 	// Azure Identities are implemented as K8s Custom Resource Definition
 	// Need to make a 'raw' call to the corresponding K8s endpoint
 	// The K8s api endpoint for Azure Indentity is: 		"apis/aadpodidentity.k8s.io/v1/azureidentities"
 	apiEndPoint := "apis/aadpodidentity.k8s.io/v1"
 	resourceType := "azureidentities"
 
-	resource, err = aks.conn.GetRawResourceByName(apiEndPoint, namespace, resourceType, azureIdentityName)
+	resource, err = aks.conn.GetRawResourceByName(ctx, apiEndPoint, namespace, resourceType, azureIdentityName)
 
 	return
 }
 
 // GetIdentityBindingByNameAndNamespace queries cluster and returns resource, 404 eror if not found
-func (aks *AKS) GetIdentityBindingByNameAndNamespace(azureIdentityBindingName, namespace string) (resource connection.APIResource, err error) {
+func (aks *AKS) GetIdentityBindingByNameAndNamespace(ctx context.Context, azureIdentityBindingName, namespace string) (resource connection.APIResource, err error) {
 	// Azure Identity Bindings are implemented as K8s Custom Resource Definition
 	// Need to make a 'raw' call to the corresponding K8s endpoint
 	// The K8s api endpoint for Azure Indentity Binding is:	"apis/aadpodidentity.k8s.io/v1/azureidentitybindings"
 	apiEndPoint := "apis/aadpodidentity.k8s.io/v1"
 	resourceType := "azureidentitybindings"
 
-	resource, err = aks.conn.GetRawResourceByName(apiEndPoint, namespace, resourceType, azureIdentityBindingName)
+	resource, err = aks.conn.GetRawResourceByName(ctx, apiEndPoint, namespace, resourceType, azureIdentityBindingName)
 
 	return
 }
@@ -11,13 +11,14 @@ import (
 	"sync"
 	"time"
 
-	"github.com/citihub/probr/audit"
 	"github.com/citihub/probr/config"
+	"github.com/citihub/probr/ledger"
 	"github.com/citihub/probr/service_packs/kubernetes/errors"
 	"github.com/citihub/probr/utils"
 	apiv1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/kubernetes"
 	_ "k8s.io/client-go/plugin/pkg/client/auth" // required
 	"k8s.io/client-go/rest"
@@ -29,22 +30,36 @@ import (
 
 // Conn simplifies the kubernetes API connection
 type Conn struct {
+	settings          settings
 	clientSet         *kubernetes.Clientset
 	clientConfig      *rest.Config
-	clusterIsDeployed error
+	clusterIsDeployed error     // set once the client has been created
+	bootstrap         sync.Once // creates the probe namespace on first use by Get
+	namespaceErr      error
 }
 
-// Connection should be used instead of Conn within probes to allow mocking during testing
+// settings are the config vars that a connection is made with. Runs with the same settings share a connection.
+type settings struct {
+	kubeConfigPath string
+	kubeContext    string
+	probeNamespace string
+	podWaitTimeout time.Duration
+	execTimeout    time.Duration
+}
+
+// Connection should be used instead of Conn within probes to allow mocking during testing.
+// Each request is bounded by a short timeout, and is also cancelled if the provided context is done.
 type Connection interface {
 	ClusterIsDeployed() error
-	CreatePodFromObject(pod *apiv1.Pod, probeName string) (*apiv1.Pod, error)
-	DeletePodIfExists(podName, namespace, probeName string) error
-	ExecCommand(command, namespace, podName string) (status int, stdout string, stderr string, err error)
-	GetPodsByNamespace(namespace string) (*apiv1.PodList, error)
-	GetPodIPs(namespace, podName string) (string, string, error)
-	GetRawResourceByName(apiEndPoint, namespace, resourceType, resourceName string) (resource APIResource, err error)
-	PostRawResource(apiEndPoint string, namespace string, resourceName string, resourceBody interface{}) (resource APIResource, err error)
-	WaitForPod(namespace string, podName string) (err error)
+	CreatePodFromObject(ctx context.Context, pod *apiv1.Pod) (*apiv1.Pod, error)
+	DeletePodIfExists(ctx context.Context, podName, namespace string) error
+	ExecCommand(ctx context.Context, command, namespace, podName string) (status int, stdout string, stderr string, err error)
+	GetPodsByNamespace(ctx context.Context, namespace string) (*apiv1.PodList, error)
+	GetPodIPs(ctx context.Context, namespace, podName string) (string, string, error)
+	GetRawResourceByName(ctx context.Context, apiEndPoint, namespace, resourceType, resourceName string) (resource APIResource, err error)
+	PostRawResource(ctx context.Context, apiEndPoint string, namespace string, resourceName string, resourceBody interface{}) (resource APIResource, err error)
+	DeleteRawResource(ctx context.Context, apiEndPoint, namespace, resourceType, resourceName string) error
+	WaitForPod(ctx context.Context, namespace string, podName string) (err error)
 }
 
 // APIResource encapsulates the response from a raw/rest call to the Kubernetes API when getting a resource by name
@@ -54,36 +69,70 @@ type APIResource struct {
 	Metadata   map[string]string
 }
 
-var instance *Conn
-var once sync.Once
-
-// Get retrieves the connection object. Instantiates the connection if necessary
-func Get() *Conn {
-	once.Do(func() {
-		instance = &Conn{}
-		instance.setClientConfig()
-		instance.setClientSet()
-		instance.bootstrapDefaultNamespace()
-	})
-	return instance
+var (
+	connections     = make(map[settings]*Conn)
+	connectionsLock sync.Mutex
+)
+
+// KindPod is the kind of the pods recorded in the ledger. Raw resources are recorded as "kubernetes/<resourceType>".
+const KindPod = "kubernetes/pod"
+
+func init() {
+	ledger.RegisterDeleter(KindPod, DeleteRecorded)
+}
+
+// Get retrieves the connection for the provided kubernetes config vars. Instantiates the connection and
+// creates the probe namespace if necessary
+func Get(vars *config.Kubernetes) *Conn {
+	connection := connect(vars)
+	connection.bootstrap.Do(connection.bootstrapDefaultNamespace)
+	return connection
+}
+
+// connect retrieves or instantiates the connection for the provided config vars, without creating the probe namespace
+func connect(vars *config.Kubernetes) *Conn {
+	s := settings{
+		kubeConfigPath: vars.KubeConfigPath,
+		kubeContext:    vars.KubeContext,
+		probeNamespace: vars.ProbeNamespace,
+		podWaitTimeout: time.Duration(vars.PodWaitTimeout),
+		execTimeout:    time.Duration(vars.ExecTimeout),
+	}
+
+	connectionsLock.Lock()
+	defer connectionsLock.Unlock()
+	if connection, found := connections[s]; found {
+		return connection
+	}
+	connection := &Conn{settings: s}
+	connection.setClientConfig()
+	connection.setClientSet()
+	connections[s] = connection
+	return connection
 }
 
 // ClusterIsDeployed verifies that the connection instantiation did not report a failure at any point
 func (connection *Conn) ClusterIsDeployed() error {
-	return connection.clusterIsDeployed
+	if connection.clusterIsDeployed != nil {
+		return connection.clusterIsDeployed
+	}
+	return connection.namespaceErr
 }
 
 func (connection *Conn) setClientSet() {
+	if connection.clientConfig == nil {
+		return // The config could not be loaded, which is reported by ClusterIsDeployed
+	}
 	var err error
 	connection.clientSet, err = kubernetes.NewForConfig(connection.clientConfig)
 	if err != nil {
-		connection.clusterIsDeployed = utils.ReformatError("Failed to create Kubernetes client set: %v", err)
+		connection.clusterIsDeployed = utils.InfrastructureError("Failed to create Kubernetes client set: %v", err)
 	}
 }
 
 // GetOrCreateNamespace will retrieve or create a namespace within the current Kubernetes cluster
-func (connection *Conn) GetOrCreateNamespace(namespace string) (*apiv1.Namespace, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+func (connection *Conn) GetOrCreateNamespace(ctx context.Context, namespace string) (*apiv1.Namespace, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
 	namespaceObject := apiv1.Namespace{
@@ -100,7 +149,7 @@ func (connection *Conn) GetOrCreateNamespace(namespace string) (*apiv1.Namespace
 			//return it and nil out the err
 			return createdNamespace, nil
 		}
-		return nil, err
+		return nil, apiError(err, "Could not create namespace '%s'", namespace)
 	}
 
 	log.Printf("[INFO] Namespace %q created.", createdNamespace.GetObjectMeta().GetName())
@@ -109,7 +158,8 @@ func (connection *Conn) GetOrCreateNamespace(namespace string) (*apiv1.Namespace
 }
 
 // CreatePodFromObject creates a pod from the supplied pod object within an existing namespace
-func (connection *Conn) CreatePodFromObject(pod *apiv1.Pod, probeName string) (*apiv1.Pod, error) {
+// Probes should count the pods that they create in their audit.
+func (connection *Conn) CreatePodFromObject(ctx context.Context, pod *apiv1.Pod) (*apiv1.Pod, error) {
 	podName := pod.ObjectMeta.Name
 	namespace := pod.ObjectMeta.Namespace
 
@@ -124,48 +174,55 @@ func (connection *Conn) CreatePodFromObject(pod *apiv1.Pod, probeName string) (*
 
 	podsClient := c.CoreV1().Pods(namespace)
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	createCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
-	res, err := podsClient.Create(ctx, pod, metav1.CreateOptions{})
+	res, err := podsClient.Create(createCtx, pod, metav1.CreateOptions{})
+	err = apiError(err, "Could not create pod '%s'", podName)
 	if err == nil {
-		err = connection.WaitForPod(namespace, podName)
+		ledger.Record(ctx, connection.ledgerResource(KindPod, namespace, podName, ""))
+		err = connection.WaitForPod(ctx, namespace, podName)
 	}
 	if err != nil {
 		log.Printf("[INFO] Attempt to create pod '%v' failed with error: '%v'", podName, err)
 	} else {
 		log.Printf("[INFO] Attempt to create pod '%v' succeeded", podName)
-		audit.State.GetProbeLog(probeName).CountPodCreated(podName)
 	}
 	return res, err
 }
 
-// DeletePodIfExists deletes the given pod in the specified namespace.
-func (connection *Conn) DeletePodIfExists(podName, namespace, probeName string) error {
+// DeletePodIfExists deletes the given pod in the specified namespace, and removes it from the ledger.
+func (connection *Conn) DeletePodIfExists(ctx context.Context, podName, namespace string) error {
 	clientSet, _ := kubernetes.NewForConfig(connection.clientConfig)
 	podsClient := clientSet.CoreV1().Pods(namespace)
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	deleteCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
 	log.Printf("[DEBUG] Attempting to delete pod: %s", podName)
 
-	err := podsClient.Delete(ctx, podName, metav1.DeleteOptions{})
+	err := podsClient.Delete(deleteCtx, podName, metav1.DeleteOptions{})
+	if err != nil && !errors.IsStatusCode(404, err) {
+		return apiError(err, "Could not delete pod '%s'", podName)
+	}
+	ledger.Remove(ctx, connection.ledgerResource(KindPod, namespace, podName, ""))
 	if err != nil {
 		return err
 	}
-	audit.State.GetProbeLog(probeName).CountPodDestroyed()
 	log.Printf("[INFO] POD %s deleted.", podName)
 	return nil
 }
 
 // ExecCommand executes the supplied command on the given pod name in the specified namespace.
-func (connection *Conn) ExecCommand(cmd, namespace, podName string) (exitCode int, stdout string, stderr string, err error) {
+func (connection *Conn) ExecCommand(ctx context.Context, cmd, namespace, podName string) (exitCode int, stdout string, stderr string, err error) {
 	exitCode = 0
 	if cmd == "" {
 		err = utils.ReformatError("Command string not provided to ExecCommand")
 		return
 	}
-	connection.WaitForPod(namespace, podName)
+	connection.WaitForPod(ctx, namespace, podName)
+	if err = ctx.Err(); err != nil {
+		return
+	}
 
 	log.Printf("[DEBUG] Executing command: \"%s\" on POD '%s' in namespace '%s'", cmd, podName, namespace)
 	request := connection.clientSet.CoreV1().RESTClient().Post().Resource("pods").
@@ -189,19 +246,34 @@ func (connection *Conn) ExecCommand(cmd, namespace, podName string) (exitCode in
 	request.VersionedParams(&options, parameterCodec)
 
 	log.Printf("[DEBUG] %s.%s: ExecCommand Request URL: %v", utils.CallerName(2), utils.CallerName(1), request.URL().String())
-	config, err := clientcmd.BuildConfigFromFlags("", config.Vars.ServicePacks.Kubernetes.KubeConfigPath)
-	exec, err := remotecommand.NewSPDYExecutor(config, "POST", request.URL())
+	timeout := connection.settings.execTimeout
+	exec, err := remotecommand.NewSPDYExecutor(connection.clientConfig, "POST", request.URL())
 	if err != nil {
-		err = utils.ReformatError("Failed to create Executor: %v", err)
+		err = utils.InfrastructureError("Failed to create Executor: %w", err)
 		return
 	}
 
 	var stdoutBuffer, stderrBuffer bytes.Buffer
-	err = exec.Stream(remotecommand.StreamOptions{
-		Stdout: &stdoutBuffer,
-		Stderr: &stderrBuffer,
-		Tty:    false,
-	})
+	streamed := make(chan error, 1)
+	go func() {
+		streamed <- exec.Stream(remotecommand.StreamOptions{
+			Stdout: &stdoutBuffer,
+			Stderr: &stderrBuffer,
+			Tty:    false,
+		})
+	}()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	select {
+	case err = <-streamed:
+	case <-ctx.Done():
+		// The stream cannot be cancelled, so it is abandoned and the buffers are not read
+		err = utils.InfrastructureError("Command \"%s\" on POD '%s' did not complete within %v", cmd, podName, timeout)
+		return
+	}
 	stdout = stdoutBuffer.String()
 	stderr = stderrBuffer.String()
 	if err != nil {
@@ -213,7 +285,7 @@ func (connection *Conn) ExecCommand(cmd, namespace, podName string) (exitCode in
 			return
 		}
 		// Internal error
-		err = utils.ReformatError("Issue in Stream: %v", err)
+		err = apiError(err, "Issue in Stream")
 	}
 	if strings.Contains(stdout, "command not found") {
 		err = utils.ReformatError("Step failed due to command '%s' not being available within the probe")
@@ -222,41 +294,42 @@ func (connection *Conn) ExecCommand(cmd, namespace, podName string) (exitCode in
 }
 
 // GetNamespace returns a particular namespace object for a given name
-func (connection *Conn) GetNamespace(namespace string) (*apiv1.Namespace, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+func (connection *Conn) GetNamespace(ctx context.Context, namespace string) (*apiv1.Namespace, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
 	namespaceObj, err := connection.clientSet.CoreV1().Namespaces().Get(
 		ctx, namespace, metav1.GetOptions{})
 
-	return namespaceObj, err
+	return namespaceObj, apiError(err, "Could not get namespace '%s'", namespace)
 }
 
 // GetPodsByNamespace returns list of pods within specified namespace
-func (connection *Conn) GetPodsByNamespace(namespace string) (*apiv1.PodList, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+func (connection *Conn) GetPodsByNamespace(ctx context.Context, namespace string) (*apiv1.PodList, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
 	// Validate namespace exists and is valid
-	namespaceObj, getNamespaceErr := connection.GetNamespace(namespace)
+	namespaceObj, getNamespaceErr := connection.GetNamespace(ctx, namespace)
 	if getNamespaceErr != nil {
-		return nil, utils.ReformatError("Error returning provided namespace: %v", getNamespaceErr)
+		return nil, utils.ReformatError("Error returning provided namespace: %w", getNamespaceErr)
 	}
 
 	pods, err := connection.clientSet.CoreV1().Pods(namespaceObj.Name).List(ctx, metav1.ListOptions{})
 
-	return pods, err
+	return pods, apiError(err, "Could not list pods in namespace '%s'", namespace)
 }
 
 // GetPodIPs will retrieve a pod by name and return its IP and its host's IP
-func (connection *Conn) GetPodIPs(namespace, podName string) (podIP string, hostIP string, err error) {
-	connection.WaitForPod(namespace, podName)
+func (connection *Conn) GetPodIPs(ctx context.Context, namespace, podName string) (podIP string, hostIP string, err error) {
+	connection.WaitForPod(ctx, namespace, podName)
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
 	pod, err := connection.clientSet.CoreV1().Pods(namespace).Get(ctx, podName, metav1.GetOptions{})
 	if err != nil {
+		err = apiError(err, "Could not get pod '%s'", podName)
 		return
 	}
 	return pod.Status.PodIP, pod.Status.HostIP, nil
@@ -269,7 +342,7 @@ func (connection *Conn) GetPodIPs(namespace, podName string) (podIP string, host
 //	apiEndPoint:	apis/aadpodidentity.k8s.io/v1
 //	namespace:		"demo-ns"
 //	resourceName:	"azureidentitybindings"
-func (connection *Conn) GetRawResourceByName(apiEndPoint, namespace, resourceType, resourceName string) (resource APIResource, err error) {
+func (connection *Conn) GetRawResourceByName(ctx context.Context, apiEndPoint, namespace, resourceType, resourceName string) (resource APIResource, err error) {
 
 	restClient := connection.clientSet.CoreV1().RESTClient()
 	log.Printf("[DEBUG] REST request: %+v", restClient)
@@ -280,12 +353,12 @@ func (connection *Conn) GetRawResourceByName(apiEndPoint, namespace, resourceTyp
 		Resource(resourceType).
 		Name(resourceName)
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
 	response := getRequest.Do(ctx)
 	if response.Error() != nil {
-		err = response.Error()
+		err = apiError(response.Error(), "Could not get %s '%s'", resourceType, resourceName)
 		return
 	}
 
@@ -308,7 +381,7 @@ func (connection *Conn) GetRawResourceByName(apiEndPoint, namespace, resourceTyp
 //	namespace:		"demo-ns"
 //	resourceName:	"azureidentitybindings"
 //	resourceBody:	"{...}"
-func (connection *Conn) PostRawResource(apiEndPoint string, namespace string, resourceName string, resourceBody interface{}) (resource APIResource, err error) {
+func (connection *Conn) PostRawResource(ctx context.Context, apiEndPoint string, namespace string, resourceName string, resourceBody interface{}) (resource APIResource, err error) {
 
 	restClient := connection.clientSet.CoreV1().RESTClient()
 	postRequest := restClient.Post().
@@ -317,12 +390,12 @@ func (connection *Conn) PostRawResource(apiEndPoint string, namespace string, re
 		Resource(resourceName).
 		Body(resourceBody)
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
 	response := postRequest.Do(ctx)
 	if response.Error() != nil {
-		err = response.Error()
+		err = apiError(response.Error(), "Could not create %s", resourceName)
 		return
 	}
 
@@ -332,32 +405,109 @@ func (connection *Conn) PostRawResource(apiEndPoint string, namespace string, re
 
 	resource = APIResource{}
 	json.Unmarshal(responseBytes, &resource)
+	if name := resource.Metadata["name"]; name != "" {
+		ledger.Record(ctx, connection.ledgerResource("kubernetes/"+resourceName, namespace, name, apiEndPoint))
+	}
 
 	return
 }
 
+// DeleteRawResource makes a 'raw' DELETE call to the specified K8s api endpoint to delete a resource, which is
+// then removed from the ledger. The params are as for GetRawResourceByName.
+func (connection *Conn) DeleteRawResource(ctx context.Context, apiEndPoint, namespace, resourceType, resourceName string) error {
+
+	restClient := connection.clientSet.CoreV1().RESTClient()
+	deleteRequest := restClient.Delete().
+		AbsPath(apiEndPoint).
+		Namespace(namespace).
+		Resource(resourceType).
+		Name(resourceName)
+
+	deleteCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	err := deleteRequest.Do(deleteCtx).Error()
+	if err != nil && !errors.IsStatusCode(404, err) {
+		return apiError(err, "Could not delete %s '%s'", resourceType, resourceName)
+	}
+	ledger.Remove(ctx, connection.ledgerResource("kubernetes/"+resourceType, namespace, resourceName, apiEndPoint))
+	if err == nil {
+		log.Printf("[INFO] %s %s deleted.", resourceType, resourceName)
+	}
+	return err
+}
+
+// apiError marks an error returned by the Kubernetes API as an infrastructure error if the cluster could not be
+// reached or could not serve the request, so that the control is recorded as inconclusive rather than failed.
+// Other errors, such as a pod being forbidden by an admission controller, are returned unchanged for probes to evaluate.
+func apiError(err error, action string, v ...interface{}) error {
+	if !errors.IsUnavailable(err) || utils.IsInfrastructureError(err) {
+		return err
+	}
+	return utils.InfrastructureError("%s: %w", fmt.Sprintf(action, v...), err)
+}
+
+// ledgerResource describes a resource created through the connection, so that it can be deleted by DeleteRecorded
+func (connection *Conn) ledgerResource(kind, namespace, name, apiEndPoint string) ledger.Resource {
+	location := map[string]string{
+		"kube_config_path": connection.settings.kubeConfigPath,
+		"kube_context":     connection.settings.kubeContext,
+	}
+	if apiEndPoint != "" {
+		location["api_endpoint"] = apiEndPoint
+	}
+	return ledger.Resource{Kind: kind, Group: namespace, Name: name, Location: location}
+}
+
+// DeleteRecorded deletes a pod or raw resource that is listed in the ledger, using the kubeconfig and context
+// that it was created with. It is registered with the ledger for each kind of resource created by the probes.
+// The probe namespace is not created, as there is nothing to delete if it does not exist.
+func DeleteRecorded(ctx context.Context, r ledger.Resource) error {
+	vars := config.FromContext(ctx).ServicePacks.Kubernetes
+	vars.KubeConfigPath = r.Location["kube_config_path"]
+	vars.KubeContext = r.Location["kube_context"]
+	connection := connect(&vars)
+	if connection.clusterIsDeployed != nil {
+		return connection.clusterIsDeployed
+	}
+
+	var err error
+	if r.Kind == KindPod {
+		err = connection.DeletePodIfExists(ctx, r.Name, r.Group)
+	} else {
+		err = connection.DeleteRawResource(ctx, r.Location["api_endpoint"], r.Group, strings.TrimPrefix(r.Kind, "kubernetes/"), r.Name)
+	}
+	if errors.IsStatusCode(404, err) {
+		return nil // Already deleted
+	}
+	return err
+}
+
 func (connection *Conn) setClientConfig() {
 	// Adapted from clientcmd.BuildConfigFromFlags:
 	// https://github.com/kubernetes/client-go/blob/5ab99756f65dbf324e5adf9bd020a20a024bad85/tools/clientcmd/client_config.go#L606
+	// The context is overridden rather than written to the kubeconfig, which may be shared by other runs
 	var err error
-	vars := &config.Vars.ServicePacks.Kubernetes
+	s := connection.settings
 
 	configLoader := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
 
-		&clientcmd.ClientConfigLoadingRules{ExplicitPath: vars.KubeConfigPath},
-		&clientcmd.ConfigOverrides{ClusterInfo: clientcmdapi.Cluster{Server: ""}})
+		&clientcmd.ClientConfigLoadingRules{ExplicitPath: s.kubeConfigPath},
+		&clientcmd.ConfigOverrides{ClusterInfo: clientcmdapi.Cluster{Server: ""}, CurrentContext: s.kubeContext})
 	rawConfig, _ := configLoader.RawConfig()
 
-	if vars.KubeContext == "" {
+	if s.kubeContext == "" {
 		log.Printf("[INFO] Initializing client with default context")
 	} else {
-		log.Printf("[INFO] Initializing client with context specified in config vars: %v", vars.KubeContext)
-		connection.modifyContext(rawConfig, vars.KubeContext)
+		log.Printf("[INFO] Initializing client with context specified in config vars: %v", s.kubeContext)
+		if rawConfig.Contexts[s.kubeContext] == nil {
+			connection.clusterIsDeployed = utils.InfrastructureError("Required context does not exist in provided kubeconfig: %v", s.kubeContext)
+		}
 	}
 
 	connection.clientConfig, err = configLoader.ClientConfig()
 	if err != nil {
-		connection.clusterIsDeployed = utils.ReformatError("Failed to retrieve rest client config to validate cluster: %v", err)
+		connection.clusterIsDeployed = utils.InfrastructureError("Failed to retrieve rest client config to validate cluster: %v", err)
 	}
 }
 
@@ -366,38 +516,37 @@ func (connection *Conn) bootstrapDefaultNamespace() {
 	if err != nil {
 		return
 	}
-	_, err = connection.GetOrCreateNamespace(config.Vars.ServicePacks.Kubernetes.ProbeNamespace)
-	if err != nil {
-		connection.clusterIsDeployed = utils.ReformatError("Failed to retrieve or create default Probr namespace: %v", err)
-	}
-}
-
-func (connection *Conn) modifyContext(rawConfig clientcmdapi.Config, context string) {
-	log.Printf("[DEBUG] Modifying Kubernetes context based on Probr config vars")
-	if rawConfig.Contexts[context] == nil {
-		connection.clusterIsDeployed = utils.ReformatError("Required context does not exist in provided kubeconfig: %v", context)
-	}
-	rawConfig.CurrentContext = context
-	err := clientcmd.ModifyConfig(clientcmd.NewDefaultPathOptions(), rawConfig, true)
+	_, err = connection.GetOrCreateNamespace(context.Background(), connection.settings.probeNamespace)
 	if err != nil {
-		connection.clusterIsDeployed = utils.ReformatError("Failed to modify context in kubeconfig: %v", context)
+		connection.namespaceErr = utils.InfrastructureError("Failed to retrieve or create default Probr namespace: %v", err)
 	}
 }
 
 // WaitForPod ensures pod has entered a running state, or returns any error encountered
-func (connection *Conn) WaitForPod(namespace string, podName string) (err error) {
-	duration := time.Minute / 2
-	ctx, cancel := context.WithTimeout(context.Background(), duration)
-	defer cancel()
+func (connection *Conn) WaitForPod(ctx context.Context, namespace string, podName string) (err error) {
+	if duration := connection.settings.podWaitTimeout; duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, duration)
+		defer cancel()
+	}
 
 	ps := connection.clientSet.CoreV1().Pods(namespace)
 	w, err := ps.Watch(ctx, metav1.ListOptions{})
 
 	if err != nil {
+		err = apiError(err, "Could not watch pod '%s'", podName)
 		return
 	}
 
+	defer w.Stop()
+
 	log.Printf("[INFO] *** Waiting for pod: %s", podName)
+	return connection.waitForRunning(ctx, w, podName)
+}
+
+// waitForRunning reads the events of the watch until the named pod is running, the pod enters an error state,
+// or the watch ends. A watch that ends, e.g. because PodWaitTimeout expired, is reported as an infrastructure error.
+func (connection *Conn) waitForRunning(ctx context.Context, w watch.Interface, podName string) error {
 	for e := range w.ResultChan() {
 		log.Printf("[DEBUG] Watch Probe Type: %v", e.Type)
 		pod, ok := e.Object.(*apiv1.Pod)
@@ -418,17 +567,18 @@ func (connection *Conn) WaitForPod(namespace string, podName string) (err error)
 			log.Printf("[DEBUG] Container Status: %+v", con)
 		}
 
-		err = connection.podInErrorState(pod)
-		if err != nil {
-			return
+		if err := connection.podInErrorState(pod); err != nil {
+			return err
 		}
 
 		if pod.Status.Phase == apiv1.PodRunning {
-			break
+			return nil
 		}
-
 	}
-	return
+	if ctx.Err() != nil {
+		return utils.InfrastructureError("Pod '%s' was not running before the wait ended: %w", podName, ctx.Err())
+	}
+	return utils.InfrastructureError("Watch ended before pod '%s' was running", podName)
 }
 
 func (connection *Conn) podInErrorState(p *apiv1.Pod) error {
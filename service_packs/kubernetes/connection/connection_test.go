@@ -0,0 +1,51 @@
+package connection
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/citihub/probr/utils"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+func podInPhase(name string, phase apiv1.PodPhase) *apiv1.Pod {
+	return &apiv1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name}, Status: apiv1.PodStatus{Phase: phase}}
+}
+
+func TestWaitForRunning(t *testing.T) {
+	expired, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	<-expired.Done()
+
+	tests := []struct {
+		testName string
+		ctx      context.Context
+		events   []*apiv1.Pod
+		running  bool
+	}{
+		{testName: "Running", ctx: context.Background(), events: []*apiv1.Pod{podInPhase("probe-pod", apiv1.PodPending), podInPhase("probe-pod", apiv1.PodRunning)}, running: true},
+		{testName: "OtherPodRunning", ctx: context.Background(), events: []*apiv1.Pod{podInPhase("other-pod", apiv1.PodRunning)}},
+		{testName: "WatchClosed", ctx: context.Background(), events: []*apiv1.Pod{podInPhase("probe-pod", apiv1.PodPending)}},
+		{testName: "DeadlinePassed", ctx: expired, events: []*apiv1.Pod{podInPhase("probe-pod", apiv1.PodPending)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			w := watch.NewFakeWithChanSize(len(tt.events), false)
+			for _, pod := range tt.events {
+				w.Modify(pod)
+			}
+			w.Stop() // The watch closes without further events, as it does when its deadline passes
+
+			err := (&Conn{}).waitForRunning(tt.ctx, w, "probe-pod")
+			if tt.running && err != nil {
+				t.Errorf("waitForRunning() = %v, expected the pod to be running", err)
+			}
+			if !tt.running && !utils.IsInfrastructureError(err) {
+				t.Errorf("waitForRunning() = %v, expected an infrastructure error as the pod never ran", err)
+			}
+		})
+	}
+}
@@ -15,8 +15,8 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
-// PodSpec constructs a simple pod object
-func PodSpec(baseName string, namespace string) *apiv1.Pod {
+// PodSpec constructs a simple pod object, using the image specified in the kubernetes config vars
+func PodSpec(baseName string, namespace string, vars *config.Kubernetes) *apiv1.Pod {
 	name := strings.Replace(baseName, "_", "-", -1)
 	podName := uniquePodName(name)
 	containerName := fmt.Sprintf("%s-probe-pod", name)
@@ -39,7 +39,7 @@ func PodSpec(baseName string, namespace string) *apiv1.Pod {
 			Containers: []apiv1.Container{
 				{
 					Name:            containerName,
-					Image:           DefaultProbrImageName(),
+					Image:           DefaultProbrImageName(vars),
 					ImagePullPolicy: apiv1.PullIfNotPresent,
 					Command:         DefaultEntrypoint(),
 					SecurityContext: DefaultContainerSecurityContext(),
@@ -73,13 +73,13 @@ func DefaultPodSecurityContext() *apiv1.PodSecurityContext {
 	}
 }
 
-// DefaultProbrImageName joins the registry and image name specified in config vars
-func DefaultProbrImageName() string {
+// DefaultProbrImageName joins the registry and image name specified in the kubernetes config vars
+func DefaultProbrImageName(vars *config.Kubernetes) string {
 	// Service pack will not start without these vars, so we can rely on them being present
 	return fmt.Sprintf(
 		"%s/%s",
-		config.Vars.ServicePacks.Kubernetes.AuthorisedContainerRegistry,
-		config.Vars.ServicePacks.Kubernetes.ProbeImage)
+		vars.AuthorisedContainerRegistry,
+		vars.ProbeImage)
 }
 
 // DefaultEntrypoint is used by all default pods
@@ -100,7 +100,7 @@ func TestPodSpec(t *testing.T) {
 			},
 			want: func(gotPod *apiv1.Pod, args args, t *testing.T) {
 				gotImageName := gotPod.Spec.Containers[0].Image
-				wantImageName := DefaultProbrImageName()
+				wantImageName := DefaultProbrImageName(&config.Vars.ServicePacks.Kubernetes)
 				if strings.Compare(gotImageName, wantImageName) != 0 {
 					t.Errorf("PodSpec() got image name '%s', but wanted: '%s'", gotImageName, wantImageName)
 				}
@@ -161,7 +161,7 @@ func TestPodSpec(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := PodSpec(tt.args.baseName, tt.args.namespace)
+			got := PodSpec(tt.args.baseName, tt.args.namespace, &config.Vars.ServicePacks.Kubernetes)
 			tt.want(got, tt.args, t)
 		})
 	}
@@ -238,9 +238,8 @@ func TestDefaultProbrImageName(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			config.Vars.ServicePacks.Kubernetes.AuthorisedContainerRegistry = tt.registry
-			config.Vars.ServicePacks.Kubernetes.ProbeImage = tt.image
-			if got := DefaultProbrImageName(); got != tt.want {
+			vars := config.Kubernetes{AuthorisedContainerRegistry: tt.registry, ProbeImage: tt.image}
+			if got := DefaultProbrImageName(&vars); got != tt.want {
 				t.Errorf("DefaultProbrImageName() = %v, want %v", got, tt.want)
 			}
 		})
@@ -2,6 +2,7 @@
 package cra
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"strings"
@@ -20,12 +21,12 @@ import (
 
 type probeStruct struct{}
 
-// Will provide functionality to interact with K8s cluster
-var conn connection.Connection
-
 // scenarioState holds the steps and state for any scenario in this probe
 type scenarioState struct {
+	settings    *config.Kubernetes
+	conn        connection.Connection
 	name        string
+	ctx         context.Context
 	currentStep string
 	namespace   string
 	audit       *audit.ScenarioAudit
@@ -35,7 +36,6 @@ type scenarioState struct {
 
 // Probe meets the service pack interface for adding the logic from this file
 var Probe probeStruct
-var scenario scenarioState
 
 func (scenario *scenarioState) aKubernetesClusterIsDeployed() error {
 	// Standard auditing logic to ensures panics are also audited
@@ -49,11 +49,11 @@ func (scenario *scenarioState) aKubernetesClusterIsDeployed() error {
 		KubeConfigPath string
 		KubeContext    string
 	}{
-		config.Vars.ServicePacks.Kubernetes.KubeConfigPath,
-		config.Vars.ServicePacks.Kubernetes.KubeContext,
+		scenario.settings.KubeConfigPath,
+		scenario.settings.KubeContext,
 	}
 
-	err = conn.ClusterIsDeployed() // Must be assigned to 'err' be audited
+	err = scenario.conn.ClusterIsDeployed() // Must be assigned to 'err' be audited
 	return err
 }
 
@@ -97,10 +97,10 @@ func (scenario *scenarioState) podCreationXWithContainerImageFromYRegistry(expec
 	}
 
 	stepTrace.WriteString(fmt.Sprintf("Get appropriate container image from an '%s' registry; ", registryAccess))
-	imageRegistry := getImageFromConfig(isRegistryAuthorized)
+	imageRegistry := getImageFromConfig(scenario.settings, isRegistryAuthorized)
 
 	stepTrace.WriteString(fmt.Sprintf("Build a pod spec with default values; "))
-	podObject := constructors.PodSpec(Probe.Name(), scenario.namespace)
+	podObject := constructors.PodSpec(Probe.Name(), scenario.namespace, scenario.settings)
 
 	stepTrace.WriteString(fmt.Sprintf("Set container image registry to appropriate value in pod spec; "))
 	podObject.Spec.Containers[0].Image = imageRegistry
@@ -113,7 +113,7 @@ func (scenario *scenarioState) podCreationXWithContainerImageFromYRegistry(expec
 	switch shouldCreatePod {
 	case true:
 		if creationErr != nil {
-			err = utils.ReformatError("Pod creation did not succeed: %v", creationErr)
+			err = utils.ReformatError("Pod creation did not succeed: %w", creationErr)
 		}
 	case false:
 		if creationErr == nil {
@@ -121,7 +121,7 @@ func (scenario *scenarioState) podCreationXWithContainerImageFromYRegistry(expec
 		} else {
 			// TODO: Optimize how we're handling expected errors
 			if !errors.IsStatusCode(403, creationErr) && !strings.Contains(creationErr.Error(), "ErrImagePull") {
-				err = utils.ReformatError("Unexpected error during Pod creation : %v", creationErr)
+				err = utils.ReformatError("Unexpected error during Pod creation : %w", creationErr)
 			}
 		}
 	}
@@ -158,73 +158,78 @@ func (probe probeStruct) Path() string {
 // ProbeInitialize handles any overall Test Suite initialisation steps.  This is registered with the
 // test handler as part of the init() function.
 func (probe probeStruct) ProbeInitialize(ctx *godog.TestSuiteContext) {
-	ctx.BeforeSuite(func() {
-		conn = connection.Get()
-	})
-
 	ctx.AfterSuite(func() {
 	})
 }
 
 // ScenarioInitialize provides initialization logic before each scenario is executed
-func (probe probeStruct) ScenarioInitialize(ctx *godog.ScenarioContext) {
+func (probe probeStruct) ScenarioInitialize(ctx context.Context, sc *godog.ScenarioContext) {
+	scenario := &scenarioState{}
 
-	ctx.BeforeScenario(func(s *godog.Scenario) {
-		beforeScenario(&scenario, probe.Name(), s)
+	sc.BeforeScenario(func(s *godog.Scenario) {
+		beforeScenario(ctx, scenario, probe.Name(), s)
 	})
 
 	// Background
-	ctx.Step(`^a Kubernetes cluster exists which we can deploy into$`, scenario.aKubernetesClusterIsDeployed)
+	sc.Step(`^a Kubernetes cluster exists which we can deploy into$`, scenario.aKubernetesClusterIsDeployed)
 
 	// Steps
-	ctx.Step(`^pod creation "([^"]*)" with container image from "([^"]*)" registry$`, scenario.podCreationXWithContainerImageFromYRegistry)
+	sc.Step(`^pod creation "([^"]*)" with container image from "([^"]*)" registry$`, scenario.podCreationXWithContainerImageFromYRegistry)
 
-	ctx.AfterScenario(func(s *godog.Scenario, err error) {
-		afterScenario(scenario, probe, s, err)
+	sc.AfterScenario(func(s *godog.Scenario, err error) {
+		afterScenario(*scenario, probe, s, err)
 	})
 
-	ctx.BeforeStep(func(st *godog.Step) {
+	sc.BeforeStep(func(st *godog.Step) {
 		scenario.currentStep = st.Text
 	})
 
-	ctx.AfterStep(func(st *godog.Step, err error) {
+	sc.AfterStep(func(st *godog.Step, err error) {
 		scenario.currentStep = ""
 	})
 }
 
-func beforeScenario(s *scenarioState, probeName string, gs *godog.Scenario) {
+func beforeScenario(ctx context.Context, s *scenarioState, probeName string, gs *godog.Scenario) {
 	s.name = gs.Name
-	s.probe = audit.State.GetProbeLog(probeName)
-	s.audit = audit.State.GetProbeLog(probeName).InitializeAuditor(gs.Name, gs.Tags)
+	s.ctx = ctx
+	s.settings = &config.FromContext(ctx).ServicePacks.Kubernetes
+	s.conn = connection.Get(s.settings)
+	s.probe = audit.ProbeLog(ctx, probeName)
+	s.audit = s.probe.InitializeAuditor(gs.Name, gs.Tags)
 	s.pods = make([]string, 0)
-	s.namespace = config.Vars.ServicePacks.Kubernetes.ProbeNamespace
+	s.namespace = s.settings.ProbeNamespace
 	coreengine.LogScenarioStart(gs)
 }
 
 func afterScenario(scenario scenarioState, probe probeStruct, gs *godog.Scenario, err error) {
-	if config.Vars.ServicePacks.Kubernetes.KeepPods == "false" {
+	if !scenario.settings.KeepPods {
 		for _, podName := range scenario.pods {
-			err = conn.DeletePodIfExists(podName, scenario.namespace, probe.Name())
+			err = scenario.conn.DeletePodIfExists(config.WithVars(context.Background(), config.FromContext(scenario.ctx)), podName, scenario.namespace) // Pods are removed even if the scenario timed out
 			if err != nil {
 				log.Printf(fmt.Sprintf("[ERROR] Could not retrieve pod from namespace '%s' for deletion: %s", scenario.namespace, err))
+			} else {
+				scenario.probe.CountPodDestroyed()
 			}
 		}
 	}
 	coreengine.LogScenarioEnd(gs)
 }
 
-func getImageFromConfig(accessLevel bool) string {
+func getImageFromConfig(settings *config.Kubernetes, accessLevel bool) string {
 	if accessLevel {
 		// full image is the repository + image
 		return fmt.Sprintf("%s/%s",
-			config.Vars.ServicePacks.Kubernetes.AuthorisedContainerRegistry,
-			config.Vars.ServicePacks.Kubernetes.ProbeImage)
+			settings.AuthorisedContainerRegistry,
+			settings.ProbeImage)
 	}
-	return config.Vars.ServicePacks.Kubernetes.UnauthorisedContainerImage
+	return settings.UnauthorisedContainerImage
 }
 
 func (scenario *scenarioState) createPodfromObject(podObject *apiv1.Pod) (createdPodObject *apiv1.Pod, err error) {
-	createdPodObject, err = conn.CreatePodFromObject(podObject, Probe.Name())
+	createdPodObject, err = scenario.conn.CreatePodFromObject(scenario.ctx, podObject)
+	if err == nil {
+		scenario.probe.CountPodCreated(createdPodObject.ObjectMeta.Name)
+	}
 	if createdPodObject != nil && createdPodObject.ObjectMeta.Name != "" {
 		scenario.pods = append(scenario.pods, createdPodObject.ObjectMeta.Name)
 	}
@@ -1,11 +1,31 @@
 package errors
 
-import "k8s.io/apimachinery/pkg/api/errors"
+import (
+	stderrors "errors"
 
-// IsStatusCode validates whether an error is a StatusError with a specific status code
+	"k8s.io/apimachinery/pkg/api/errors"
+)
+
+// IsStatusCode validates whether an error, or any error it wraps, is a StatusError with a specific status code
 func IsStatusCode(expected int32, err error) bool {
-	if se, ok := err.(*errors.StatusError); ok {
+	var se *errors.StatusError
+	if stderrors.As(err, &se) {
 		return se.ErrStatus.Code == expected
 	}
 	return false
 }
+
+// IsUnavailable reports whether an error from the Kubernetes API shows that the cluster could not be reached or
+// could not serve the request. Requests that the cluster rejected, for instance by an admission controller, are
+// not unavailable, as probes may expect them to be rejected.
+func IsUnavailable(err error) bool {
+	if err == nil {
+		return false
+	}
+	var se *errors.StatusError
+	if !stderrors.As(err, &se) {
+		return true // The request did not get a response, e.g. the connection was refused
+	}
+	code := se.ErrStatus.Code
+	return code == 401 || code == 429 || code >= 500
+}
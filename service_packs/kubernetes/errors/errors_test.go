@@ -0,0 +1,42 @@
+package errors
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestIsUnavailable(t *testing.T) {
+	pods := schema.GroupResource{Resource: "pods"}
+	tests := []struct {
+		testName string
+		err      error
+		expected bool
+	}{
+		{"Nil", nil, false},
+		{"ConnectionRefused", &url.Error{Op: "Get", URL: "https://cluster", Err: fmt.Errorf("connection refused")}, true},
+		{"Unauthorized", errors.NewUnauthorized("token expired"), true},
+		{"ServiceUnavailable", errors.NewServiceUnavailable("etcd is down"), true},
+		{"Forbidden", errors.NewForbidden(pods, "probr-pod", fmt.Errorf("denied by admission controller")), false},
+		{"NotFound", errors.NewNotFound(pods, "probr-pod"), false},
+		{"WrappedForbidden", fmt.Errorf("create failed: %w", errors.NewForbidden(pods, "probr-pod", context.Canceled)), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			if IsUnavailable(tt.err) != tt.expected {
+				t.Errorf("IsUnavailable(%v) = %v, want %v", tt.err, !tt.expected, tt.expected)
+			}
+		})
+	}
+}
+
+func TestIsStatusCode_Wrapped(t *testing.T) {
+	err := fmt.Errorf("delete failed: %w", errors.NewNotFound(schema.GroupResource{Resource: "pods"}, "probr-pod"))
+	if !IsStatusCode(404, err) {
+		t.Errorf("Expected the status code of a wrapped StatusError to be found")
+	}
+}
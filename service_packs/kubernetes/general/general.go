@@ -2,6 +2,7 @@
 package general
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"net/url"
@@ -21,10 +22,11 @@ import (
 
 type probeStruct struct{}
 
-var conn connection.Connection
-
 type scenarioState struct {
+	settings    *config.Kubernetes
+	conn        connection.Connection
 	name        string
+	ctx         context.Context
 	currentStep string
 	namespace   string
 	audit       *audit.ScenarioAudit
@@ -34,7 +36,6 @@ type scenarioState struct {
 
 // Probe meets the service pack interface for adding the logic from this file
 var Probe probeStruct
-var scenario scenarioState
 
 func (scenario *scenarioState) aKubernetesClusterIsDeployed() error {
 	// Standard auditing logic to ensures panics are also audited
@@ -48,11 +49,11 @@ func (scenario *scenarioState) aKubernetesClusterIsDeployed() error {
 		KubeConfigPath string
 		KubeContext    string
 	}{
-		config.Vars.ServicePacks.Kubernetes.KubeConfigPath,
-		config.Vars.ServicePacks.Kubernetes.KubeContext,
+		scenario.settings.KubeConfigPath,
+		scenario.settings.KubeContext,
 	}
 
-	err = conn.ClusterIsDeployed() // Must be assigned to 'err' be audited
+	err = scenario.conn.ClusterIsDeployed() // Must be assigned to 'err' be audited
 	return err
 }
 
@@ -63,14 +64,14 @@ func (scenario *scenarioState) theKubernetesWebUIIsDisabled() error {
 		scenario.audit.AuditScenarioStep(scenario.currentStep, stepTrace.String(), payload, err)
 	}()
 
-	kubeSystemNamespace := config.Vars.ServicePacks.Kubernetes.SystemNamespace
-	dashboardPodNamePrefix := config.Vars.ServicePacks.Kubernetes.DashboardPodNamePrefix
+	kubeSystemNamespace := scenario.settings.SystemNamespace
+	dashboardPodNamePrefix := scenario.settings.DashboardPodNamePrefix
 	stepTrace.WriteString(fmt.Sprintf("Attempt to find a pod in the '%s' namespace with the prefix '%s'; ", kubeSystemNamespace, dashboardPodNamePrefix))
 
 	stepTrace.WriteString(fmt.Sprintf("Get all pods from '%s' namespace; ", kubeSystemNamespace))
-	podList, getError := conn.GetPodsByNamespace(kubeSystemNamespace) // Also validates if provided namespace is valid
+	podList, getError := scenario.conn.GetPodsByNamespace(scenario.ctx, kubeSystemNamespace) // Also validates if provided namespace is valid
 	if getError != nil {
-		err = utils.ReformatError("An error occurred while retrieving pods from '%s' namespace. Error: %s", kubeSystemNamespace, getError)
+		err = utils.InfrastructureError("An error occurred while retrieving pods from '%s' namespace. Error: %s", kubeSystemNamespace, getError)
 		return err
 	}
 
@@ -102,13 +103,13 @@ func (scenario *scenarioState) aPodIsDeployedInTheCluster() error {
 	}()
 
 	stepTrace.WriteString(fmt.Sprintf("Build a pod spec with default values; "))
-	podObject := constructors.PodSpec(Probe.Name(), config.Vars.ServicePacks.Kubernetes.ProbeNamespace)
+	podObject := constructors.PodSpec(Probe.Name(), scenario.settings.ProbeNamespace, scenario.settings)
 
 	stepTrace.WriteString(fmt.Sprintf("Create pod from spec; "))
 	createdPodObject, creationErr := scenario.createPodfromObject(podObject)
 
 	if creationErr != nil {
-		err = utils.ReformatError("Pod creation did not succeed: %v", creationErr)
+		err = utils.ReformatError("Pod creation did not succeed: %w", creationErr)
 	}
 
 	payload = struct {
@@ -154,7 +155,7 @@ func (scenario *scenarioState) theResultOfAProcessInsideThePodEstablishingADirec
 	cmd := fmt.Sprintf("curl -m 10 %s", urlAddress) // 10 second timeout should be enough
 
 	stepTrace.WriteString("Attempt to run curl command in the pod; ")
-	exitCode, stdOut, stdErr, err := conn.ExecCommand(cmd, scenario.namespace, scenario.pods[0])
+	exitCode, stdOut, stdErr, err := scenario.conn.ExecCommand(scenario.ctx, cmd, scenario.namespace, scenario.pods[0])
 
 	payload = struct {
 		PodName             string
@@ -220,57 +221,59 @@ func (probe probeStruct) Path() string {
 // test handler as part of the init() function.
 func (probe probeStruct) ProbeInitialize(ctx *godog.TestSuiteContext) {
 
-	ctx.BeforeSuite(func() {
-		conn = connection.Get()
-	})
-
 	ctx.AfterSuite(func() {})
 
 }
 
 // ScenarioInitialize provides initialization logic before each scenario is executed
-func (probe probeStruct) ScenarioInitialize(ctx *godog.ScenarioContext) {
+func (probe probeStruct) ScenarioInitialize(ctx context.Context, sc *godog.ScenarioContext) {
+	scenario := &scenarioState{}
 
-	ctx.BeforeScenario(func(s *godog.Scenario) {
-		beforeScenario(&scenario, probe.Name(), s)
+	sc.BeforeScenario(func(s *godog.Scenario) {
+		beforeScenario(ctx, scenario, probe.Name(), s)
 	})
 
 	// Background
-	ctx.Step(`^a Kubernetes cluster exists which we can deploy into$`, scenario.aKubernetesClusterIsDeployed)
+	sc.Step(`^a Kubernetes cluster exists which we can deploy into$`, scenario.aKubernetesClusterIsDeployed)
 
 	// Steps
-	ctx.Step(`^the Kubernetes Web UI is disabled$`, scenario.theKubernetesWebUIIsDisabled)
-	ctx.Step(`^a pod is deployed in the cluster$`, scenario.aPodIsDeployedInTheCluster)
-	ctx.Step(`^the result of a process inside the pod establishing a direct connection to "([^"]*)" is blocked$`, scenario.theResultOfAProcessInsideThePodEstablishingADirectHTTPConnectionToXIsBlocked)
+	sc.Step(`^the Kubernetes Web UI is disabled$`, scenario.theKubernetesWebUIIsDisabled)
+	sc.Step(`^a pod is deployed in the cluster$`, scenario.aPodIsDeployedInTheCluster)
+	sc.Step(`^the result of a process inside the pod establishing a direct connection to "([^"]*)" is blocked$`, scenario.theResultOfAProcessInsideThePodEstablishingADirectHTTPConnectionToXIsBlocked)
 
-	ctx.AfterScenario(func(s *godog.Scenario, err error) {
-		afterScenario(scenario, probe, s, err)
+	sc.AfterScenario(func(s *godog.Scenario, err error) {
+		afterScenario(*scenario, probe, s, err)
 	})
 
-	ctx.BeforeStep(func(st *godog.Step) {
+	sc.BeforeStep(func(st *godog.Step) {
 		scenario.currentStep = st.Text
 	})
 
-	ctx.AfterStep(func(st *godog.Step, err error) {
+	sc.AfterStep(func(st *godog.Step, err error) {
 		scenario.currentStep = ""
 	})
 }
 
-func beforeScenario(s *scenarioState, probeName string, gs *godog.Scenario) {
+func beforeScenario(ctx context.Context, s *scenarioState, probeName string, gs *godog.Scenario) {
 	s.name = gs.Name
-	s.probe = audit.State.GetProbeLog(probeName)
-	s.audit = audit.State.GetProbeLog(probeName).InitializeAuditor(gs.Name, gs.Tags)
+	s.ctx = ctx
+	s.settings = &config.FromContext(ctx).ServicePacks.Kubernetes
+	s.conn = connection.Get(s.settings)
+	s.probe = audit.ProbeLog(ctx, probeName)
+	s.audit = s.probe.InitializeAuditor(gs.Name, gs.Tags)
 	s.pods = make([]string, 0)
-	s.namespace = config.Vars.ServicePacks.Kubernetes.ProbeNamespace
+	s.namespace = s.settings.ProbeNamespace
 	coreengine.LogScenarioStart(gs)
 }
 
 func afterScenario(scenario scenarioState, probe probeStruct, gs *godog.Scenario, err error) {
-	if config.Vars.ServicePacks.Kubernetes.KeepPods == "false" {
+	if !scenario.settings.KeepPods {
 		for _, podName := range scenario.pods {
-			err = conn.DeletePodIfExists(podName, scenario.namespace, probe.Name())
+			err = scenario.conn.DeletePodIfExists(config.WithVars(context.Background(), config.FromContext(scenario.ctx)), podName, scenario.namespace) // Pods are removed even if the scenario timed out
 			if err != nil {
 				log.Printf(fmt.Sprintf("[ERROR] Could not retrieve pod from namespace '%s' for deletion: %s", scenario.namespace, err))
+			} else {
+				scenario.probe.CountPodDestroyed()
 			}
 		}
 	}
@@ -278,7 +281,10 @@ func afterScenario(scenario scenarioState, probe probeStruct, gs *godog.Scenario
 }
 
 func (scenario *scenarioState) createPodfromObject(podObject *apiv1.Pod) (createdPodObject *apiv1.Pod, err error) {
-	createdPodObject, err = conn.CreatePodFromObject(podObject, Probe.Name())
+	createdPodObject, err = scenario.conn.CreatePodFromObject(scenario.ctx, podObject)
+	if err == nil {
+		scenario.probe.CountPodCreated(createdPodObject.ObjectMeta.Name)
+	}
 	if createdPodObject != nil && createdPodObject.ObjectMeta.Name != "" {
 		scenario.pods = append(scenario.pods, createdPodObject.ObjectMeta.Name)
 	}
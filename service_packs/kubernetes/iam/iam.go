@@ -2,6 +2,7 @@
 package iam
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -23,7 +24,11 @@ type probeStruct struct{}
 
 // scenarioState holds the steps and state for any scenario in this probe
 type scenarioState struct {
+	settings              *config.Kubernetes
+	conn                  connection.Connection
+	aks                   *aks.AKS
 	name                  string
+	ctx                   context.Context
 	currentStep           string
 	namespace             string
 	probeAudit            *audit.Probe
@@ -35,9 +40,6 @@ type scenarioState struct {
 
 // Probe meets the service pack interface for adding the logic from this file
 var Probe probeStruct
-var scenario scenarioState
-var conn connection.Connection
-var azureK8S *aks.AKS
 
 func (scenario *scenarioState) aKubernetesClusterIsDeployed() error {
 	// Standard auditing logic to ensures panics are also audited
@@ -51,11 +53,11 @@ func (scenario *scenarioState) aKubernetesClusterIsDeployed() error {
 		KubeConfigPath string
 		KubeContext    string
 	}{
-		config.Vars.ServicePacks.Kubernetes.KubeConfigPath,
-		config.Vars.ServicePacks.Kubernetes.KubeContext,
+		scenario.settings.KubeConfigPath,
+		scenario.settings.KubeContext,
 	}
 
-	err = conn.ClusterIsDeployed() // Must be assigned to 'err' be audited
+	err = scenario.conn.ClusterIsDeployed() // Must be assigned to 'err' be audited
 	return err
 }
 
@@ -86,11 +88,11 @@ func (scenario *scenarioState) aResourceTypeXCalledYExistsInNamespaceCalledZ(res
 	case "AzureIdentity":
 		stepTrace.WriteString(fmt.Sprintf(
 			"Retrieve Azure Identities from cluster; "))
-		foundInNamespace, resource, findErr = azureIdentityExistsInNamespace(resourceName, namespace)
+		foundInNamespace, resource, findErr = azureIdentityExistsInNamespace(scenario.ctx, scenario.aks, resourceName, namespace)
 	case "AzureIdentityBinding":
 		stepTrace.WriteString(fmt.Sprintf(
 			"Retrieve Azure Identity Bindings from cluster; "))
-		foundInNamespace, resource, findErr = azureIdentityBindingExistsInNamespace(resourceName, namespace)
+		foundInNamespace, resource, findErr = azureIdentityBindingExistsInNamespace(scenario.ctx, scenario.aks, resourceName, namespace)
 	default:
 		err = utils.ReformatError("Unexpected value provided for resourceType: %s", resourceType)
 		return err
@@ -104,7 +106,7 @@ func (scenario *scenarioState) aResourceTypeXCalledYExistsInNamespaceCalledZ(res
 	stepTrace.WriteString(fmt.Sprintf(
 		"Check that %s '%s' exists in namespace '%s'; ", resourceType, resourceName, namespace))
 	if !foundInNamespace {
-		err = utils.ReformatError("%s '%s' was not found in namespace '%s'; ", resourceType, resourceName, namespace)
+		err = utils.GivenNotMet("%s '%s' was not found in namespace '%s'; ", resourceType, resourceName, namespace)
 	}
 
 	payload = struct {
@@ -147,11 +149,11 @@ func (scenario *scenarioState) iSucceedToCreateASimplePodInNamespaceAssignedWith
 	// Validate input
 	switch namespace {
 	case "the probr":
-		scenario.namespace = config.Vars.ServicePacks.Kubernetes.ProbeNamespace
+		scenario.namespace = scenario.settings.ProbeNamespace
 		aadPodIDBinding = aibName // TODO: This value is the same in both config and feature file
 	case "the default":
 		scenario.namespace = "default"
-		aadPodIDBinding = config.Vars.ServicePacks.Kubernetes.Azure.DefaultNamespaceAIB // TODO: This value is the same in both config and feature file
+		aadPodIDBinding = scenario.settings.Azure.DefaultNamespaceAIB // TODO: This value is the same in both config and feature file
 	default:
 		err = utils.ReformatError("Unexpected value provided for namespace: %s", namespace)
 		return err
@@ -162,7 +164,7 @@ func (scenario *scenarioState) iSucceedToCreateASimplePodInNamespaceAssignedWith
 	// Should revisit how to handle this.
 
 	stepTrace.WriteString(fmt.Sprintf("Build a pod spec with default values; "))
-	podObject := constructors.PodSpec(Probe.Name(), config.Vars.ServicePacks.Kubernetes.ProbeNamespace)
+	podObject := constructors.PodSpec(Probe.Name(), scenario.settings.ProbeNamespace, scenario.settings)
 	// TODO: Delete iam-azi-test-aib-curl.yaml file from 'assets' folder
 
 	stepTrace.WriteString(fmt.Sprintf("Add '%s' namespace to pod spec; ", scenario.namespace))
@@ -178,7 +180,7 @@ func (scenario *scenarioState) iSucceedToCreateASimplePodInNamespaceAssignedWith
 
 	stepTrace.WriteString("Validate pod creation succeeds; ")
 	if creationErr != nil {
-		err = utils.ReformatError("Pod creation did not succeed: %v", creationErr)
+		err = utils.ReformatError("Pod creation did not succeed: %w", creationErr)
 	}
 
 	payload = struct {
@@ -235,11 +237,11 @@ func (scenario *scenarioState) anAttemptToObtainAnAccessTokenFromThatPodShouldX(
 	cmd := "curl http://169.254.169.254/metadata/identity/oauth2/token?api-version=2018-02-01&resource=https%3A%2F%2Fmanagement.azure.com%2F -H Metadata:true -s"
 
 	stepTrace.WriteString(fmt.Sprintf("Attempt to run command in the pod: '%s'; ", cmd))
-	_, stdOut, _, cmdErr := conn.ExecCommand(cmd, scenario.namespace, podName)
+	_, stdOut, _, cmdErr := scenario.conn.ExecCommand(scenario.ctx, cmd, scenario.namespace, podName)
 
 	// Validate that no internal error occurred during execution of curl command
 	if cmdErr != nil {
-		err = utils.ReformatError("Error raised when attempting to execute curl command inside container: %v", cmdErr)
+		err = utils.ReformatError("Error raised when attempting to execute curl command inside container: %w", cmdErr)
 		return err
 	}
 
@@ -283,13 +285,11 @@ func (scenario *scenarioState) iCreateAnAzureIdentityBindingCalledInANondefaultN
 	aibName = aibName + "-test-test-test"
 	stepTrace.WriteString(fmt.Sprintf(
 		"Attempt to create '%s' binding in '%s' namespace bound to '%s' identity; ", aibName, probrNameSpace, aiName))
-	createdAIB, err := azureCreateAIB(probrNameSpace, aibName, aiName) // create an AIB in a non-default NS if it doesn't already exist
+	createdAIB, err := azureCreateAIB(scenario.ctx, scenario.aks, probrNameSpace, aibName, aiName) // create an AIB in a non-default NS if it doesn't already exist
 	if err != nil {
-		err = utils.ReformatError("An error occurred while creating '%s' binding: %v", aibName, err)
+		err = utils.ReformatError("An error occurred while creating '%s' binding: %w", aibName, err)
 		log.Print(err)
 	}
-	// TODO:
-	//	- Delete AIB at the end of test scenario
 	scenario.azureIdentityBindings = append(scenario.azureIdentityBindings, aibName)
 
 	payload = struct {
@@ -315,14 +315,14 @@ func (scenario *scenarioState) theClusterHasManagedIdentityComponentsDeployed()
 		scenario.audit.AuditScenarioStep(scenario.currentStep, stepTrace.String(), payload, err)
 	}()
 
-	identityPodsNamespace := config.Vars.ServicePacks.Kubernetes.Azure.IdentityNamespace
+	identityPodsNamespace := scenario.settings.Azure.IdentityNamespace
 	stepTrace.WriteString(fmt.Sprintf(
 		"Get pods from '%s' namespace; ", identityPodsNamespace))
 	// look for the mic pods
-	podList, getErr := conn.GetPodsByNamespace(identityPodsNamespace)
+	podList, getErr := scenario.conn.GetPodsByNamespace(scenario.ctx, identityPodsNamespace)
 
 	if getErr != nil {
-		err = utils.ReformatError("An error occurred when trying to retrieve pods %v", err)
+		err = utils.InfrastructureError("An error occurred when trying to retrieve pods %v", getErr)
 		return err
 	}
 
@@ -337,7 +337,7 @@ func (scenario *scenarioState) theClusterHasManagedIdentityComponentsDeployed()
 	}
 
 	if micPodName == "" {
-		err = utils.ReformatError("No MIC pod found")
+		err = utils.GivenNotMet("No MIC pod found")
 		return err
 	}
 	scenario.micPodName = micPodName
@@ -393,10 +393,10 @@ func (scenario *scenarioState) theExecutionOfAXCommandInsideTheMICPodIsY(command
 		return err
 	}
 
-	identityPodsNamespace := config.Vars.ServicePacks.Kubernetes.Azure.IdentityNamespace
+	identityPodsNamespace := scenario.settings.Azure.IdentityNamespace
 	stepTrace.WriteString(fmt.Sprintf(
 		"Attempt to execute command '%s' in MIC pod '%s'; ", cmd, scenario.micPodName))
-	exitCode, stdOut, _, err := conn.ExecCommand(cmd, identityPodsNamespace, scenario.micPodName)
+	exitCode, stdOut, _, err := scenario.conn.ExecCommand(scenario.ctx, cmd, identityPodsNamespace, scenario.micPodName)
 
 	payload = struct {
 		MICPodName       string
@@ -431,7 +431,7 @@ func (scenario *scenarioState) theExecutionOfAXCommandInsideTheMICPodIsY(command
 
 	// Validate that no internal error occurred during execution of curl command
 	if err != nil && exitCode == -1 {
-		err = utils.ReformatError("Error raised when attempting to execute command inside container: %v", err)
+		err = utils.ReformatError("Error raised when attempting to execute command inside container: %w", err)
 		return err
 	}
 
@@ -458,9 +458,6 @@ func (probe probeStruct) Path() string {
 // test handler as part of the init() function.
 func (probe probeStruct) ProbeInitialize(ctx *godog.TestSuiteContext) {
 	ctx.BeforeSuite(func() {
-		conn = connection.Get()
-		azureK8S = aks.NewAKS(conn)
-
 		//setup AzureIdentity stuff ..??  Or should this be a pre-test setup
 	})
 
@@ -470,69 +467,85 @@ func (probe probeStruct) ProbeInitialize(ctx *godog.TestSuiteContext) {
 }
 
 // ScenarioInitialize initialises the specific test steps
-func (probe probeStruct) ScenarioInitialize(ctx *godog.ScenarioContext) {
+func (probe probeStruct) ScenarioInitialize(ctx context.Context, sc *godog.ScenarioContext) {
+	scenario := &scenarioState{}
 
-	ctx.BeforeScenario(func(s *godog.Scenario) {
-		beforeScenario(&scenario, probe.Name(), s)
+	sc.BeforeScenario(func(s *godog.Scenario) {
+		beforeScenario(ctx, scenario, probe.Name(), s)
 	})
 
 	// Background
-	ctx.Step(`^a Kubernetes cluster exists which we can deploy into$`, scenario.aKubernetesClusterIsDeployed)
+	sc.Step(`^a Kubernetes cluster exists which we can deploy into$`, scenario.aKubernetesClusterIsDeployed)
 
 	// Steps
-	ctx.Step(`^an "([^"]*)" called "([^"]*)" exists in the namespace called "([^"]*)"$`, scenario.aResourceTypeXCalledYExistsInNamespaceCalledZ)
-	ctx.Step(`^I succeed to create a simple pod in "([^"]*)" namespace assigned with the "([^"]*)" AzureIdentityBinding$`, scenario.iSucceedToCreateASimplePodInNamespaceAssignedWithThatAzureIdentityBinding)
-	ctx.Step(`^an attempt to obtain an access token from that pod should "([^"]*)"$`, scenario.anAttemptToObtainAnAccessTokenFromThatPodShouldX)
-	ctx.Step(`^I create an AzureIdentityBinding called "([^"]*)" in the Probr namespace bound to the "([^"]*)" AzureIdentity$`, scenario.iCreateAnAzureIdentityBindingCalledInANondefaultNamespace)
-	ctx.Step(`^the cluster has managed identity components deployed$`, scenario.theClusterHasManagedIdentityComponentsDeployed)
-	ctx.Step(`^the execution of a "([^"]*)" command inside the MIC pod is "([^"]*)"$`, scenario.theExecutionOfAXCommandInsideTheMICPodIsY)
-
-	ctx.AfterScenario(func(s *godog.Scenario, err error) {
-		afterScenario(scenario, probe, s, err)
+	sc.Step(`^an "([^"]*)" called "([^"]*)" exists in the namespace called "([^"]*)"$`, scenario.aResourceTypeXCalledYExistsInNamespaceCalledZ)
+	sc.Step(`^I succeed to create a simple pod in "([^"]*)" namespace assigned with the "([^"]*)" AzureIdentityBinding$`, scenario.iSucceedToCreateASimplePodInNamespaceAssignedWithThatAzureIdentityBinding)
+	sc.Step(`^an attempt to obtain an access token from that pod should "([^"]*)"$`, scenario.anAttemptToObtainAnAccessTokenFromThatPodShouldX)
+	sc.Step(`^I create an AzureIdentityBinding called "([^"]*)" in the Probr namespace bound to the "([^"]*)" AzureIdentity$`, scenario.iCreateAnAzureIdentityBindingCalledInANondefaultNamespace)
+	sc.Step(`^the cluster has managed identity components deployed$`, scenario.theClusterHasManagedIdentityComponentsDeployed)
+	sc.Step(`^the execution of a "([^"]*)" command inside the MIC pod is "([^"]*)"$`, scenario.theExecutionOfAXCommandInsideTheMICPodIsY)
+
+	sc.AfterScenario(func(s *godog.Scenario, err error) {
+		afterScenario(*scenario, probe, s, err)
 	})
 
-	ctx.BeforeStep(func(st *godog.Step) {
+	sc.BeforeStep(func(st *godog.Step) {
 		scenario.currentStep = st.Text
 	})
 
-	ctx.AfterStep(func(st *godog.Step, err error) {
+	sc.AfterStep(func(st *godog.Step, err error) {
 		scenario.currentStep = ""
 	})
 }
 
-func beforeScenario(s *scenarioState, probeName string, gs *godog.Scenario) {
+func beforeScenario(ctx context.Context, s *scenarioState, probeName string, gs *godog.Scenario) {
 	s.name = gs.Name
-	s.probeAudit = audit.State.GetProbeLog(probeName)
-	s.audit = audit.State.GetProbeLog(probeName).InitializeAuditor(gs.Name, gs.Tags)
+	s.ctx = ctx
+	s.settings = &config.FromContext(ctx).ServicePacks.Kubernetes
+	s.conn = connection.Get(s.settings)
+	s.aks = aks.NewAKS(s.conn)
+	s.probeAudit = audit.ProbeLog(ctx, probeName)
+	s.audit = s.probeAudit.InitializeAuditor(gs.Name, gs.Tags)
 	s.pods = make([]string, 0)
-	s.namespace = config.Vars.ServicePacks.Kubernetes.ProbeNamespace
+	s.namespace = s.settings.ProbeNamespace
 	s.azureIdentityBindings = make([]string, 0)
 	coreengine.LogScenarioStart(gs)
 }
 
 func afterScenario(scenario scenarioState, probe probeStruct, gs *godog.Scenario, err error) {
-	if config.Vars.ServicePacks.Kubernetes.KeepPods == "false" {
+	if !scenario.settings.KeepPods {
 		for _, podName := range scenario.pods {
-			err = conn.DeletePodIfExists(podName, scenario.namespace, probe.Name())
+			err = scenario.conn.DeletePodIfExists(config.WithVars(context.Background(), config.FromContext(scenario.ctx)), podName, scenario.namespace) // Pods are removed even if the scenario timed out
 			if err != nil {
 				log.Printf(fmt.Sprintf("[ERROR] Could not retrieve pod from namespace '%s' for deletion: %s", scenario.namespace, err))
+			} else {
+				scenario.probeAudit.CountPodDestroyed()
 			}
 		}
 	}
+	for _, aibName := range scenario.azureIdentityBindings {
+		err = scenario.aks.DeleteAIB(config.WithVars(context.Background(), config.FromContext(scenario.ctx)), scenario.namespace, aibName)
+		if err != nil {
+			log.Printf("[ERROR] Could not delete AzureIdentityBinding '%s' from namespace '%s': %s", aibName, scenario.namespace, err)
+		}
+	}
 	coreengine.LogScenarioEnd(gs)
 }
 
 func (scenario *scenarioState) createPodfromObject(podObject *apiv1.Pod) (createdPodObject *apiv1.Pod, err error) {
-	createdPodObject, err = conn.CreatePodFromObject(podObject, Probe.Name())
+	createdPodObject, err = scenario.conn.CreatePodFromObject(scenario.ctx, podObject)
+	if err == nil {
+		scenario.probeAudit.CountPodCreated(createdPodObject.ObjectMeta.Name)
+	}
 	if createdPodObject != nil && createdPodObject.ObjectMeta.Name != "" {
 		scenario.pods = append(scenario.pods, createdPodObject.ObjectMeta.Name)
 	}
 	return
 }
 
-func azureIdentityExistsInNamespace(azureIdentityName, namespace string) (exists bool, resource connection.APIResource, err error) {
+func azureIdentityExistsInNamespace(ctx context.Context, azureK8S *aks.AKS, azureIdentityName, namespace string) (exists bool, resource connection.APIResource, err error) {
 
-	resource, getError := azureK8S.GetIdentityByNameAndNamespace(azureIdentityName, namespace)
+	resource, getError := azureK8S.GetIdentityByNameAndNamespace(ctx, azureIdentityName, namespace)
 	if getError != nil {
 		if errors.IsStatusCode(404, getError) {
 			exists = false
@@ -546,9 +559,9 @@ func azureIdentityExistsInNamespace(azureIdentityName, namespace string) (exists
 	return
 }
 
-func azureIdentityBindingExistsInNamespace(azureIdentityBindingName, namespace string) (exists bool, resource connection.APIResource, err error) {
+func azureIdentityBindingExistsInNamespace(ctx context.Context, azureK8S *aks.AKS, azureIdentityBindingName, namespace string) (exists bool, resource connection.APIResource, err error) {
 
-	resource, getError := azureK8S.GetIdentityBindingByNameAndNamespace(azureIdentityBindingName, namespace)
+	resource, getError := azureK8S.GetIdentityBindingByNameAndNamespace(ctx, azureIdentityBindingName, namespace)
 	if getError != nil {
 		if errors.IsStatusCode(404, getError) {
 			exists = false
@@ -563,9 +576,9 @@ func azureIdentityBindingExistsInNamespace(azureIdentityBindingName, namespace s
 }
 
 // azureCreateAIB creates an AzureIdentityBinding in the cluster
-func azureCreateAIB(namespace, aibName, aiName string) (aibResource connection.APIResource, err error) {
+func azureCreateAIB(ctx context.Context, azureK8S *aks.AKS, namespace, aibName, aiName string) (aibResource connection.APIResource, err error) {
 
-	resource, createErr := azureK8S.CreateAIB(namespace, aibName, aiName)
+	resource, createErr := azureK8S.CreateAIB(ctx, namespace, aibName, aiName)
 	if errors.IsStatusCode(409, createErr) { // Already Exists
 		// TODO: Delete and recreate ?
 		createErr = nil
@@ -10,20 +10,32 @@ import (
 	"github.com/markbates/pkger"
 )
 
-// GetProbes returns a list of probe objects
-func GetProbes() []coreengine.Probe {
-	if config.Vars.ServicePacks.Kubernetes.IsExcluded() {
-		return nil
-	}
-	return []coreengine.Probe{
-		cra.Probe,
-		general.Probe,
-		podsecurity.Probe,
-		iam.Probe,
-	}
+var pack = coreengine.ServicePack{
+	Name: "kubernetes",
+	Config: config.PackConfig{
+		RequiredVars: []string{"AuthorisedContainerRegistry", "UnauthorisedContainerImage"},
+		Settings:     func(v *config.VarOptions) interface{} { return v.ServicePacks.Kubernetes },
+		Probes:       func(v *config.VarOptions) []config.Probe { return v.ServicePacks.Kubernetes.Probes },
+	},
+	Probes: map[string][]coreengine.Probe{
+		"": {
+			cra.Probe,
+			general.Probe,
+			podsecurity.Probe,
+			iam.Probe,
+		},
+	},
+	Tags: tags,
+}
+
+// GetProbes returns a list of probe objects that may be run using the given config
+func GetProbes(vars *config.VarOptions) []coreengine.Probe {
+	return pack.GetProbes(vars)
 }
 
 func init() {
+	coreengine.RegisterServicePack(pack)
+
 	// This line will ensure that all static files are bundled into pked.go file when using pkger cli tool
 	// See: https://github.com/markbates/pkger
 	pkger.Include("/service_packs/kubernetes/container_registry_access/container_registry_access.feature")
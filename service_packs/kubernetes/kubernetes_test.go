@@ -8,23 +8,24 @@ import (
 )
 
 func TestGetProbes(t *testing.T) {
+	vars := config.VarOptions{}
 	pack := make([]coreengine.Probe, 0)
-	pack = GetProbes()
+	pack = GetProbes(&vars)
 	// No required vars set
 	if len(pack) > 0 {
 		t.Logf("Unexpected value returned from GetProbes")
 		t.Fail()
 	}
 	// 1 of 2 required vars set
-	config.Vars.ServicePacks.Kubernetes.AuthorisedContainerRegistry = "not-empty"
-	pack = GetProbes()
+	vars.ServicePacks.Kubernetes.AuthorisedContainerRegistry = "not-empty"
+	pack = GetProbes(&vars)
 	if len(pack) > 0 {
 		t.Logf("Unexpected value returned from GetProbes")
 		t.Fail()
 	}
 	// All required vars set
-	config.Vars.ServicePacks.Kubernetes.UnauthorisedContainerImage = "not-empty"
-	pack = GetProbes()
+	vars.ServicePacks.Kubernetes.UnauthorisedContainerImage = "not-empty"
+	pack = GetProbes(&vars)
 	if len(pack) == 0 {
 		t.Logf("Expected value not returned from GetProbes")
 		t.Fail()
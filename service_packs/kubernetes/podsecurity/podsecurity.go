@@ -1,6 +1,7 @@
 package podsecurity
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"strconv"
@@ -22,11 +23,12 @@ import (
 type probeStruct struct {
 }
 
-var conn connection.Connection
-
 // scenarioState holds the steps and state for any scenario in this probe
 type scenarioState struct {
+	settings    *config.Kubernetes
+	conn        connection.Connection
 	name        string
+	ctx         context.Context
 	currentStep string
 	namespace   string
 	probeAudit  *audit.Probe
@@ -37,10 +39,12 @@ type scenarioState struct {
 
 // Probe meets the service pack interface for adding the logic from this file
 var Probe probeStruct
-var scenario scenarioState
 
 func (scenario *scenarioState) createPodfromObject(podObject *apiv1.Pod) (createdPodObject *apiv1.Pod, err error) {
-	createdPodObject, err = conn.CreatePodFromObject(podObject, Probe.Name())
+	createdPodObject, err = scenario.conn.CreatePodFromObject(scenario.ctx, podObject)
+	if err == nil {
+		scenario.probeAudit.CountPodCreated(createdPodObject.ObjectMeta.Name)
+	}
 	if createdPodObject != nil && createdPodObject.ObjectMeta.Name != "" {
 		scenario.pods = append(scenario.pods, createdPodObject.ObjectMeta.Name)
 	}
@@ -59,11 +63,11 @@ func (scenario *scenarioState) aKubernetesClusterIsDeployed() error {
 		KubeConfigPath string
 		KubeContext    string
 	}{
-		config.Vars.ServicePacks.Kubernetes.KubeConfigPath,
-		config.Vars.ServicePacks.Kubernetes.KubeContext,
+		scenario.settings.KubeConfigPath,
+		scenario.settings.KubeContext,
 	}
 
-	err = conn.ClusterIsDeployed() // Must be assigned to 'err' be audited
+	err = scenario.conn.ClusterIsDeployed() // Must be assigned to 'err' be audited
 	return err
 }
 
@@ -76,7 +80,8 @@ func (scenario *scenarioState) toDo(todo string) error {
 	payload = struct {
 		TODO string
 	}{TODO: todo}
-	return godog.ErrPending
+	err = godog.ErrPending
+	return err
 }
 
 // Attempt to deploy a pod from a default pod spec, with specified modification
@@ -101,7 +106,7 @@ func (scenario *scenarioState) podCreationResultsWithXSetToYInThePodSpec(result,
 	}
 
 	stepTrace.WriteString(fmt.Sprintf("Build a pod spec with default values; "))
-	pod := constructors.PodSpec(Probe.Name(), config.Vars.ServicePacks.Kubernetes.ProbeNamespace)
+	pod := constructors.PodSpec(Probe.Name(), scenario.settings.ProbeNamespace, scenario.settings)
 
 	// Any key that expects a non-bool value should have it's own case here to handle the pod modification
 
@@ -130,14 +135,14 @@ func (scenario *scenarioState) podCreationResultsWithXSetToYInThePodSpec(result,
 	switch podShouldCreate {
 	case true:
 		if creationErr != nil {
-			err = utils.ReformatError("Pod creation did not succeed: %v", creationErr)
+			err = utils.ReformatError("Pod creation did not succeed: %w", creationErr)
 		}
 	case false:
 		if creationErr == nil {
 			err = utils.ReformatError("Pod creation succeeded, but should have failed")
 		} else {
 			if !errors.IsStatusCode(403, creationErr) {
-				err = utils.ReformatError("Unexpected error during Pod creation : %v", creationErr)
+				err = utils.ReformatError("Unexpected error during Pod creation : %w", creationErr)
 			}
 		}
 	}
@@ -207,7 +212,7 @@ func (scenario *scenarioState) theExecutionOfAXCommandInsideThePodIsY(cmdType, r
 
 	}
 	stepTrace.WriteString("Attempt to run a command in the pod that was created by the previous step; ")
-	exitCode, stdout, stderr, err := conn.ExecCommand(cmd, scenario.namespace, scenario.pods[0])
+	exitCode, stdout, stderr, err := scenario.conn.ExecCommand(scenario.ctx, cmd, scenario.namespace, scenario.pods[0])
 
 	payload = struct {
 		Command           string
@@ -266,7 +271,7 @@ func (scenario *scenarioState) aXInspectionShouldOnlyShowTheContainerProcesses(i
 		return
 	}
 	entrypoint := strings.Join(constructors.DefaultEntrypoint(), " ")
-	exitCode, stdout, _, err := conn.ExecCommand(command, scenario.namespace, scenario.pods[0])
+	exitCode, stdout, _, err := scenario.conn.ExecCommand(scenario.ctx, command, scenario.namespace, scenario.pods[0])
 
 	if err != nil {
 		// TODO: Validate that this fails as expected
@@ -311,7 +316,7 @@ func (scenario *scenarioState) thePodIPAndHostIPHaveDifferentValues() (err error
 	}()
 
 	stepTrace.WriteString(fmt.Sprintf("Retrieve IP values from created pod; "))
-	podIP, hostIP, err := conn.GetPodIPs(config.Vars.ServicePacks.Kubernetes.ProbeNamespace, scenario.pods[0])
+	podIP, hostIP, err := scenario.conn.GetPodIPs(scenario.ctx, scenario.settings.ProbeNamespace, scenario.pods[0])
 
 	stepTrace.WriteString(fmt.Sprintf("Validate that PodIP and HostIP have different values; "))
 	if err != nil && podIP == hostIP {
@@ -343,62 +348,64 @@ func (probe probeStruct) Path() string {
 // ProbeInitialize handles any overall Test Suite initialisation steps.  This is registered with the
 // test handler as part of the init() function.
 func (probe probeStruct) ProbeInitialize(ctx *godog.TestSuiteContext) {
-	ctx.BeforeSuite(func() {
-		conn = connection.Get()
-	})
-
 	ctx.AfterSuite(func() {
 	})
 }
 
 // ScenarioInitialize initializes the specific test steps
-func (probe probeStruct) ScenarioInitialize(ctx *godog.ScenarioContext) {
+func (probe probeStruct) ScenarioInitialize(ctx context.Context, sc *godog.ScenarioContext) {
+	scenario := &scenarioState{}
 
-	ctx.BeforeScenario(func(s *godog.Scenario) {
-		beforeScenario(&scenario, probe.Name(), s)
+	sc.BeforeScenario(func(s *godog.Scenario) {
+		beforeScenario(ctx, scenario, probe.Name(), s)
 	})
 
 	// Background
-	ctx.Step(`^a Kubernetes cluster exists which we can deploy into$`, scenario.aKubernetesClusterIsDeployed)
+	sc.Step(`^a Kubernetes cluster exists which we can deploy into$`, scenario.aKubernetesClusterIsDeployed)
 
 	// Use for steps that have yet to be written
-	ctx.Step(`^TODO: "([^"]*)"$`, scenario.toDo)
+	sc.Step(`^TODO: "([^"]*)"$`, scenario.toDo)
 
 	// Parameterized Scenarios
-	ctx.Step(`^pod creation "([^"]*)" with "([^"]*)" set to "([^"]*)" in the pod spec$`, scenario.podCreationResultsWithXSetToYInThePodSpec)
-	ctx.Step(`^pod creation "([^"]*)" with "([^"]*)" set to "([^"]*)" in the pod spec$`, scenario.podCreationResultsWithXSetToYInThePodSpec)
-	ctx.Step(`^the execution of a "([^"]*)" command inside the pod is "([^"]*)"$`, scenario.theExecutionOfAXCommandInsideThePodIsY)
-	ctx.Step(`^a "([^"]*)" inspection should only show the container processes$`, scenario.aXInspectionShouldOnlyShowTheContainerProcesses)
-	ctx.Step(`^the PodIP and HostIP have different values$`, scenario.thePodIPAndHostIPHaveDifferentValues)
-
-	ctx.AfterScenario(func(s *godog.Scenario, err error) {
-		afterScenario(scenario, probe, s, err)
+	sc.Step(`^pod creation "([^"]*)" with "([^"]*)" set to "([^"]*)" in the pod spec$`, scenario.podCreationResultsWithXSetToYInThePodSpec)
+	sc.Step(`^pod creation "([^"]*)" with "([^"]*)" set to "([^"]*)" in the pod spec$`, scenario.podCreationResultsWithXSetToYInThePodSpec)
+	sc.Step(`^the execution of a "([^"]*)" command inside the pod is "([^"]*)"$`, scenario.theExecutionOfAXCommandInsideThePodIsY)
+	sc.Step(`^a "([^"]*)" inspection should only show the container processes$`, scenario.aXInspectionShouldOnlyShowTheContainerProcesses)
+	sc.Step(`^the PodIP and HostIP have different values$`, scenario.thePodIPAndHostIPHaveDifferentValues)
+
+	sc.AfterScenario(func(s *godog.Scenario, err error) {
+		afterScenario(*scenario, probe, s, err)
 	})
 
-	ctx.BeforeStep(func(st *godog.Step) {
+	sc.BeforeStep(func(st *godog.Step) {
 		scenario.currentStep = st.Text
 	})
 
-	ctx.AfterStep(func(st *godog.Step, err error) {
+	sc.AfterStep(func(st *godog.Step, err error) {
 		scenario.currentStep = ""
 	})
 }
 
-func beforeScenario(s *scenarioState, probeName string, gs *godog.Scenario) {
+func beforeScenario(ctx context.Context, s *scenarioState, probeName string, gs *godog.Scenario) {
 	s.name = gs.Name
-	s.probeAudit = audit.State.GetProbeLog(probeName)
-	s.audit = audit.State.GetProbeLog(probeName).InitializeAuditor(gs.Name, gs.Tags)
+	s.ctx = ctx
+	s.settings = &config.FromContext(ctx).ServicePacks.Kubernetes
+	s.conn = connection.Get(s.settings)
+	s.probeAudit = audit.ProbeLog(ctx, probeName)
+	s.audit = s.probeAudit.InitializeAuditor(gs.Name, gs.Tags)
 	s.pods = make([]string, 0)
-	s.namespace = config.Vars.ServicePacks.Kubernetes.ProbeNamespace
+	s.namespace = s.settings.ProbeNamespace
 	coreengine.LogScenarioStart(gs)
 }
 
 func afterScenario(scenario scenarioState, probe probeStruct, gs *godog.Scenario, err error) {
-	if config.Vars.ServicePacks.Kubernetes.KeepPods == "false" {
+	if !scenario.settings.KeepPods {
 		for _, podName := range scenario.pods {
-			err = conn.DeletePodIfExists(podName, scenario.namespace, probe.Name())
+			err = scenario.conn.DeletePodIfExists(config.WithVars(context.Background(), config.FromContext(scenario.ctx)), podName, scenario.namespace) // Pods are removed even if the scenario timed out
 			if err != nil {
 				log.Printf(fmt.Sprintf("[ERROR] Could not retrieve pod from namespace '%s' for deletion: %s", scenario.namespace, err))
+			} else {
+				scenario.probeAudit.CountPodDestroyed()
 			}
 		}
 	}
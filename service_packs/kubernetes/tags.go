@@ -0,0 +1,24 @@
+package kubernetes
+
+var (
+	tags = map[string][]string{
+		"@probes/kubernetes/container_registry_access": {"@k-cra"},
+		"@probes/kubernetes/general":                   {"@k-gen"},
+		"@probes/kubernetes/iam":                       {"@k-iam"},
+		"@probes/kubernetes/podsecurity":               {"@k-pod"},
+		"@category/pod_security_policy":                {"@k-pod"},
+		"@category/internet_access":                    {"@k-gen-002", "@k-gen-003"},
+		"@category/iam":                                {"@k-iam"},
+		"@standard/cis/5.2.2":                          {"@k-pod-003", "@k-pod-004"},
+		"@standard/cis/5.2.3":                          {"@k-pod-005", "@k-pod-006"},
+		"@standard/cis/5.2.4":                          {"@k-pod-007", "@k-pod-008"},
+		"@standard/cis/5.2.5":                          {"@k-pod-001", "@k-pod-002"},
+		"@standard/cis/5.2.6":                          {"@k-pod-009", "@k-pod-010"},
+		"@standard/cis/5.2.7":                          {"@k-pod-012", "@k-pod-013"},
+		"@standard/cis/5.7.2":                          {"@k-pod-011"},
+		"@standard/citihub/CHC2-APPDEV135":             {"@k-cra-001"},
+		"@csp/azure":                                   {"@k-iam"},
+		"@control_type/detective":                      {"@k-gen"},
+		"@control_type/preventative":                   {"@k-cra", "@k-iam", "@k-pod"},
+	}
+)
@@ -0,0 +1,183 @@
+package plugin
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/citihub/probr/utils"
+)
+
+var (
+	handshakeTimeout = 30 * time.Second // how long a plugin is given to start and reply to the handshake
+	shutdownTimeout  = 5 * time.Second  // how long a plugin is given to exit after being asked to shut down
+)
+
+// Client manages a single plugin process. Calls are serialized, so a Client may be
+// shared by several probes that are running at the same time.
+type Client struct {
+	Manifest Manifest
+	path     string
+	cmd      *exec.Cmd
+	stdin    io.WriteCloser
+	stdout   *bufio.Reader
+	lock     sync.Mutex
+	nextID   int
+	err      error // set once the plugin has been killed, after which every call fails
+}
+
+// Start runs the plugin at the provided path and completes the handshake
+func Start(path string) (*Client, error) {
+	return start(exec.Command(path))
+}
+
+func start(cmd *exec.Cmd) (*Client, error) {
+	c := &Client{path: cmd.Path, cmd: cmd}
+	cmd.Stderr = os.Stderr // Plugins may log to stderr
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, utils.ReformatError("Could not open stdin for plugin '%s': %v", c.path, err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, utils.ReformatError("Could not open stdout for plugin '%s': %v", c.path, err)
+	}
+	c.stdin = stdin
+	c.stdout = bufio.NewReader(stdout)
+
+	if err = cmd.Start(); err != nil {
+		return nil, utils.ReformatError("Could not start plugin '%s': %v", c.path, err)
+	}
+	if err = c.handshake(); err != nil {
+		c.kill()
+		cmd.Wait()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Client) handshake() error {
+	ctx, cancel := context.WithTimeout(context.Background(), handshakeTimeout)
+	defer cancel()
+	err := c.Call(ctx, MethodHandshake, HandshakeParams{ProtocolVersion: ProtocolVersion}, &c.Manifest)
+	if err != nil {
+		return utils.ReformatError("Handshake with plugin '%s' failed: %v", c.path, err)
+	}
+	if c.Manifest.ProtocolVersion != ProtocolVersion {
+		return utils.ReformatError("Plugin '%s' uses protocol version %v, but probr requires version %v",
+			c.path, c.Manifest.ProtocolVersion, ProtocolVersion)
+	}
+	if c.Manifest.Name == "" {
+		return utils.ReformatError("Plugin '%s' did not provide a service pack name", c.path)
+	}
+	return nil
+}
+
+// Call sends a request to the plugin and decodes the response into result, which may be nil.
+// If ctx is done before the plugin responds, the plugin is killed, as its later responses could
+// not be matched to their requests. Every later call then fails.
+func (c *Client) Call(ctx context.Context, method string, params interface{}, result interface{}) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.err != nil {
+		return c.err
+	}
+	if err := ctx.Err(); err != nil {
+		return utils.InfrastructureError("'%s' request was not sent: %w", method, err)
+	}
+	c.nextID++
+	request := Request{ID: c.nextID, Method: method}
+	if params != nil {
+		p, err := json.Marshal(params)
+		if err != nil {
+			return err
+		}
+		request.Params = p
+	}
+
+	type reply struct {
+		line []byte
+		err  error
+	}
+	replies := make(chan reply, 1) // Left unread if the call is abandoned
+	go func() {
+		line, err := c.roundTrip(request)
+		replies <- reply{line, err}
+	}()
+	var line []byte
+	select {
+	case r := <-replies:
+		if r.err != nil {
+			return r.err
+		}
+		line = r.line
+	case <-ctx.Done():
+		c.kill()
+		c.err = utils.InfrastructureError("Plugin '%s' was stopped, as it did not answer a '%s' request: %w", c.path, method, ctx.Err())
+		return c.err
+	}
+
+	var response Response
+	if err := json.Unmarshal(line, &response); err != nil {
+		return utils.InfrastructureError("Invalid response to '%s' request: %w", method, err)
+	}
+	if response.ID != request.ID {
+		return utils.InfrastructureError("Response ID %v does not match '%s' request ID %v", response.ID, method, request.ID)
+	}
+	if response.Error != "" {
+		return fmt.Errorf("%s", response.Error)
+	}
+	if result != nil && len(response.Result) > 0 {
+		return json.Unmarshal(response.Result, result)
+	}
+	return nil
+}
+
+// roundTrip writes the request to the plugin and reads a line in response, either of which may block
+func (c *Client) roundTrip(request Request) ([]byte, error) {
+	if err := json.NewEncoder(c.stdin).Encode(request); err != nil {
+		return nil, utils.InfrastructureError("Could not send '%s' request: %w", request.Method, err)
+	}
+	line, err := c.stdout.ReadBytes('\n')
+	if err != nil {
+		return nil, utils.InfrastructureError("No response to '%s' request: %w", request.Method, err)
+	}
+	return line, nil
+}
+
+// Close asks the plugin to shut down, and kills the process if it does not exit in time
+func (c *Client) Close() {
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := c.Call(ctx, MethodShutdown, nil, nil); err != nil {
+		log.Printf("[WARN] Plugin '%s' did not acknowledge shutdown: %v", c.Manifest.Name, err)
+	}
+	c.stdin.Close()
+
+	done := make(chan error, 1)
+	go func() { done <- c.cmd.Wait() }()
+	select {
+	case err := <-done:
+		if err != nil {
+			log.Printf("[WARN] Plugin '%s' exited with error: %v", c.Manifest.Name, err)
+		}
+	case <-time.After(shutdownTimeout):
+		log.Printf("[WARN] Plugin '%s' did not exit after %v, killing process", c.Manifest.Name, shutdownTimeout)
+		c.kill()
+	}
+}
+
+func (c *Client) kill() {
+	if c.cmd.Process != nil {
+		c.cmd.Process.Kill()
+	}
+}
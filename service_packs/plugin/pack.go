@@ -0,0 +1,305 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cucumber/godog"
+
+	"github.com/citihub/probr/audit"
+	"github.com/citihub/probr/config"
+	"github.com/citihub/probr/service_packs/coreengine"
+	"github.com/citihub/probr/utils"
+)
+
+var (
+	clients     = make(map[string]*Client)     // running plugins, keyed by plugin path
+	packs       = make(map[string]*pluginPack) // packs registered by plugins, keyed by plugin path
+	users       int                            // calls to LoadPlugins that are yet to be matched by ClosePlugins
+	clientsLock sync.Mutex
+
+	cleanupTimeout = 30 * time.Second // how long a plugin is given to clean up a scenario
+)
+
+// pluginPack is the service pack registered by a running plugin
+type pluginPack struct {
+	name   string
+	dir    string  // tmp directory holding the plugin's feature files, see pluginProbe.Path
+	client *Client // nil once the plugin has been shut down
+}
+
+// LoadPlugins starts every executable in the provided directory and registers each as a service pack.
+// Plugins that cannot be started are logged and skipped. Plugins that are already running are not restarted.
+// Each call must be followed by a call to ClosePlugins once the plugins are no longer needed.
+func LoadPlugins(dir string) {
+	clientsLock.Lock()
+	users++
+	clientsLock.Unlock()
+
+	if dir == "" {
+		return
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		log.Printf("[ERROR] Could not read plugin directory '%s': %v", dir, err)
+		return
+	}
+	for _, f := range files {
+		if f.IsDir() || f.Mode()&0111 == 0 {
+			continue // Only executables are plugins
+		}
+		path := filepath.Join(dir, f.Name())
+		if err := loadPlugin(path); err != nil {
+			log.Printf("[ERROR] Plugin '%s' was not loaded: %v", path, err)
+		}
+	}
+}
+
+func loadPlugin(path string) error {
+	clientsLock.Lock()
+	_, loaded := clients[path]
+	clientsLock.Unlock()
+	if loaded {
+		return nil
+	}
+	// The lock is not held while the plugin starts, so that a slow plugin does not hold up the probes of other plugins
+	c, err := Start(path)
+	if err != nil {
+		return err
+	}
+	loaded, err = register(path, c)
+	if loaded || err != nil {
+		c.Close()
+	}
+	return err
+}
+
+// register records the started plugin and registers its service pack. It reports whether the plugin had
+// already been loaded by another run, in which case c is not used.
+func register(path string, c *Client) (loaded bool, err error) {
+	clientsLock.Lock()
+	defer clientsLock.Unlock()
+
+	if _, loaded := clients[path]; loaded {
+		return true, nil
+	}
+	if err := validateProbeNames(c.Manifest); err != nil {
+		return false, err
+	}
+	if _, err := coreengine.GetServicePack(c.Manifest.Name); err == nil {
+		return false, fmt.Errorf("a service pack named '%s' already exists", c.Manifest.Name)
+	}
+	dir, err := ioutil.TempDir("", "probr-plugin-")
+	if err != nil {
+		return false, err
+	}
+	pack := &pluginPack{name: c.Manifest.Name, dir: dir, client: c}
+	clients[path] = c
+	packs[path] = pack
+	coreengine.RegisterServicePack(servicePack(pack))
+	log.Printf("[INFO] Loaded service pack '%s' from plugin '%s'", c.Manifest.Name, path)
+	return false, nil
+}
+
+// validateProbeNames ensures that each probe in the manifest has a unique name that can be used as a file name,
+// as the name is used for the probe's feature file and audit key
+func validateProbeNames(m Manifest) error {
+	names := make(map[string]bool)
+	for _, p := range m.Probes {
+		if p.Name == "" || p.Name == "." || strings.Contains(p.Name, "..") || strings.ContainsAny(p.Name, `/\`) {
+			return fmt.Errorf("invalid probe name '%s' in manifest of service pack '%s'", p.Name, m.Name)
+		}
+		if names[p.Name] {
+			return fmt.Errorf("duplicate probe name '%s' in manifest of service pack '%s'", p.Name, m.Name)
+		}
+		names[p.Name] = true
+	}
+	return nil
+}
+
+// ClosePlugins shuts down all running plugins once every call to LoadPlugins has been matched by a call
+// to ClosePlugins, so that a run can not stop the plugins of another. Their service packs are unregistered
+// until the plugins are loaded again.
+func ClosePlugins() {
+	clientsLock.Lock()
+	defer clientsLock.Unlock()
+
+	if users--; users > 0 {
+		return
+	}
+	users = 0
+	var paths []string
+	for path := range clients {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		pack := packs[path]
+		coreengine.UnregisterServicePack(pack.name)
+		pack.client = nil // Probes that are still held by a caller can no longer reach the plugin
+		clients[path].Close()
+		delete(clients, path)
+		delete(packs, path)
+		os.RemoveAll(pack.dir)
+	}
+}
+
+func servicePack(pp *pluginPack) coreengine.ServicePack {
+	c := pp.client
+	var probes []coreengine.Probe
+	for _, p := range c.Manifest.Probes {
+		probes = append(probes, &pluginProbe{pack: pp, manifest: p})
+	}
+	pack := coreengine.ServicePack{
+		Name:   c.Manifest.Name,
+		Probes: map[string][]coreengine.Probe{c.Manifest.Provider: probes},
+		Tags:   c.Manifest.Tags,
+	}
+	if c.Manifest.Provider != "" {
+		provider := c.Manifest.Provider
+		pack.Provider = func(*config.VarOptions) string { return provider }
+	}
+	return pack
+}
+
+// pluginProbe meets the coreengine.Probe interface for a probe that is provided by a plugin.
+// Every step in the feature is sent to the plugin to be evaluated.
+type pluginProbe struct {
+	pack     *pluginPack
+	manifest ProbeManifest
+}
+
+// scenarioState holds the state for a single scenario in a plugin probe
+type scenarioState struct {
+	name        string
+	ctx         context.Context
+	currentStep string
+	audit       *audit.ScenarioAudit
+	prepareErr  error // returned by the first step if the plugin failed to prepare the scenario
+}
+
+// Name returns this probe's name
+func (p *pluginProbe) Name() string {
+	return p.manifest.Name
+}
+
+// client returns the running process of the plugin that provides the probe
+func (p *pluginProbe) client() *Client {
+	clientsLock.Lock()
+	defer clientsLock.Unlock()
+	return p.pack.client
+}
+
+// Path writes the feature provided by the plugin to the plugin's tmp directory, and returns its path
+func (p *pluginProbe) Path() string {
+	path := filepath.Join(p.pack.dir, p.Name()+".feature")
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err == nil {
+		err = ioutil.WriteFile(path, []byte(p.manifest.Feature), 0644)
+	}
+	if err != nil {
+		log.Printf("[ERROR] Could not write feature for plugin probe '%s': %v", p.Name(), err)
+		return ""
+	}
+	return path
+}
+
+// ProbeInitialize handles any overall Test Suite initialisation steps
+func (p *pluginProbe) ProbeInitialize(ctx *godog.TestSuiteContext) {}
+
+// ScenarioInitialize registers a single step definition that forwards every step to the plugin
+func (p *pluginProbe) ScenarioInitialize(ctx context.Context, sc *godog.ScenarioContext) {
+	state := &scenarioState{ctx: ctx}
+	client := p.client()
+	if client == nil {
+		// The step definition is still registered, so that each step reports the plugin as unavailable
+		sc.Step(`^(.*)$`, func(step string) error {
+			return utils.InfrastructureError("Plugin for service pack '%s' is not running", p.pack.name)
+		})
+		return
+	}
+
+	sc.BeforeScenario(func(s *godog.Scenario) {
+		state.name = s.Name
+		state.audit = audit.ProbeLog(ctx, p.Name()).InitializeAuditor(s.Name, s.Tags)
+		var tags []string
+		for _, t := range s.Tags {
+			tags = append(tags, t.Name)
+		}
+		err := client.Call(ctx, MethodBeforeScenario, ScenarioParams{Probe: p.Name(), Scenario: s.Name, Tags: tags}, nil)
+		state.prepareErr = nil
+		if err != nil {
+			// The steps are not sent to a plugin that failed to prepare, as their results could not be trusted
+			state.prepareErr = utils.InfrastructureError("Plugin '%s' failed to prepare scenario '%s': %w", p.pack.name, s.Name, err)
+			log.Print(state.prepareErr)
+		}
+		coreengine.LogScenarioStart(s)
+	})
+
+	sc.Step(`^(.*)$`, func(step string) error {
+		return p.runStep(client, state, step)
+	})
+
+	sc.AfterScenario(func(s *godog.Scenario, err error) {
+		// The scenario may have timed out, but the plugin is still given the chance to clean up
+		cleanupCtx, cancel := context.WithTimeout(context.Background(), cleanupTimeout)
+		defer cancel()
+		err = client.Call(cleanupCtx, MethodAfterScenario, ScenarioParams{Probe: p.Name(), Scenario: s.Name}, nil)
+		if err != nil {
+			log.Printf("[ERROR] Plugin '%s' failed to clean up scenario '%s': %v", p.pack.name, s.Name, err)
+		}
+		coreengine.LogScenarioEnd(s)
+	})
+
+	sc.BeforeStep(func(st *godog.Step) {
+		state.currentStep = st.Text
+	})
+
+	sc.AfterStep(func(st *godog.Step, err error) {
+		state.currentStep = ""
+	})
+}
+
+func (p *pluginProbe) runStep(client *Client, state *scenarioState, step string) error {
+	var result StepResult
+	err := state.prepareErr
+	if err == nil {
+		err = client.Call(state.ctx, MethodRunStep, StepParams{Probe: p.Name(), Scenario: state.name, Step: step}, &result)
+	}
+	if err == nil {
+		switch result.Result {
+		case StepPassed:
+		case StepFailed:
+			err = errors.New(result.Error)
+		case StepGivenNotMet:
+			err = utils.GivenNotMet("%s", result.Error)
+		case StepInconclusive:
+			err = utils.InfrastructureError("%s", result.Error)
+		case StepPending:
+			err = godog.ErrPending
+		default:
+			err = fmt.Errorf("plugin returned unknown step result '%s'", result.Result)
+		}
+	}
+
+	function := result.Function
+	if function == "" {
+		function = fmt.Sprintf("%s/%s", p.pack.name, p.Name())
+	}
+	var payload interface{}
+	if len(result.Payload) > 0 {
+		payload = json.RawMessage(result.Payload)
+	}
+	state.audit.AuditStep(function, state.currentStep, result.Description, payload, err)
+	return err
+}
@@ -0,0 +1,286 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/citihub/probr/audit"
+	"github.com/citihub/probr/config"
+	"github.com/citihub/probr/service_packs/coreengine"
+	"github.com/citihub/probr/utils"
+)
+
+// fakePluginEnv causes the test binary to act as a plugin, see TestMain
+const fakePluginEnv = "PROBR_FAKE_PLUGIN"
+
+const fakeFeature = `@fake
+Feature: Fake plugin probe
+
+  Scenario: A control that is met
+    Given the fake service exists
+    Then the control is met
+
+  Scenario: A control that is not met
+    Given the fake service exists
+    Then the control is not met
+`
+
+type fakePlugin struct{}
+
+func (p fakePlugin) Manifest() Manifest {
+	return Manifest{
+		Name:   os.Getenv(fakePluginEnv),
+		Probes: []ProbeManifest{{Name: "fake_probe", Feature: fakeFeature}},
+	}
+}
+
+func (p fakePlugin) BeforeScenario(params ScenarioParams) error {
+	if os.Getenv(fakePluginEnv) == "unprepared_pack" {
+		return fmt.Errorf("the fake service could not be prepared")
+	}
+	return nil
+}
+
+func (p fakePlugin) AfterScenario(params ScenarioParams) error { return nil }
+
+func (p fakePlugin) RunStep(params StepParams) StepResult {
+	switch params.Step {
+	case "the fake service exists", "the control is met":
+		return StepResult{Result: StepPassed, Description: "step ran in fake plugin;", Payload: []byte(`{"step":"ok"}`)}
+	case "the control is not met":
+		return StepResult{Result: StepFailed, Error: "control was not met"}
+	case "the plugin hangs":
+		time.Sleep(time.Minute)
+	}
+	return StepResult{Result: StepPending}
+}
+
+func TestMain(m *testing.M) {
+	if os.Getenv(fakePluginEnv) != "" {
+		if err := Serve(fakePlugin{}); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// fakePluginCommand re-runs the test binary as a plugin with the provided pack name
+func fakePluginCommand(packName string) *exec.Cmd {
+	cmd := exec.Command(os.Args[0])
+	cmd.Env = append(os.Environ(), fakePluginEnv+"="+packName)
+	return cmd
+}
+
+func TestClient_Handshake(t *testing.T) {
+	c, err := start(fakePluginCommand("handshake_pack"))
+	if err != nil {
+		t.Fatalf("Could not start fake plugin: %v", err)
+	}
+	defer c.Close()
+
+	if c.Manifest.Name != "handshake_pack" || c.Manifest.ProtocolVersion != ProtocolVersion {
+		t.Errorf("Unexpected manifest: %+v", c.Manifest)
+	}
+	if len(c.Manifest.Probes) != 1 || c.Manifest.Probes[0].Feature != fakeFeature {
+		t.Errorf("Probe manifest was not received: %+v", c.Manifest.Probes)
+	}
+
+	var result StepResult
+	err = c.Call(context.Background(), MethodRunStep, StepParams{Probe: "fake_probe", Step: "the control is not met"}, &result)
+	if err != nil || result.Result != StepFailed || result.Error != "control was not met" {
+		t.Errorf("Unexpected step result: %+v, %v", result, err)
+	}
+	if err = c.Call(context.Background(), "unknown_method", nil, nil); err == nil {
+		t.Errorf("Expected an error for an unknown method")
+	}
+}
+
+func TestClient_CallDeadline(t *testing.T) {
+	c, err := start(fakePluginCommand("deadline_pack"))
+	if err != nil {
+		t.Fatalf("Could not start fake plugin: %v", err)
+	}
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	started := time.Now()
+	err = c.Call(ctx, MethodRunStep, StepParams{Probe: "fake_probe", Step: "the plugin hangs"}, nil)
+	if !utils.IsInfrastructureError(err) || time.Since(started) > 10*time.Second {
+		t.Fatalf("Expected the call to fail with an infrastructure error once its deadline passed, got %v after %v", err, time.Since(started))
+	}
+	// The plugin was killed, so later calls fail without waiting for it
+	err = c.Call(context.Background(), MethodRunStep, StepParams{Probe: "fake_probe", Step: "the control is met"}, nil)
+	if !utils.IsInfrastructureError(err) {
+		t.Errorf("Expected calls to a killed plugin to fail with an infrastructure error, got %v", err)
+	}
+}
+
+func TestClient_HandshakeTimeout(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires the sleep command")
+	}
+	defer func(timeout time.Duration) { handshakeTimeout = timeout }(handshakeTimeout)
+	handshakeTimeout = 100 * time.Millisecond
+
+	started := time.Now()
+	if _, err := start(exec.Command("sleep", "30")); err == nil {
+		t.Errorf("Expected a plugin that does not reply to the handshake to be rejected")
+	}
+	if time.Since(started) > 10*time.Second {
+		t.Errorf("The plugin was not killed when the handshake timed out")
+	}
+}
+
+func TestServe_ProtocolMismatch(t *testing.T) {
+	in := strings.NewReader(`{"id":1,"method":"handshake","params":{"protocol_version":999}}` + "\n")
+	var out strings.Builder
+	if err := serve(fakePlugin{}, in, &out); err != nil {
+		t.Fatalf("serve() returned error: %v", err)
+	}
+	if !strings.Contains(out.String(), "unsupported protocol version") {
+		t.Errorf("Expected protocol version to be rejected, got: %s", out.String())
+	}
+}
+
+func TestValidateProbeNames(t *testing.T) {
+	tests := []struct {
+		testName string
+		names    []string
+		valid    bool
+	}{
+		{testName: "ValidNames", names: []string{"fake_probe", "other-probe"}, valid: true},
+		{testName: "Empty", names: []string{""}},
+		{testName: "ParentDirectory", names: []string{"../../x"}},
+		{testName: "DotDot", names: []string{".."}},
+		{testName: "Slash", names: []string{"sub/probe"}},
+		{testName: "Backslash", names: []string{`sub\probe`}},
+		{testName: "Duplicate", names: []string{"fake_probe", "fake_probe"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			m := Manifest{Name: "fake_plugin_pack"}
+			for _, name := range tt.names {
+				m.Probes = append(m.Probes, ProbeManifest{Name: name})
+			}
+			if err := validateProbeNames(m); (err == nil) != tt.valid {
+				t.Errorf("validateProbeNames(%v) = %v, expected valid: %v", tt.names, err, tt.valid)
+			}
+		})
+	}
+}
+
+func TestLoadPlugins(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugin wrapper script requires a POSIX shell")
+	}
+	dir, err := ioutil.TempDir("", "probr-plugins")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	vars, _ := config.NewConfig("")
+	vars.WriteDirectory = dir
+	vars.OutputType = "INMEM"
+	vars.ResultsFormat = "cucumber"
+
+	// A non-executable file should be ignored
+	ioutil.WriteFile(filepath.Join(dir, "README"), []byte("not a plugin"), 0644)
+	script := fmt.Sprintf("#!/bin/sh\n%s=fake_plugin_pack exec '%s'\n", fakePluginEnv, os.Args[0])
+	ioutil.WriteFile(filepath.Join(dir, "fake-plugin"), []byte(script), 0755)
+
+	LoadPlugins(dir)
+	LoadPlugins(dir) // Loading the same plugins again should not restart or re-register them
+	ClosePlugins()   // The plugins are still needed by the first call to LoadPlugins
+
+	pack, err := coreengine.GetServicePack("fake_plugin_pack")
+	if err != nil {
+		t.Fatalf("Plugin service pack was not registered: %v", err)
+	}
+	runPluginProbe(t, &vars, pack)
+	ClosePlugins()
+
+	if _, err := coreengine.GetServicePack("fake_plugin_pack"); err == nil {
+		t.Errorf("Plugin service pack should be unregistered once the plugin has been shut down")
+	}
+
+	// A later run may load the plugins again
+	LoadPlugins(dir)
+	defer ClosePlugins()
+	if pack, err = coreengine.GetServicePack("fake_plugin_pack"); err != nil {
+		t.Fatalf("Plugin service pack was not registered again: %v", err)
+	}
+	runPluginProbe(t, &vars, pack)
+}
+
+func TestPluginProbe_PrepareFails(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("plugin wrapper script requires a POSIX shell")
+	}
+	dir, err := ioutil.TempDir("", "probr-plugins")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	vars, _ := config.NewConfig("")
+	vars.WriteDirectory = dir
+	vars.OutputType = "INMEM"
+	vars.ResultsFormat = "cucumber"
+
+	script := fmt.Sprintf("#!/bin/sh\n%s=unprepared_pack exec '%s'\n", fakePluginEnv, os.Args[0])
+	ioutil.WriteFile(filepath.Join(dir, "unprepared-plugin"), []byte(script), 0755)
+	LoadPlugins(dir)
+	defer ClosePlugins()
+
+	pack, err := coreengine.GetServicePack("unprepared_pack")
+	if err != nil {
+		t.Fatalf("Plugin service pack was not registered: %v", err)
+	}
+	probes := pack.GetProbes(&vars)
+	summary := audit.NewSummary(&vars)
+	ps := coreengine.NewProbeStore(&vars, summary)
+	ps.AddProbe(coreengine.NewGodogProbe(pack.Identity(&vars), probes[0], vars.TmpDir()))
+	if s, _ := ps.ExecAllProbes(context.Background()); s != coreengine.ExitInconclusive {
+		t.Errorf("ExecAllProbes() = %v, expected %v as the plugin could not prepare its scenarios", s, coreengine.ExitInconclusive)
+	}
+
+	probeLog := summary.GetProbeLog("unprepared_pack/fake_probe")
+	if probeLog.Result != "Inconclusive" || probeLog.ScenariosSucceeded != 0 || probeLog.ScenariosFailed != 0 {
+		t.Errorf("Expected every scenario to be inconclusive, got %+v", probeLog)
+	}
+}
+
+func runPluginProbe(t *testing.T, vars *config.VarOptions, pack coreengine.ServicePack) {
+	probes := pack.GetProbes(vars)
+	if len(probes) != 1 {
+		t.Fatalf("Expected 1 probe from plugin, got %v", len(probes))
+	}
+
+	summary := audit.NewSummary(vars)
+	ps := coreengine.NewProbeStore(vars, summary)
+	ps.AddProbe(coreengine.NewGodogProbe(pack.Identity(vars), probes[0], vars.TmpDir()))
+	s, err := ps.ExecAllProbes(context.Background())
+	if s == 0 || err != nil {
+		t.Errorf("ExecAllProbes() = %v, %v; expected a failure status from the failing scenario", s, err)
+	}
+
+	probeLog := summary.GetProbeLog("fake_plugin_pack/fake_probe")
+	if probeLog.ScenariosAttempted != 2 || probeLog.ScenariosSucceeded != 1 || probeLog.ScenariosFailed != 1 {
+		t.Errorf("Plugin results were not audited: %+v", probeLog)
+	}
+	if probeLog.Result != "Failed" {
+		t.Errorf("Expected probe result 'Failed', got '%s'", probeLog.Result)
+	}
+}
@@ -0,0 +1,93 @@
+// Package plugin allows service packs to be run out-of-process. A plugin is an executable
+// that is found in the configured PluginDirectory and speaks the protocol defined below
+// over its stdin and stdout. Each message is a single line of JSON.
+//
+// Probr starts each plugin and sends a handshake. The plugin replies with a Manifest that
+// lists its probes and their feature files. Probr then runs the features with godog, as it would
+// for a built-in pack, and asks the plugin to evaluate each step. Step results are recorded in
+// the audit summary of the run and the probe status is tracked by the coreengine.ProbeStore.
+package plugin
+
+import (
+	"encoding/json"
+)
+
+// ProtocolVersion is incremented whenever a breaking change is made to the messages below.
+// Plugins must reply to the handshake with the same version, or they will not be loaded.
+const ProtocolVersion = 1
+
+// Methods that may be sent from probr to a plugin
+const (
+	MethodHandshake      = "handshake"
+	MethodBeforeScenario = "before_scenario"
+	MethodRunStep        = "run_step"
+	MethodAfterScenario  = "after_scenario"
+	MethodShutdown       = "shutdown"
+)
+
+// Step results that may be returned by a plugin
+const (
+	StepPassed  = "Passed"
+	StepFailed  = "Failed"
+	StepPending = "Pending"
+
+	StepInconclusive = "Inconclusive"  // The step could not evaluate the control, e.g. because a cloud API was unreachable
+	StepGivenNotMet  = "Given Not Met" // A Given step found that the scenario's precondition does not hold
+)
+
+// Request is sent from probr to the plugin
+type Request struct {
+	ID     int             `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+// Response is sent from the plugin to probr, and must contain the ID of the request it answers
+type Response struct {
+	ID     int             `json:"id"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// HandshakeParams are sent with MethodHandshake
+type HandshakeParams struct {
+	ProtocolVersion int `json:"protocol_version"`
+}
+
+// Manifest is returned by the plugin in response to MethodHandshake
+type Manifest struct {
+	ProtocolVersion int                 `json:"protocol_version"`
+	Name            string              `json:"name"`               // Service pack name, must not clash with any other pack
+	Provider        string              `json:"provider,omitempty"` // Optional provider variant, e.g. "Azure"
+	Probes          []ProbeManifest     `json:"probes"`
+	Tags            map[string][]string `json:"tags,omitempty"` // Optional tag aliases, e.g. {"@csp/azure": ["@my-tag"]}
+}
+
+// ProbeManifest describes a single probe provided by a plugin
+type ProbeManifest struct {
+	Name    string `json:"name"`
+	Feature string `json:"feature"` // Content of the probe's gherkin feature file
+}
+
+// ScenarioParams are sent with MethodBeforeScenario and MethodAfterScenario
+type ScenarioParams struct {
+	Probe    string   `json:"probe"`
+	Scenario string   `json:"scenario"`
+	Tags     []string `json:"tags,omitempty"`
+}
+
+// StepParams are sent with MethodRunStep
+type StepParams struct {
+	Probe    string `json:"probe"`
+	Scenario string `json:"scenario"`
+	Step     string `json:"step"` // Step text, without the gherkin keyword
+}
+
+// StepResult is returned by the plugin in response to MethodRunStep
+type StepResult struct {
+	Result      string          `json:"result"`             // One of the Step* results above
+	Function    string          `json:"function,omitempty"` // Name of the function that implements the step, for the audit
+	Description string          `json:"description,omitempty"`
+	Payload     json.RawMessage `json:"payload,omitempty"`
+	Error       string          `json:"error,omitempty"` // Reason the step did not pass
+}
@@ -0,0 +1,87 @@
+package plugin
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Plugin is implemented by out-of-process service packs, and is made available to probr by Serve
+type Plugin interface {
+	Manifest() Manifest
+	BeforeScenario(params ScenarioParams) error
+	RunStep(params StepParams) StepResult
+	AfterScenario(params ScenarioParams) error
+}
+
+// Serve answers requests from probr on stdin and stdout until probr asks the plugin to shut down.
+// It should be called from the plugin's main function.
+func Serve(p Plugin) error {
+	return serve(p, os.Stdin, os.Stdout)
+}
+
+func serve(p Plugin, in io.Reader, out io.Writer) error {
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024) // Allow for large payloads
+	encoder := json.NewEncoder(out)
+
+	for scanner.Scan() {
+		var request Request
+		if err := json.Unmarshal(scanner.Bytes(), &request); err != nil {
+			return fmt.Errorf("invalid request from probr: %v", err)
+		}
+		result, err := handle(p, request)
+		response := Response{ID: request.ID}
+		if err != nil {
+			response.Error = err.Error()
+		} else if result != nil {
+			if response.Result, err = json.Marshal(result); err != nil {
+				response.Error = err.Error()
+			}
+		}
+		if err = encoder.Encode(response); err != nil {
+			return err
+		}
+		if request.Method == MethodShutdown {
+			return nil
+		}
+	}
+	return scanner.Err()
+}
+
+func handle(p Plugin, request Request) (interface{}, error) {
+	switch request.Method {
+	case MethodHandshake:
+		var params HandshakeParams
+		if err := json.Unmarshal(request.Params, &params); err != nil {
+			return nil, err
+		}
+		if params.ProtocolVersion != ProtocolVersion {
+			return nil, fmt.Errorf("unsupported protocol version %v, plugin requires version %v", params.ProtocolVersion, ProtocolVersion)
+		}
+		m := p.Manifest()
+		m.ProtocolVersion = ProtocolVersion
+		return m, nil
+	case MethodBeforeScenario, MethodAfterScenario:
+		var params ScenarioParams
+		if err := json.Unmarshal(request.Params, &params); err != nil {
+			return nil, err
+		}
+		if request.Method == MethodBeforeScenario {
+			return nil, p.BeforeScenario(params)
+		}
+		return nil, p.AfterScenario(params)
+	case MethodRunStep:
+		var params StepParams
+		if err := json.Unmarshal(request.Params, &params); err != nil {
+			return nil, err
+		}
+		return p.RunStep(params), nil
+	case MethodShutdown:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown method '%s'", request.Method)
+	}
+}
@@ -1,39 +1,34 @@
 package servicepacks
 
 import (
-	"github.com/citihub/probr/service_packs/apim"
+	"github.com/citihub/probr/config"
 	"github.com/citihub/probr/service_packs/coreengine"
-	"github.com/citihub/probr/service_packs/kubernetes"
-	"github.com/citihub/probr/service_packs/storage"
-)
-
-func packs() (packs map[string][]coreengine.Probe) {
-	packs = make(map[string][]coreengine.Probe)
+	"github.com/citihub/probr/service_packs/plugin"
 
-	packs["kubernetes"] = kubernetes.GetProbes()
-	packs["storage"] = storage.GetProbes()
-	packs["apim"] = apim.GetProbes()
+	// Service packs register themselves with coreengine when imported
+	_ "github.com/citihub/probr/service_packs/apim"
+	_ "github.com/citihub/probr/service_packs/kubernetes"
+	_ "github.com/citihub/probr/service_packs/storage"
+)
 
-	return
+// LoadPlugins registers a service pack for each plugin found in the PluginDirectory of the config
+func LoadPlugins(vars *config.VarOptions) {
+	plugin.LoadPlugins(vars.PluginDirectory)
 }
 
-func makeGodogProbe(pack string, p coreengine.Probe) *coreengine.GodogProbe {
-	descriptor := coreengine.ProbeDescriptor{Group: coreengine.Kubernetes, Name: p.Name()}
-	return &coreengine.GodogProbe{
-		ProbeDescriptor:     &descriptor,
-		ProbeInitializer:    p.ProbeInitialize,
-		ScenarioInitializer: p.ScenarioInitialize,
-		FeaturePath:         p.Path(),
-	}
+// ClosePlugins shuts down any plugins that were started by LoadPlugins, once every call to it has been matched
+func ClosePlugins() {
+	plugin.ClosePlugins()
 }
 
-// GetAllProbes returns a list of probes that are ready to be run by Godog
-func GetAllProbes() []*coreengine.GodogProbe {
+// GetAllProbes returns a list of probes that are ready to be run by Godog using the given config.
+// Bundled feature files are unpacked into tmpDir.
+func GetAllProbes(vars *config.VarOptions, tmpDir string) []*coreengine.GodogProbe {
 	var allProbes []*coreengine.GodogProbe
 
-	for packName, pack := range packs() {
-		for _, probe := range pack {
-			allProbes = append(allProbes, makeGodogProbe(packName, probe))
+	for _, pack := range coreengine.GetServicePacks() {
+		for _, probe := range pack.GetProbes(vars) {
+			allProbes = append(allProbes, coreengine.NewGodogProbe(pack.Identity(vars), probe, tmpDir))
 		}
 	}
 	return allProbes
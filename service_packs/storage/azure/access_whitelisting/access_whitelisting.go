@@ -68,23 +68,24 @@ func (state *scenarioState) anAzureResourceGroupExists() error {
 		AzureSubscriptionID string
 		AzureResourceGroup  string
 	}{
-		AzureSubscriptionID: azureutil.SubscriptionID(),
-		AzureResourceGroup:  azureutil.ResourceGroup(),
+		AzureSubscriptionID: azureutil.SubscriptionID(state.ctx),
+		AzureResourceGroup:  azureutil.ResourceGroup(state.ctx),
 	}
 	defer func() {
 		state.audit.AuditScenarioStep(state.currentStep, stepTrace.String(), payload, err)
 	}()
 
 	stepTrace.WriteString("Check if value for Azure resource group is set in config vars;")
-	if azureutil.ResourceGroup() == "" {
+	if azureutil.ResourceGroup(state.ctx) == "" {
 		log.Printf("[ERROR] Azure resource group config var not set")
 		err = errors.New("Azure resource group config var not set")
 	}
 	if err == nil {
 		stepTrace.WriteString("Check the resource group exists in the specified azure subscription;")
-		_, err = group.Get(state.ctx, azureutil.ResourceGroup())
+		_, err = group.Get(state.ctx, azureutil.ResourceGroup(state.ctx))
 		if err != nil {
-			log.Printf("[ERROR] Configured Azure resource group %s does not exists", azureutil.ResourceGroup())
+			log.Printf("[ERROR] Configured Azure resource group %s does not exists", azureutil.ResourceGroup(state.ctx))
+			err = utils.InfrastructureError("Unable to retrieve Azure resource group %s: %v", azureutil.ResourceGroup(state.ctx), err)
 		}
 	}
 
@@ -109,14 +110,14 @@ func (state *scenarioState) checkPolicyAssigned() error {
 
 	if state.policyAssignmentMgmtGroup == "" {
 		stepTrace.WriteString("Management Group has not been set, check Policy Assignment at the Subscription;")
-		a, err = policy.AssignmentBySubscription(state.ctx, azureutil.SubscriptionID(), policyAssignmentName)
+		a, err = policy.AssignmentBySubscription(state.ctx, azureutil.SubscriptionID(state.ctx), policyAssignmentName)
 	} else {
 		stepTrace.WriteString("Check Policy Assignment at the Management Group;")
 		a, err = policy.AssignmentByManagementGroup(state.ctx, state.policyAssignmentMgmtGroup, policyAssignmentName)
 	}
 
 	//Audit log
-	payload.AzureSubscriptionID = azureutil.SubscriptionID()
+	payload.AzureSubscriptionID = azureutil.SubscriptionID(state.ctx)
 	payload.ManagamentGroup = state.policyAssignmentMgmtGroup
 	payload.PolicyAssignmentName = policyAssignmentName
 	payload.PolicyAssignment = a
@@ -193,12 +194,12 @@ func (state *scenarioState) createWithWhitelist(ipRange string) error {
 	}
 
 	stepTrace.WriteString("Creating storage bucket with Network Rule Set within Resource Group;")
-	state.storageAccount, state.runningErr = connection.CreateWithNetworkRuleSet(state.ctx, state.bucketName, azureutil.ResourceGroup(), state.tags, true, &networkRuleSet)
+	state.storageAccount, state.runningErr = connection.CreateWithNetworkRuleSet(state.ctx, state.bucketName, azureutil.ResourceGroup(state.ctx), state.tags, true, &networkRuleSet)
 
 	//Audit log
 	err = state.runningErr
-	payload.SubscriptionID = azureutil.SubscriptionID()
-	payload.ResourceGroup = azureutil.ResourceGroup()
+	payload.SubscriptionID = azureutil.SubscriptionID(state.ctx)
+	payload.ResourceGroup = azureutil.ResourceGroup(state.ctx)
 	payload.BucketName = state.bucketName
 	payload.IPRange = ipRange
 	payload.NetworkRuleSet = networkRuleSet
@@ -255,7 +256,7 @@ func (state *scenarioState) cspSupportsWhitelisting() error {
 		state.audit.AuditScenarioStep(state.currentStep, stepTrace.String(), payload, err)
 	}()
 
-	err = fmt.Errorf("Not Implemented")
+	err = godog.ErrPending
 
 	stepTrace.WriteString("TODO: Pending implementation;")
 
@@ -346,7 +347,7 @@ func (state *scenarioState) whitelistingIsConfigured() error {
 		state.audit.AuditScenarioStep(state.currentStep, stepTrace.String(), payload, err)
 	}()
 
-	err = fmt.Errorf("Not Implemented")
+	err = godog.ErrPending
 
 	stepTrace.WriteString("TODO: Pending implementation;")
 
@@ -354,11 +355,11 @@ func (state *scenarioState) whitelistingIsConfigured() error {
 	return nil //TODO: Remove this line. This is temporary to ensure test doesn't halt and other steps are not skipped
 }
 
-func (state *scenarioState) beforeScenario(probeName string, gs *godog.Scenario) {
+func (state *scenarioState) beforeScenario(ctx context.Context, probeName string, gs *godog.Scenario) {
 	state.name = gs.Name
-	state.probe = audit.State.GetProbeLog(probeName)
-	state.audit = audit.State.GetProbeLog(probeName).InitializeAuditor(gs.Name, gs.Tags)
-	state.ctx = context.Background()
+	state.ctx = ctx
+	state.probe = audit.ProbeLog(ctx, probeName)
+	state.audit = state.probe.InitializeAuditor(gs.Name, gs.Tags)
 	coreengine.LogScenarioStart(gs)
 }
 
@@ -384,30 +385,30 @@ func (p ProbeStruct) ProbeInitialize(ctx *godog.TestSuiteContext) {
 }
 
 // ScenarioInitialize initialises the scenario
-func (p ProbeStruct) ScenarioInitialize(ctx *godog.ScenarioContext) {
+func (p ProbeStruct) ScenarioInitialize(ctx context.Context, sc *godog.ScenarioContext) {
 
-	ctx.BeforeScenario(func(s *godog.Scenario) {
-		p.state.beforeScenario(p.Name(), s)
+	sc.BeforeScenario(func(s *godog.Scenario) {
+		p.state.beforeScenario(ctx, p.Name(), s)
 	})
 
-	ctx.Step(`^the CSP provides a whitelisting capability for Object Storage containers$`, p.state.cspSupportsWhitelisting)
-	ctx.Step(`^a specified azure resource group exists$`, p.state.anAzureResourceGroupExists)
-	ctx.Step(`^we examine the Object Storage container in environment variable "([^"]*)"$`, p.state.examineStorageContainer)
-	ctx.Step(`^whitelisting is configured with the given IP address range or an endpoint$`, p.state.whitelistingIsConfigured)
-	ctx.Step(`^security controls that Prevent Object Storage from being created without network source address whitelisting are applied$`, p.state.checkPolicyAssigned)
-	ctx.Step(`^we provision an Object Storage container$`, p.state.provisionStorageContainer)
-	ctx.Step(`^it is created with whitelisting entry "([^"]*)"$`, p.state.createWithWhitelist)
-	ctx.Step(`^creation will "([^"]*)"$`, p.state.creationWill)
+	sc.Step(`^the CSP provides a whitelisting capability for Object Storage containers$`, p.state.cspSupportsWhitelisting)
+	sc.Step(`^a specified azure resource group exists$`, p.state.anAzureResourceGroupExists)
+	sc.Step(`^we examine the Object Storage container in environment variable "([^"]*)"$`, p.state.examineStorageContainer)
+	sc.Step(`^whitelisting is configured with the given IP address range or an endpoint$`, p.state.whitelistingIsConfigured)
+	sc.Step(`^security controls that Prevent Object Storage from being created without network source address whitelisting are applied$`, p.state.checkPolicyAssigned)
+	sc.Step(`^we provision an Object Storage container$`, p.state.provisionStorageContainer)
+	sc.Step(`^it is created with whitelisting entry "([^"]*)"$`, p.state.createWithWhitelist)
+	sc.Step(`^creation will "([^"]*)"$`, p.state.creationWill)
 
-	ctx.AfterScenario(func(s *godog.Scenario, err error) {
+	sc.AfterScenario(func(s *godog.Scenario, err error) {
 		coreengine.LogScenarioEnd(s)
 	})
 
-	ctx.BeforeStep(func(st *godog.Step) {
+	sc.BeforeStep(func(st *godog.Step) {
 		p.state.currentStep = st.Text
 	})
 
-	ctx.AfterStep(func(st *godog.Step, err error) {
+	sc.AfterStep(func(st *godog.Step, err error) {
 		p.state.currentStep = ""
 	})
 }
@@ -1,74 +1,64 @@
 package azure
 
 import (
+	"context"
 	"log"
 	"os"
 
 	"github.com/citihub/probr/config"
-	"github.com/citihub/probr/utils"
 )
 
-var prefix string
-var rgName string
-
 //TenantID returns the azure Tenant in which the tests should be executed, configured by the user and may be set by the environment variable AZURE_TENANT_ID.
-func TenantID() string {
-	if config.Vars.CloudProviders.Azure.TenantID == "" {
+func TenantID(ctx context.Context) string {
+	if config.FromContext(ctx).CloudProviders.Azure.TenantID == "" {
 		log.Printf("[ERROR] Azure connection config var not set: config.Vars.CloudProviders.Azure.TenantID")
 	}
-	return config.Vars.CloudProviders.Azure.TenantID
+	return config.FromContext(ctx).CloudProviders.Azure.TenantID
 }
 
 //ClientID returns the client (typically a service principal) that must be authorized for performing operations within the azure tenant, configured by the user and may be set by the environment variable AZURE_CLIENT_ID.
-func ClientID() string {
-	if config.Vars.CloudProviders.Azure.ClientID == "" {
+func ClientID(ctx context.Context) string {
+	if config.FromContext(ctx).CloudProviders.Azure.ClientID == "" {
 		log.Printf("[ERROR] Azure connection config var not set: config.Vars.CloudProviders.Azure.ClientID")
 	}
-	return config.Vars.CloudProviders.Azure.ClientID
+	return config.FromContext(ctx).CloudProviders.Azure.ClientID
 }
 
 //ClientSecret returns the client secret to allow client authetication and authorization, configured by the user and may be set by the environment variable AZURE_CLIENT_SECRET.
-func ClientSecret() string {
-	if config.Vars.CloudProviders.Azure.ClientSecret == "" {
+func ClientSecret(ctx context.Context) string {
+	if config.FromContext(ctx).CloudProviders.Azure.ClientSecret == "" {
 		log.Printf("[ERROR] Azure connection config var not set: config.Vars.CloudProviders.Azure.ClientSecret")
 	}
-	return config.Vars.CloudProviders.Azure.ClientSecret
+	return config.FromContext(ctx).CloudProviders.Azure.ClientSecret
 }
 
 //SubscriptionID returns the azure Subscription in which the tests should be executed, configured by the user and may be set by the environment variable AZURE_SUBSCRIPTION_ID.
-func SubscriptionID() string {
-	if config.Vars.CloudProviders.Azure.SubscriptionID == "" {
+func SubscriptionID(ctx context.Context) string {
+	if config.FromContext(ctx).CloudProviders.Azure.SubscriptionID == "" {
 		log.Printf("[ERROR] Azure connection config var not set: config.Vars.CloudProviders.Azure.SubscriptionID")
 	}
-	return config.Vars.CloudProviders.Azure.SubscriptionID
+	return config.FromContext(ctx).CloudProviders.Azure.SubscriptionID
 }
 
 //ResourceGroup returns the Probr user's azure resource group in which resurces should be created fpr testing, configured by the user and may be set by the environment variable AZURE_RESOURCE_GROUP.
-func ResourceGroup() string {
-	if config.Vars.CloudProviders.Azure.ResourceGroup == "" {
+func ResourceGroup(ctx context.Context) string {
+	if config.FromContext(ctx).CloudProviders.Azure.ResourceGroup == "" {
 		log.Printf("[ERROR] Azure connection config var not set: config.Vars.CloudProviders.Azure.ResourceGroup")
 	}
-	return config.Vars.CloudProviders.Azure.ResourceGroup
+	return config.FromContext(ctx).CloudProviders.Azure.ResourceGroup
 }
 
 //ResourceLocation returns the default location in which azure resources should be created, configured by the user and may be set by the environment variable AZURE_LOCATION.
-func ResourceLocation() string {
-	if config.Vars.CloudProviders.Azure.ResourceLocation == "" {
+func ResourceLocation(ctx context.Context) string {
+	if config.FromContext(ctx).CloudProviders.Azure.ResourceLocation == "" {
 		log.Printf("[ERROR] Azure connection config var not set: config.Vars.CloudProviders.Azure.ResourceLocation")
 	}
-	return config.Vars.CloudProviders.Azure.ResourceLocation
+	return config.FromContext(ctx).CloudProviders.Azure.ResourceLocation
 }
 
 //ManagementGroup returns an Azure Management Group which may be used for policy assignment, configured by the user and may be set by the environment variable AZURE_MANAGEMENT_GROUP.
-func ManagementGroup() string {
-	return config.Vars.CloudProviders.Azure.ManagementGroup
-}
-
-func randomPrefix() string {
-	if prefix == "" {
-		prefix = "test" + utils.RandomString(6) + ""
-	}
-	return "test" + utils.RandomString(6) + ""
+func ManagementGroup(ctx context.Context) string {
+	return config.FromContext(ctx).CloudProviders.Azure.ManagementGroup
 }
 
 func getFromEnvVar(varName string) string {
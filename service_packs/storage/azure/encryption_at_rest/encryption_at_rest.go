@@ -2,7 +2,6 @@ package azureear
 
 import (
 	"context"
-	"fmt"
 	"log"
 	"strings"
 
@@ -40,7 +39,7 @@ func (state *scenarioState) securityControlsThatRestrictDataFromBeingUnencrypted
 	defer func() {
 		state.audit.AuditScenarioStep(state.currentStep, stepTrace.String(), payload, err)
 	}()
-	err = fmt.Errorf("Not Implemented")
+	err = godog.ErrPending
 	stepTrace.WriteString("TODO: Pending implementation;")
 
 	// It is available
@@ -59,7 +58,7 @@ func (state *scenarioState) weProvisionAnObjectStorageBucket() error {
 	defer func() {
 		state.audit.AuditScenarioStep(state.currentStep, stepTrace.String(), payload, err)
 	}()
-	err = fmt.Errorf("Not Implemented")
+	err = godog.ErrPending
 	stepTrace.WriteString("TODO: Pending implementation;")
 
 	return nil
@@ -75,7 +74,7 @@ func (state *scenarioState) encryptionAtRestIs(encryptionOption string) error {
 	defer func() {
 		state.audit.AuditScenarioStep(state.currentStep, stepTrace.String(), payload, err)
 	}()
-	err = fmt.Errorf("Not Implemented")
+	err = godog.ErrPending
 	stepTrace.WriteString("TODO: Pending implementation;")
 
 	return nil
@@ -91,7 +90,7 @@ func (state *scenarioState) creationWillWithAnErrorMatching(result string) error
 	defer func() {
 		state.audit.AuditScenarioStep(state.currentStep, stepTrace.String(), payload, err)
 	}()
-	err = fmt.Errorf("Not Implemented")
+	err = godog.ErrPending
 	stepTrace.WriteString("TODO: Pending implementation;")
 
 	return nil
@@ -107,7 +106,7 @@ func (state *scenarioState) createContainerWithoutEncryption() error {
 	defer func() {
 		state.audit.AuditScenarioStep(state.currentStep, stepTrace.String(), payload, err)
 	}()
-	err = fmt.Errorf("Not Implemented")
+	err = godog.ErrPending
 	stepTrace.WriteString("TODO: Pending implementation;")
 
 	return nil
@@ -123,7 +122,7 @@ func (state *scenarioState) detectiveDetectsNonCompliant() error {
 	defer func() {
 		state.audit.AuditScenarioStep(state.currentStep, stepTrace.String(), payload, err)
 	}()
-	err = fmt.Errorf("Not Implemented")
+	err = godog.ErrPending
 	stepTrace.WriteString("TODO: Pending implementation;")
 
 	return nil
@@ -139,7 +138,7 @@ func (state *scenarioState) containerIsRemediated() error {
 	defer func() {
 		state.audit.AuditScenarioStep(state.currentStep, stepTrace.String(), payload, err)
 	}()
-	err = fmt.Errorf("Not Implemented")
+	err = godog.ErrPending
 	stepTrace.WriteString("TODO: Pending implementation;")
 
 	return nil
@@ -163,7 +162,7 @@ func (state *scenarioState) policyOrRuleAvailable() error {
 	defer func() {
 		state.audit.AuditScenarioStep(state.currentStep, stepTrace.String(), payload, err)
 	}()
-	err = fmt.Errorf("Not Implemented")
+	err = godog.ErrPending
 	stepTrace.WriteString("TODO: Pending implementation;")
 
 	// It is available
@@ -181,7 +180,7 @@ func (state *scenarioState) checkPolicyOrRuleAssignment() error {
 	defer func() {
 		state.audit.AuditScenarioStep(state.currentStep, stepTrace.String(), payload, err)
 	}()
-	err = fmt.Errorf("Not Implemented")
+	err = godog.ErrPending
 	stepTrace.WriteString("TODO: Pending implementation;")
 
 	return nil
@@ -197,7 +196,7 @@ func (state *scenarioState) policyOrRuleAssigned() error {
 	defer func() {
 		state.audit.AuditScenarioStep(state.currentStep, stepTrace.String(), payload, err)
 	}()
-	err = fmt.Errorf("Not Implemented")
+	err = godog.ErrPending
 	stepTrace.WriteString("TODO: Pending implementation;")
 
 	return nil
@@ -218,10 +217,11 @@ func (state *scenarioState) createResult(result string) error {
 	return nil
 }
 
-func (state *scenarioState) beforeScenario(probeName string, gs *godog.Scenario) {
+func (state *scenarioState) beforeScenario(ctx context.Context, probeName string, gs *godog.Scenario) {
 	state.name = gs.Name
-	state.probe = audit.State.GetProbeLog(probeName)
-	state.audit = audit.State.GetProbeLog(probeName).InitializeAuditor(gs.Name, gs.Tags)
+	state.ctx = ctx
+	state.probe = audit.ProbeLog(ctx, probeName)
+	state.audit = state.probe.InitializeAuditor(gs.Name, gs.Tags)
 	coreengine.LogScenarioStart(gs)
 }
 
@@ -247,33 +247,33 @@ func (p ProbeStruct) ProbeInitialize(ctx *godog.TestSuiteContext) {
 }
 
 // ScenarioInitialize initialises the scenario
-func (p ProbeStruct) ScenarioInitialize(ctx *godog.ScenarioContext) {
+func (p ProbeStruct) ScenarioInitialize(ctx context.Context, sc *godog.ScenarioContext) {
 
-	ctx.BeforeScenario(func(s *godog.Scenario) {
-		p.state.beforeScenario(p.Name(), s)
+	sc.BeforeScenario(func(s *godog.Scenario) {
+		p.state.beforeScenario(ctx, p.Name(), s)
 	})
 
-	ctx.Step(`^security controls that restrict data from being unencrypted at rest$`, p.state.securityControlsThatRestrictDataFromBeingUnencryptedAtRest)
-	ctx.Step(`^we provision an Object Storage bucket$`, p.state.weProvisionAnObjectStorageBucket)
-	ctx.Step(`^encryption at rest is "([^"]*)"$`, p.state.encryptionAtRestIs)
-	ctx.Step(`^creation will "([^"]*)" with an error matching "([^"]*)"$`, p.state.creationWillWithAnErrorMatching)
+	sc.Step(`^security controls that restrict data from being unencrypted at rest$`, p.state.securityControlsThatRestrictDataFromBeingUnencryptedAtRest)
+	sc.Step(`^we provision an Object Storage bucket$`, p.state.weProvisionAnObjectStorageBucket)
+	sc.Step(`^encryption at rest is "([^"]*)"$`, p.state.encryptionAtRestIs)
+	sc.Step(`^creation will "([^"]*)" with an error matching "([^"]*)"$`, p.state.creationWillWithAnErrorMatching)
 
-	ctx.Step(`^there is a detective capability for creation of Object Storage without encryption at rest$`, p.state.policyOrRuleAvailable)
-	ctx.Step(`^the capability for detecting the creation of Object Storage without encryption at rest is active$`, p.state.checkPolicyOrRuleAssignment)
-	ctx.Step(`^the detective measure is enabled$`, p.state.policyOrRuleAssigned)
-	ctx.Step(`^Object Storage is created with without encryption at rest$`, p.state.createContainerWithoutEncryption)
-	ctx.Step(`^the detective capability detects the creation of Object Storage without encryption at rest$`, p.state.detectiveDetectsNonCompliant)
-	ctx.Step(`^the detective capability enforces encryption at rest on the Object Storage Bucket$`, p.state.containerIsRemediated)
+	sc.Step(`^there is a detective capability for creation of Object Storage without encryption at rest$`, p.state.policyOrRuleAvailable)
+	sc.Step(`^the capability for detecting the creation of Object Storage without encryption at rest is active$`, p.state.checkPolicyOrRuleAssignment)
+	sc.Step(`^the detective measure is enabled$`, p.state.policyOrRuleAssigned)
+	sc.Step(`^Object Storage is created with without encryption at rest$`, p.state.createContainerWithoutEncryption)
+	sc.Step(`^the detective capability detects the creation of Object Storage without encryption at rest$`, p.state.detectiveDetectsNonCompliant)
+	sc.Step(`^the detective capability enforces encryption at rest on the Object Storage Bucket$`, p.state.containerIsRemediated)
 
-	ctx.AfterScenario(func(s *godog.Scenario, err error) {
+	sc.AfterScenario(func(s *godog.Scenario, err error) {
 		coreengine.LogScenarioEnd(s)
 	})
 
-	ctx.BeforeStep(func(st *godog.Step) {
+	sc.BeforeStep(func(st *godog.Step) {
 		p.state.currentStep = st.Text
 	})
 
-	ctx.AfterStep(func(st *godog.Step, err error) {
+	sc.AfterStep(func(st *godog.Step, err error) {
 		p.state.currentStep = ""
 	})
 }
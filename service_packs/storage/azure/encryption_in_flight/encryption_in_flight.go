@@ -13,6 +13,7 @@ import (
 	"github.com/cucumber/godog"
 
 	"github.com/citihub/probr/audit"
+	"github.com/citihub/probr/config"
 	"github.com/citihub/probr/service_packs/coreengine"
 	azureutil "github.com/citihub/probr/service_packs/storage/azure"
 	"github.com/citihub/probr/service_packs/storage/azure/group"
@@ -50,7 +51,8 @@ func (state *scenarioState) setup() {
 func (state *scenarioState) teardown() {
 	for _, account := range state.storageAccounts {
 		log.Printf("[DEBUG] need to delete the storageAccount: %s", account)
-		err := connection.DeleteAccount(state.ctx, azureutil.ResourceGroup(), account)
+		ctx := config.WithVars(context.Background(), config.FromContext(state.ctx)) // Scenario contexts have been cancelled by now
+		err := connection.DeleteAccount(ctx, azureutil.ResourceGroup(ctx), account)
 
 		if err != nil {
 			log.Printf("[ERROR] error deleting the storageAccount: %v", err)
@@ -68,23 +70,24 @@ func (state *scenarioState) anAzureResourceGroupExists() error {
 		AzureSubscriptionID string
 		AzureResourceGroup  string
 	}{
-		AzureSubscriptionID: azureutil.SubscriptionID(),
-		AzureResourceGroup:  azureutil.ResourceGroup(),
+		AzureSubscriptionID: azureutil.SubscriptionID(state.ctx),
+		AzureResourceGroup:  azureutil.ResourceGroup(state.ctx),
 	}
 	defer func() {
 		state.audit.AuditScenarioStep(state.currentStep, stepTrace.String(), payload, err)
 	}()
 
 	stepTrace.WriteString("Check if value for Azure resource group is set in config vars;")
-	if azureutil.ResourceGroup() == "" {
+	if azureutil.ResourceGroup(state.ctx) == "" {
 		log.Printf("[ERROR] Azure resource group config var not set")
 		err = errors.New("Azure resource group config var not set")
 	}
 	if err == nil {
 		stepTrace.WriteString("Check the resource group exists in the specified azure subscription;")
-		_, err = group.Get(state.ctx, azureutil.ResourceGroup())
+		_, err = group.Get(state.ctx, azureutil.ResourceGroup(state.ctx))
 		if err != nil {
-			log.Printf("[ERROR] Configured Azure resource group %s does not exists", azureutil.ResourceGroup())
+			log.Printf("[ERROR] Configured Azure resource group %s does not exists", azureutil.ResourceGroup(state.ctx))
+			err = utils.InfrastructureError("Unable to retrieve Azure resource group %s: %v", azureutil.ResourceGroup(state.ctx), err)
 		}
 	}
 	return err
@@ -99,7 +102,7 @@ func (state *scenarioState) weProvisionAnObjectStorageBucket() error {
 	defer func() {
 		state.audit.AuditScenarioStep(state.currentStep, stepTrace.String(), payload, err)
 	}()
-	err = fmt.Errorf("Not Implemented")
+	err = godog.ErrPending
 	stepTrace.WriteString("TODO: Pending implementation;")
 
 	// Nothing to do here
@@ -178,19 +181,19 @@ func (state *scenarioState) creationWillWithAnErrorMatching(expectation, errDesc
 			"Creating Storage Account with HTTPS: %v;", false))
 		log.Printf("[DEBUG] Creating Storage Account with HTTPS: %v;", false)
 		_, err = connection.CreateWithNetworkRuleSet(state.ctx, accountName,
-			azureutil.ResourceGroup(), state.tags, false, &networkRuleSet)
+			azureutil.ResourceGroup(state.ctx), state.tags, false, &networkRuleSet)
 	} else if state.httpsOption {
 		stepTrace.WriteString(fmt.Sprintf(
 			"Creating Storage Account with HTTPS: %v;", state.httpsOption))
 		log.Printf("[DEBUG] Creating Storage Account with HTTPS: %v", state.httpsOption)
 		_, err = connection.CreateWithNetworkRuleSet(state.ctx, accountName,
-			azureutil.ResourceGroup(), state.tags, state.httpsOption, &networkRuleSet)
+			azureutil.ResourceGroup(state.ctx), state.tags, state.httpsOption, &networkRuleSet)
 	} else if state.httpOption {
 		stepTrace.WriteString(fmt.Sprintf(
 			"Creating Storage Account with HTTPS: %v;", state.httpsOption))
 		log.Printf("[DEBUG] Creating Storage Account with HTTPS: %v", state.httpsOption)
 		_, err = connection.CreateWithNetworkRuleSet(state.ctx, accountName,
-			azureutil.ResourceGroup(), state.tags, state.httpsOption, &networkRuleSet)
+			azureutil.ResourceGroup(state.ctx), state.tags, state.httpsOption, &networkRuleSet)
 	}
 	if err == nil {
 		// storage account created so add to state
@@ -243,7 +246,7 @@ func (state *scenarioState) detectObjectStorageUnencryptedTransferAvailable() er
 	defer func() {
 		state.audit.AuditScenarioStep(state.currentStep, stepTrace.String(), payload, err)
 	}()
-	err = fmt.Errorf("Not Implemented")
+	err = godog.ErrPending
 	stepTrace.WriteString("TODO: Pending implementation;")
 
 	return nil
@@ -258,7 +261,7 @@ func (state *scenarioState) detectObjectStorageUnencryptedTransferEnabled() erro
 	defer func() {
 		state.audit.AuditScenarioStep(state.currentStep, stepTrace.String(), payload, err)
 	}()
-	err = fmt.Errorf("Not Implemented")
+	err = godog.ErrPending
 	stepTrace.WriteString("TODO: Pending implementation;")
 
 	return nil
@@ -273,7 +276,7 @@ func (state *scenarioState) createUnencryptedTransferObjectStorage() error {
 	defer func() {
 		state.audit.AuditScenarioStep(state.currentStep, stepTrace.String(), payload, err)
 	}()
-	err = fmt.Errorf("Not Implemented")
+	err = godog.ErrPending
 	stepTrace.WriteString("TODO: Pending implementation;")
 
 	return nil
@@ -288,7 +291,7 @@ func (state *scenarioState) detectsTheObjectStorage() error {
 	defer func() {
 		state.audit.AuditScenarioStep(state.currentStep, stepTrace.String(), payload, err)
 	}()
-	err = fmt.Errorf("Not Implemented")
+	err = godog.ErrPending
 	stepTrace.WriteString("TODO: Pending implementation;")
 
 	return nil
@@ -303,17 +306,17 @@ func (state *scenarioState) encryptedDataTrafficIsEnforced() error {
 	defer func() {
 		state.audit.AuditScenarioStep(state.currentStep, stepTrace.String(), payload, err)
 	}()
-	err = fmt.Errorf("Not Implemented")
+	err = godog.ErrPending
 	stepTrace.WriteString("TODO: Pending implementation;")
 
 	return nil
 }
 
-func (state *scenarioState) beforeScenario(probeName string, gs *godog.Scenario) {
+func (state *scenarioState) beforeScenario(ctx context.Context, probeName string, gs *godog.Scenario) {
 	state.name = gs.Name
-	state.probe = audit.State.GetProbeLog(probeName)
-	state.audit = audit.State.GetProbeLog(probeName).InitializeAuditor(gs.Name, gs.Tags)
-	state.ctx = context.Background()
+	state.ctx = ctx
+	state.probe = audit.ProbeLog(ctx, probeName)
+	state.audit = state.probe.InitializeAuditor(gs.Name, gs.Tags)
 	coreengine.LogScenarioStart(gs)
 }
 
@@ -338,33 +341,33 @@ func (p ProbeStruct) ProbeInitialize(ctx *godog.TestSuiteContext) {
 }
 
 // ScenarioInitialize initialises the scenario
-func (p ProbeStruct) ScenarioInitialize(ctx *godog.ScenarioContext) {
+func (p ProbeStruct) ScenarioInitialize(ctx context.Context, sc *godog.ScenarioContext) {
 
-	ctx.BeforeScenario(func(s *godog.Scenario) {
-		p.state.beforeScenario(p.Name(), s)
+	sc.BeforeScenario(func(s *godog.Scenario) {
+		p.state.beforeScenario(ctx, p.Name(), s)
 	})
 
-	ctx.Step(`^a specified azure resource group exists$`, p.state.anAzureResourceGroupExists)
-	ctx.Step(`^we provision an Object Storage bucket$`, p.state.weProvisionAnObjectStorageBucket)
-	ctx.Step(`^http access is "([^"]*)"$`, p.state.httpAccessIs)
-	ctx.Step(`^https access is "([^"]*)"$`, p.state.httpsAccessIs)
-	ctx.Step(`^creation will "([^"]*)" with an error matching "([^"]*)"$`, p.state.creationWillWithAnErrorMatching)
+	sc.Step(`^a specified azure resource group exists$`, p.state.anAzureResourceGroupExists)
+	sc.Step(`^we provision an Object Storage bucket$`, p.state.weProvisionAnObjectStorageBucket)
+	sc.Step(`^http access is "([^"]*)"$`, p.state.httpAccessIs)
+	sc.Step(`^https access is "([^"]*)"$`, p.state.httpsAccessIs)
+	sc.Step(`^creation will "([^"]*)" with an error matching "([^"]*)"$`, p.state.creationWillWithAnErrorMatching)
 
-	ctx.Step(`^there is a detective capability for creation of Object Storage with unencrypted data transfer enabled$`, p.state.detectObjectStorageUnencryptedTransferAvailable)
-	ctx.Step(`^the capability for detecting the creation of Object Storage with unencrypted data transfer enabled is active$`, p.state.detectObjectStorageUnencryptedTransferEnabled)
-	ctx.Step(`^Object Storage is created with unencrypted data transfer enabled$`, p.state.createUnencryptedTransferObjectStorage)
-	ctx.Step(`^the detective capability detects the creation of Object Storage with unencrypted data transfer enabled$`, p.state.detectsTheObjectStorage)
-	ctx.Step(`^the detective capability enforces encrypted data transfer on the Object Storage Bucket$`, p.state.encryptedDataTrafficIsEnforced)
+	sc.Step(`^there is a detective capability for creation of Object Storage with unencrypted data transfer enabled$`, p.state.detectObjectStorageUnencryptedTransferAvailable)
+	sc.Step(`^the capability for detecting the creation of Object Storage with unencrypted data transfer enabled is active$`, p.state.detectObjectStorageUnencryptedTransferEnabled)
+	sc.Step(`^Object Storage is created with unencrypted data transfer enabled$`, p.state.createUnencryptedTransferObjectStorage)
+	sc.Step(`^the detective capability detects the creation of Object Storage with unencrypted data transfer enabled$`, p.state.detectsTheObjectStorage)
+	sc.Step(`^the detective capability enforces encrypted data transfer on the Object Storage Bucket$`, p.state.encryptedDataTrafficIsEnforced)
 
-	ctx.AfterScenario(func(s *godog.Scenario, err error) {
+	sc.AfterScenario(func(s *godog.Scenario, err error) {
 		coreengine.LogScenarioEnd(s)
 	})
 
-	ctx.BeforeStep(func(st *godog.Step) {
+	sc.BeforeStep(func(st *godog.Step) {
 		p.state.currentStep = st.Text
 	})
 
-	ctx.AfterStep(func(st *godog.Step, err error) {
+	sc.AfterStep(func(st *godog.Step, err error) {
 		p.state.currentStep = ""
 	})
 }
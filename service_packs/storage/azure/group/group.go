@@ -12,40 +12,40 @@ import (
 
 // Create creates a new Resource Group in the default location (configured using the AZURE_LOCATION environment variable).
 func Create(ctx context.Context, name string) (resources.Group, error) {
-	log.Printf("[INFO] creating Resource Group '%s' in location: %v", name, azure.ResourceLocation())
-	return client().CreateOrUpdate(
+	log.Printf("[INFO] creating Resource Group '%s' in location: %v", name, azure.ResourceLocation(ctx))
+	return client(ctx).CreateOrUpdate(
 		ctx,
 		name,
 		resources.Group{
-			Location: to.StringPtr(azure.ResourceLocation()),
+			Location: to.StringPtr(azure.ResourceLocation(ctx)),
 		})
 }
 
 // Get an existing Resource Group by name
 func Get(ctx context.Context, name string) (resources.Group, error) {
 	log.Printf("[DEBUG] getting a Resource Group '%s'", name)
-	return client().Get(ctx, name)
+	return client(ctx).Get(ctx, name)
 }
 
 // CreateWithTags creates a new Resource Group in the default location (configured using the AZURE_LOCATION environment variable) and sets the supplied tags.
 func CreateWithTags(ctx context.Context, name string, tags map[string]*string) (resources.Group, error) {
-	log.Printf("[INFO] creating Resource Group '%s' on location: '%v'", name, azure.ResourceLocation())
-	return client().CreateOrUpdate(
+	log.Printf("[INFO] creating Resource Group '%s' on location: '%v'", name, azure.ResourceLocation(ctx))
+	return client(ctx).CreateOrUpdate(
 		ctx,
 		name,
 		resources.Group{
-			Location: to.StringPtr(azure.ResourceLocation()),
+			Location: to.StringPtr(azure.ResourceLocation(ctx)),
 			Tags:     tags,
 		})
 }
 
-func client() resources.GroupsClient {
+func client(ctx context.Context) resources.GroupsClient {
 
 	// Create an azure resource group client object via the connection config vars
-	c := resources.NewGroupsClient(azure.SubscriptionID())
+	c := resources.NewGroupsClient(azure.SubscriptionID(ctx))
 
 	// Create an authorization object via the connection config vars
-	authorizer := auth.NewClientCredentialsConfig(azure.ClientID(), azure.ClientSecret(), azure.TenantID())
+	authorizer := auth.NewClientCredentialsConfig(azure.ClientID(ctx), azure.ClientSecret(ctx), azure.TenantID(ctx))
 
 	authorizerToken, err := authorizer.Authorizer()
 	if err == nil {
@@ -13,18 +13,18 @@ import (
 func AssignmentBySubscription(ctx context.Context, subscriptionID, name string) (policy.Assignment, error) {
 	scope := "/subscriptions/" + subscriptionID
 	log.Printf("[DEBUG] Getting Policy Assignment with subscriptionID: %v", scope)
-	return assignmentClient().Get(ctx, scope, name)
+	return assignmentClient(ctx).Get(ctx, scope, name)
 }
 
 // AssignmentByManagementGroup gets a Policy Assignment by Policy Assignment name, scoped to a Managed Group.
 func AssignmentByManagementGroup(ctx context.Context, managementGroup, name string) (policy.Assignment, error) {
 	scope := "/providers/Microsoft.Management/managementGroups/" + managementGroup
 	log.Printf("[DEBUG] Getting Policy Assignment with scope: %v", scope)
-	return assignmentClient().Get(ctx, scope, name)
+	return assignmentClient(ctx).Get(ctx, scope, name)
 }
 
-func assignmentClient() policy.AssignmentsClient {
-	c := policy.NewAssignmentsClient(azureutil.SubscriptionID())
+func assignmentClient(ctx context.Context) policy.AssignmentsClient {
+	c := policy.NewAssignmentsClient(azureutil.SubscriptionID(ctx))
 	a, err := auth.NewAuthorizerFromEnvironment()
 	if err == nil {
 		c.Authorizer = a
@@ -11,11 +11,11 @@ import (
 
 // DefinitionByName get a Policy Definition by name.
 func DefinitionByName(ctx context.Context, name string) (policy.Definition, error) {
-	return definitionClient().Get(ctx, name)
+	return definitionClient(ctx).Get(ctx, name)
 }
 
-func definitionClient() policy.DefinitionsClient {
-	c := policy.NewDefinitionsClient(azureutil.SubscriptionID())
+func definitionClient(ctx context.Context) policy.DefinitionsClient {
+	c := policy.NewDefinitionsClient(azureutil.SubscriptionID(ctx))
 	a, err := auth.NewAuthorizerFromEnvironment()
 	if err == nil {
 		c.Authorizer = a
@@ -2,30 +2,63 @@ package connection
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 
 	"github.com/Azure/azure-sdk-for-go/services/storage/mgmt/2019-04-01/storage"
+	"github.com/Azure/go-autorest/autorest"
 	"github.com/Azure/go-autorest/autorest/azure/auth"
 	"github.com/Azure/go-autorest/autorest/to"
+	"github.com/citihub/probr/ledger"
 	"github.com/citihub/probr/service_packs/storage/azure"
+	"github.com/citihub/probr/utils"
 )
 
-// DeleteAccount - deletes a storage account given the azure contect, resource group and account name
+// KindAccount is the kind of the storage accounts recorded in the ledger
+const KindAccount = "azure/storageaccount"
+
+func init() {
+	ledger.RegisterDeleter(KindAccount, deleteRecordedAccount)
+}
+
+// DeleteAccount - deletes a storage account given the azure contect, resource group and account name, and removes it from the ledger
 func DeleteAccount(ctx context.Context, resourceGroupName, accountName string) error {
 
-	c := accountClient()
+	c := accountClient(ctx)
 
 	_, err := c.Delete(ctx, resourceGroupName, accountName)
+	if err == nil {
+		ledger.Remove(ctx, ledgerAccount(ctx, resourceGroupName, accountName))
+	}
+
+	return apiError(err, "Could not delete storage account '%s'", accountName)
+}
 
-	return err
+// ledgerAccount describes a storage account in the subscription of the config carried by ctx
+func ledgerAccount(ctx context.Context, resourceGroupName, accountName string) ledger.Resource {
+	return ledger.Resource{
+		Kind:     KindAccount,
+		Group:    resourceGroupName,
+		Name:     accountName,
+		Location: map[string]string{"subscription_id": azure.SubscriptionID(ctx)},
+	}
+}
+
+// deleteRecordedAccount deletes a storage account listed in the ledger, which must be in the configured subscription
+func deleteRecordedAccount(ctx context.Context, r ledger.Resource) error {
+	if subscription := azure.SubscriptionID(ctx); subscription != r.Location["subscription_id"] {
+		return utils.ReformatError("Storage account was created in subscription '%s', but the config is for subscription '%s'",
+			r.Location["subscription_id"], subscription)
+	}
+	return DeleteAccount(ctx, r.Group, r.Name)
 }
 
 // CreateWithNetworkRuleSet starts creation of a new Storage Account and waits for the account to be created.
 func CreateWithNetworkRuleSet(ctx context.Context, accountName, accountGroupName string, tags map[string]*string, httpsOnly bool, networkRuleSet *storage.NetworkRuleSet) (storage.Account, error) {
 
 	var sa storage.Account
-	c := accountClient()
+	c := accountClient(ctx)
 
 	r, err := c.CheckNameAvailability(
 		ctx,
@@ -34,7 +67,7 @@ func CreateWithNetworkRuleSet(ctx context.Context, accountName, accountGroupName
 			Type: to.StringPtr("Microsoft.Storage/storageAccounts"),
 		})
 	if err != nil {
-		return sa, err
+		return sa, apiError(err, "Could not check the availability of storage account name '%s'", accountName)
 	}
 
 	if *r.NameAvailable != true {
@@ -56,18 +89,19 @@ func CreateWithNetworkRuleSet(ctx context.Context, accountName, accountGroupName
 			Sku: &storage.Sku{
 				Name: storage.StandardLRS},
 			Kind:                              storage.Storage,
-			Location:                          to.StringPtr(azure.ResourceLocation()),
+			Location:                          to.StringPtr(azure.ResourceLocation(ctx)),
 			AccountPropertiesCreateParameters: networkRuleSetParam,
 			Tags:                              tags,
 		})
 
 	if err != nil {
-		return sa, err
+		return sa, apiError(err, "Could not create storage account '%s'", accountName)
 	}
+	ledger.Record(ctx, ledgerAccount(ctx, accountGroupName, accountName))
 
 	err = future.WaitForCompletionRef(ctx, c.Client)
 	if err != nil {
-		return sa, err
+		return sa, apiError(err, "Could not create storage account '%s'", accountName)
 	}
 
 	return future.Result(c)
@@ -75,7 +109,8 @@ func CreateWithNetworkRuleSet(ctx context.Context, accountName, accountGroupName
 
 // AccountProperties returns the properties for the specified storage account including but not limited to name, SKU name, location, and account status
 func AccountProperties(ctx context.Context, rgName, accountName string) (storage.Account, error) {
-	return accountClient().GetProperties(ctx, rgName, accountName, "")
+	account, err := accountClient(ctx).GetProperties(ctx, rgName, accountName, "")
+	return account, apiError(err, "Could not get the properties of storage account '%s'", accountName)
 }
 
 // AccountPrimaryKey return the primary key
@@ -88,16 +123,32 @@ func AccountPrimaryKey(ctx context.Context, accountName, accountGroupName string
 }
 
 func getAccountKeys(ctx context.Context, accountName, accountGroupName string) (storage.AccountListKeysResult, error) {
-	return accountClient().ListKeys(ctx, accountGroupName, accountName, "")
+	return accountClient(ctx).ListKeys(ctx, accountGroupName, accountName, "")
+}
+
+// apiError marks an error returned by Azure as an infrastructure error if Azure could not be reached or could not
+// serve the request, so that the control is recorded as inconclusive rather than failed. Other errors, such as a
+// storage account being denied by a policy, are returned unchanged for probes to evaluate.
+func apiError(err error, action string, v ...interface{}) error {
+	if err == nil || utils.IsInfrastructureError(err) {
+		return err
+	}
+	var detailed autorest.DetailedError
+	if errors.As(err, &detailed) {
+		if code, ok := detailed.StatusCode.(int); ok && code != 401 && code != 429 && code > 0 && code < 500 {
+			return err
+		}
+	}
+	return utils.InfrastructureError("%s: %w", fmt.Sprintf(action, v...), err)
 }
 
-func accountClient() storage.AccountsClient {
+func accountClient(ctx context.Context) storage.AccountsClient {
 
 	// Create an azure storage account client object via the connection config vars
-	c := storage.NewAccountsClient(azure.SubscriptionID())
+	c := storage.NewAccountsClient(azure.SubscriptionID(ctx))
 
 	// Create an authorization object via the connection config vars
-	authorizer := auth.NewClientCredentialsConfig(azure.ClientID(), azure.ClientSecret(), azure.TenantID())
+	authorizer := auth.NewClientCredentialsConfig(azure.ClientID(ctx), azure.ClientSecret(ctx), azure.TenantID(ctx))
 
 	authorizerToken, err := authorizer.Authorizer()
 	if err == nil {
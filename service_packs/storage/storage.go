@@ -9,24 +9,32 @@ import (
 	"github.com/markbates/pkger"
 )
 
-// GetProbes returns a list of probe objects
-func GetProbes() []coreengine.Probe {
-	if config.Vars.ServicePacks.Storage.IsExcluded() {
-		return nil
-	}
-	switch config.Vars.ServicePacks.Storage.Provider {
-	case "Azure":
-		return []coreengine.Probe{
+var pack = coreengine.ServicePack{
+	Name: "storage",
+	Config: config.PackConfig{
+		RequiredVars: []string{"Provider"},
+		Settings:     func(v *config.VarOptions) interface{} { return v.ServicePacks.Storage },
+		Probes:       func(v *config.VarOptions) []config.Probe { return v.ServicePacks.Storage.Probes },
+	},
+	Provider: func(v *config.VarOptions) string { return v.ServicePacks.Storage.Provider },
+	Probes: map[string][]coreengine.Probe{
+		"Azure": {
 			azureaw.Probe,
 			azureear.Probe,
 			azureeif.Probe,
-		}
-	default:
-		return nil
-	}
+		},
+	},
+	Tags: tags,
+}
+
+// GetProbes returns a list of probe objects that may be run using the given config
+func GetProbes(vars *config.VarOptions) []coreengine.Probe {
+	return pack.GetProbes(vars)
 }
 
 func init() {
+	coreengine.RegisterServicePack(pack)
+
 	// This line will ensure that all static files are bundled into pked.go file when using pkger cli tool
 	// See: https://github.com/markbates/pkger
 	pkger.Include("/service_packs/storage/azure/access_whitelisting/access_whitelisting.feature")
@@ -0,0 +1,16 @@
+package storage
+
+var (
+	tags = map[string][]string{
+		"@probes/storage/access_whitelisting":  {"@s-azaw"},
+		"@probes/storage/encryption_at_rest":   {"@s-azear"},
+		"@probes/storage/encryption_in_flight": {"@s-azeif"},
+		"@standard/citihub/CHC2-SVD030":        {"@s-azaw"},
+		"@standard/citihub/CHC2-SVD001":        {"@s-azear", "@s-azeif"},
+		"@standard/citihub/CHC2-AGP140":        {"@s-azear", "@s-azeif"},
+		"@standard/citihub/CHC2-EUC001":        {"@s-azear"},
+		"@csp/azure":                           {"@s-azaw", "@s-azear", "@s-azeif"},
+		"@control_type/detective":              {"@s-azaw-001", "@s-azear-002", "@s-azeif-002"},
+		"@control_type/preventative":           {"@s-azaw-002", "@s-azear-001", "@s-azeif-001"},
+	}
+)
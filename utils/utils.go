@@ -2,6 +2,8 @@
 package utils
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
@@ -67,15 +69,63 @@ func CallerFileLine() (string, int) {
 	return file, line
 }
 
-// ReformatError prefixes the error string ready for logging and/or output
+// ReformatError prefixes the error string ready for logging and/or output.
+// As with fmt.Errorf, an error formatted with %w is wrapped by the returned error.
 func ReformatError(e string, v ...interface{}) error {
-	var b strings.Builder
-	b.WriteString("[ERROR] ")
-	b.WriteString(e)
+	return fmt.Errorf("[ERROR] "+e, v...)
+}
+
+// infrastructureError marks an error that prevented a control from being evaluated
+type infrastructureError struct {
+	err error
+}
+
+func (e *infrastructureError) Error() string {
+	return e.err.Error()
+}
+
+func (e *infrastructureError) Unwrap() error {
+	return e.err
+}
+
+// InfrastructureError is used in place of ReformatError when a step could not evaluate a control,
+// for instance because a cluster or cloud API could not be reached. The scenario is then recorded as
+// inconclusive, rather than as a failure of the control.
+func InfrastructureError(e string, v ...interface{}) error {
+	return &infrastructureError{err: ReformatError(e, v...)}
+}
 
-	s := fmt.Sprintf(b.String(), v...)
+// IsInfrastructureError reports whether err, or any error it wraps, was created by InfrastructureError.
+// Errors caused by a cancelled or expired context are also treated as infrastructure errors.
+func IsInfrastructureError(err error) bool {
+	var infraErr *infrastructureError
+	return errors.As(err, &infraErr) || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// givenNotMetError marks an error raised by a Given step whose precondition does not hold
+type givenNotMetError struct {
+	err error
+}
+
+func (e *givenNotMetError) Error() string {
+	return e.err.Error()
+}
+
+func (e *givenNotMetError) Unwrap() error {
+	return e.err
+}
+
+// GivenNotMet is used in place of ReformatError when a Given step finds that the scenario's precondition
+// does not hold, for instance because the CSP lacks the capability being probed. The scenario is then
+// recorded as "Given Not Met", rather than as a failure of the control.
+func GivenNotMet(e string, v ...interface{}) error {
+	return &givenNotMetError{err: ReformatError(e, v...)}
+}
 
-	return fmt.Errorf(s)
+// IsGivenNotMet reports whether err, or any error it wraps, was created by GivenNotMet
+func IsGivenNotMet(err error) bool {
+	var givenErr *givenNotMetError
+	return errors.As(err, &givenErr)
 }
 
 // ReadStaticFile returns the bytes for a given static file
@@ -2,6 +2,8 @@ package utils
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"log"
@@ -26,6 +28,55 @@ func TestReformatError(t *testing.T) {
 		t.Logf("Test string was not properly included in retured error")
 		t.Fail()
 	}
+
+	cause := errors.New("connection refused")
+	if err := ReformatError("Could not list pods: %w", cause); !errors.Is(err, cause) {
+		t.Errorf("Expected the error formatted with %%w to be wrapped, got: %v", err)
+	}
+}
+
+func TestIsInfrastructureError(t *testing.T) {
+	var tests = []struct {
+		testName string
+		err      error
+		expected bool
+	}{
+		{"InfrastructureError", InfrastructureError("cluster %s is unreachable", "test"), true},
+		{"WrappedInfrastructureError", fmt.Errorf("step failed: %w", InfrastructureError("auth failed")), true},
+		{"ReformatErrorWrappingInfrastructureError", ReformatError("step failed: %w", InfrastructureError("auth failed")), true},
+		{"DeadlineExceeded", context.DeadlineExceeded, true},
+		{"ReformatError", ReformatError("privileged pod was admitted"), false},
+		{"PlainError", errors.New("plain"), false},
+		{"Nil", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			if IsInfrastructureError(tt.err) != tt.expected {
+				t.Errorf("IsInfrastructureError(%v) = %v, want %v", tt.err, !tt.expected, tt.expected)
+			}
+		})
+	}
+}
+
+func TestIsGivenNotMet(t *testing.T) {
+	var tests = []struct {
+		testName string
+		err      error
+		expected bool
+	}{
+		{"GivenNotMet", GivenNotMet("%s is not supported", "whitelisting"), true},
+		{"WrappedGivenNotMet", fmt.Errorf("step failed: %w", GivenNotMet("binding not found")), true},
+		{"InfrastructureError", InfrastructureError("cluster is unreachable"), false},
+		{"ReformatError", ReformatError("privileged pod was admitted"), false},
+		{"Nil", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.testName, func(t *testing.T) {
+			if IsGivenNotMet(tt.err) != tt.expected {
+				t.Errorf("IsGivenNotMet(%v) = %v, want %v", tt.err, !tt.expected, tt.expected)
+			}
+		})
+	}
 }
 
 func TestFindString(t *testing.T) {